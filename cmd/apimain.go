@@ -16,6 +16,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/lib/lock"
 	"github.com/RobertLesgros/rustdesk-interface/v2/lib/logger"
 	"github.com/RobertLesgros/rustdesk-interface/v2/lib/orm"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/secret"
 	"github.com/RobertLesgros/rustdesk-interface/v2/lib/upload"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
@@ -24,7 +25,7 @@ import (
 	"github.com/spf13/cobra"
 )
 
-const DatabaseVersion = 265
+const DatabaseVersion = 286
 
 // @title RustDesk API
 // @version 1.0
@@ -45,6 +46,15 @@ var rootCmd = &cobra.Command{
 	},
 	Run: func(cmd *cobra.Command, args []string) {
 		global.Logger.Info("API SERVER START")
+		if secret.Enabled() {
+			// Encrypt rows written before the master key was configured
+			if res, err := service.AllService.SecretService.Migrate(false); err != nil {
+				global.Logger.Error("Encrypt existing secrets failed: ", err)
+			} else if res.Total() > 0 {
+				global.Logger.Info("Encrypted existing secrets: ", res)
+			}
+		}
+		service.StartCronJobs()
 		http.ApiInit()
 	},
 }
@@ -100,9 +110,38 @@ var resetUserPwdCmd = &cobra.Command{
 	},
 }
 
+var rotateKeysCmd = &cobra.Command{
+	Use:   "rotate-keys",
+	Short: "Re-encrypt secrets with the current master key",
+	Long: "Re-encrypt address book passwords (including the change history) and OAuth client secrets with encryption.master-key.\n" +
+		"Move the previous key to encryption.old-keys before running, and remove it once the command succeeds.",
+	Run: func(cmd *cobra.Command, args []string) {
+		if !secret.Enabled() {
+			global.Logger.Warn("encryption.master-key is not configured! ")
+			return
+		}
+		res, err := service.AllService.SecretService.Migrate(true)
+		if err != nil {
+			global.Logger.Error("rotate keys fail! ", err)
+			return
+		}
+		global.Logger.Info("rotate keys success! ", res, " key id: ", secret.Current().KeyId())
+	},
+}
+
+var genKeyCmd = &cobra.Command{
+	Use:   "gen-key",
+	Short: "Generate a random encryption master key",
+	// No config or database needed
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(secret.GenerateKey())
+	},
+}
+
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&global.ConfigPath, "config", "c", "./conf/config.yaml", "choose config file")
-	rootCmd.AddCommand(resetPwdCmd, resetUserPwdCmd)
+	rootCmd.AddCommand(resetPwdCmd, resetUserPwdCmd, rotateKeysCmd, genKeyCmd)
 }
 func main() {
 	if err := rootCmd.Execute(); err != nil {
@@ -132,6 +171,15 @@ func InitGlobal() {
 
 	global.InitI18n()
 
+	// Master key for encrypted columns, must be loaded before any database access
+	if err := secret.Init(&secret.Config{
+		MasterKey:     global.Config.Encryption.MasterKey,
+		MasterKeyFile: global.Config.Encryption.MasterKeyFile,
+		OldKeys:       global.Config.Encryption.OldKeys,
+	}); err != nil {
+		global.Logger.Fatalf("Failed to load encryption master key: %v", err)
+	}
+
 	// Redis connection
 	global.Redis = redis.NewClient(&redis.Options{
 		Addr:     global.Config.Redis.Addr,
@@ -315,6 +363,29 @@ func Migrate(version uint) {
 		&model.AddressBookCollectionRule{},
 		&model.ServerCmd{},
 		&model.DeviceGroup{},
+		&model.IpAccessRule{},
+		&model.AccessSchedule{},
+		&model.PeerPresenceEvent{},
+		&model.PeerSysinfo{},
+		&model.StaleDevicePolicy{},
+		&model.StaleDeviceRun{},
+		&model.PeerAttributeDef{},
+		&model.PeerAttributeValue{},
+		&model.DeviceGroupRule{},
+		&model.DeviceGroupPermission{},
+		&model.Strategy{},
+		&model.StrategyAssignment{},
+		&model.PeerStrategyState{},
+		&model.PeerAction{},
+		&model.ClientVersionPolicy{},
+		&model.SavedSearch{},
+		&model.PeerIdentityChange{},
+		&model.AddressBookChange{},
+		&model.AddressBookRevision{},
+		&model.Notification{},
+		&model.AddressBookFolder{},
+		&model.GlobalTag{},
+		&model.PeerShare{},
 	)
 	if err != nil {
 		global.Logger.Error("migrate err :=>", err)
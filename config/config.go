@@ -14,16 +14,41 @@ const (
 )
 
 type App struct {
-	WebClient        int           `mapstructure:"web-client"`
-	Register         bool          `mapstructure:"register"`
-	RegisterStatus   int           `mapstructure:"register-status"`
-	ShowSwagger      int           `mapstructure:"show-swagger"`
-	TokenExpire      time.Duration `mapstructure:"token-expire"`
-	WebSso           bool          `mapstructure:"web-sso"`
-	DisablePwdLogin  bool          `mapstructure:"disable-pwd-login"`
-	CaptchaThreshold int           `mapstructure:"captcha-threshold"`
-	BanThreshold     int           `mapstructure:"ban-threshold"`
+	WebClient          int           `mapstructure:"web-client"`
+	Register           bool          `mapstructure:"register"`
+	RegisterStatus     int           `mapstructure:"register-status"`
+	ShowSwagger        int           `mapstructure:"show-swagger"`
+	TokenExpire        time.Duration `mapstructure:"token-expire"`
+	WebSso             bool          `mapstructure:"web-sso"`
+	DisablePwdLogin    bool          `mapstructure:"disable-pwd-login"`
+	CaptchaThreshold   int           `mapstructure:"captcha-threshold"`
+	BanThreshold       int           `mapstructure:"ban-threshold"`
+	AccessTimezone     string        `mapstructure:"access-timezone"`
+	PresenceTimeout    time.Duration `mapstructure:"presence-timeout"`
+	SysinfoHistoryMax  int           `mapstructure:"sysinfo-history-max"`
+	SysinfoHistoryDays int           `mapstructure:"sysinfo-history-days"`
+	ClientVersionAlert bool          `mapstructure:"client-version-alert"`
+	PeerMergeMode      string        `mapstructure:"peer-merge-mode"`
+	AbHistoryDays      int           `mapstructure:"ab-history-days"`
+	AbRuleExpireAction string        `mapstructure:"ab-rule-expire-action"`
+	AbRuleNotifyBefore time.Duration `mapstructure:"ab-rule-notify-before"`
 }
+
+// Geoip 本地 mmdb 文件, 修改后按 ReloadInterval 自动重新加载
+type Geoip struct {
+	Enable         bool          `mapstructure:"enable"`
+	CityDb         string        `mapstructure:"city-db"`
+	AsnDb          string        `mapstructure:"asn-db"`
+	ReloadInterval time.Duration `mapstructure:"reload-interval"`
+}
+
+// Encryption 地址簿密码和 OAuth ClientSecret 的加密主密钥, 32 字节 base64 或 hex, 为空时不加密
+type Encryption struct {
+	MasterKey     string   `mapstructure:"master-key"`
+	MasterKeyFile string   `mapstructure:"master-key-file"`
+	OldKeys       []string `mapstructure:"old-keys"` // 轮换前的主密钥, 只用于解密
+}
+
 type Admin struct {
 	Title           string `mapstructure:"title"`
 	Hello           string `mapstructure:"hello"`
@@ -48,6 +73,8 @@ type Config struct {
 	Rustdesk   Rustdesk
 	Proxy      Proxy
 	Ldap       Ldap
+	Geoip      Geoip
+	Encryption Encryption
 }
 
 func (a *Admin) Init() {
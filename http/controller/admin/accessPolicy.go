@@ -0,0 +1,450 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+type AccessPolicy struct {
+}
+
+// IpRuleList Liste
+// @Tags Politique d'accès
+// @Summary Liste des règles IP
+// @Description Liste des règles IP
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param type query int false "Type (1: utilisateur, 2: groupe)"
+// @Param to_id query int false "ID de l'utilisateur ou du groupe"
+// @Success 200 {object} response.Response{data=model.IpAccessRuleList}
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/list [get]
+// @Security token
+func (ct *AccessPolicy) IpRuleList(c *gin.Context) {
+	query := &admin.IpAccessRuleQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AccessPolicyService.ListIpRules(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Type > 0 {
+			tx.Where("type = ?", query.Type)
+		}
+		if query.ToId > 0 {
+			tx.Where("to_id = ?", query.ToId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// IpRuleDetail Règle IP
+// @Tags Politique d'accès
+// @Summary Détails de la règle IP
+// @Description Détails de la règle IP
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response{data=model.IpAccessRule}
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/detail/{id} [get]
+// @Security token
+func (ct *AccessPolicy) IpRuleDetail(c *gin.Context) {
+	id := c.Param("id")
+	iid, _ := strconv.Atoi(id)
+	r := service.AllService.AccessPolicyService.IpRuleInfoById(uint(iid))
+	if r.Id > 0 {
+		response.Success(c, r)
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// IpRuleCreate Créer une règle IP
+// @Tags Politique d'accès
+// @Summary Créer une règle IP
+// @Description Créer une règle IP
+// @Accept  json
+// @Produce  json
+// @Param body body admin.IpAccessRuleForm true "Informations sur la règle IP"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/create [post]
+// @Security token
+func (ct *AccessPolicy) IpRuleCreate(c *gin.Context) {
+	f := &admin.IpAccessRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if msg, ok := ct.checkIpRuleForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	err := service.AllService.AccessPolicyService.CreateIpRule(f.ToIpAccessRule())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// IpRuleUpdate Modifier
+// @Tags Politique d'accès
+// @Summary Modifier la règle IP
+// @Description Modifier la règle IP
+// @Accept  json
+// @Produce  json
+// @Param body body admin.IpAccessRuleForm true "Informations sur la règle IP"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/update [post]
+// @Security token
+func (ct *AccessPolicy) IpRuleUpdate(c *gin.Context) {
+	f := &admin.IpAccessRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if msg, ok := ct.checkIpRuleForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	ex := service.AllService.AccessPolicyService.IpRuleInfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToIpAccessRule()
+	r.CreatedAt = ex.CreatedAt
+	err := service.AllService.AccessPolicyService.UpdateIpRule(r)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// IpRuleDelete Supprimer
+// @Tags Politique d'accès
+// @Summary Supprimer la règle IP
+// @Description Supprimer la règle IP
+// @Accept  json
+// @Produce  json
+// @Param body body admin.IpAccessRuleForm true "Informations sur la règle IP"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/delete [post]
+// @Security token
+func (ct *AccessPolicy) IpRuleDelete(c *gin.Context) {
+	f := &admin.IpAccessRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.AccessPolicyService.IpRuleInfoById(f.Id)
+	if r.Id > 0 {
+		err := service.AllService.AccessPolicyService.DeleteIpRule(r)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// TestIp Tester une IP
+// @Tags Politique d'accès
+// @Summary Tester une IP
+// @Description Indique si un utilisateur ou un groupe peut se connecter depuis cette IP
+// @Accept  json
+// @Produce  json
+// @Param body body admin.IpTestForm true "Utilisateur ou groupe et IP"
+// @Success 200 {object} response.Response{data=service.IpCheckResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/ip_access_rule/testIp [post]
+// @Security token
+func (ct *AccessPolicy) TestIp(c *gin.Context) {
+	f := &admin.IpTestForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if f.UserId == 0 && f.GroupId == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	groupId := f.GroupId
+	if f.UserId > 0 {
+		u := service.AllService.UserService.InfoById(f.UserId)
+		if u.Id == 0 {
+			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+			return
+		}
+		groupId = u.GroupId
+	}
+	res := service.AllService.AccessPolicyService.CheckIpByIds(f.UserId, groupId, f.Ip)
+	response.Success(c, res)
+}
+
+func (ct *AccessPolicy) checkIpRuleForm(c *gin.Context, f *admin.IpAccessRuleForm) (string, bool) {
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		return errList[0], false
+	}
+	if err := service.AllService.AccessPolicyService.ValidateCidr(f.Cidr); err != nil {
+		return response.TranslateMsg(c, "InvalidCidr"), false
+	}
+	if f.Type == model.AccessTargetTypeUser {
+		if service.AllService.UserService.InfoById(f.ToId).Id == 0 {
+			return response.TranslateMsg(c, "ItemNotFound"), false
+		}
+	} else if service.AllService.GroupService.InfoById(f.ToId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	return "", true
+}
+
+// ScheduleList Liste
+// @Tags Politique d'accès
+// @Summary Liste des plages horaires d'accès
+// @Description Liste des plages horaires d'accès
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param type query int false "Type (1: utilisateur, 2: groupe)"
+// @Param to_id query int false "ID de l'utilisateur ou du groupe"
+// @Success 200 {object} response.Response{data=model.AccessScheduleList}
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/list [get]
+// @Security token
+func (ct *AccessPolicy) ScheduleList(c *gin.Context) {
+	query := &admin.AccessScheduleQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AccessPolicyService.ListSchedules(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Type > 0 {
+			tx.Where("type = ?", query.Type)
+		}
+		if query.ToId > 0 {
+			tx.Where("to_id = ?", query.ToId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// ScheduleDetail Plage horaire
+// @Tags Politique d'accès
+// @Summary Détails de la plage horaire d'accès
+// @Description Détails de la plage horaire d'accès
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response{data=model.AccessSchedule}
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/detail/{id} [get]
+// @Security token
+func (ct *AccessPolicy) ScheduleDetail(c *gin.Context) {
+	id := c.Param("id")
+	iid, _ := strconv.Atoi(id)
+	r := service.AllService.AccessPolicyService.ScheduleInfoById(uint(iid))
+	if r.Id > 0 {
+		response.Success(c, r)
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// ScheduleCreate Créer une plage horaire
+// @Tags Politique d'accès
+// @Summary Créer une plage horaire d'accès
+// @Description Créer une plage horaire d'accès
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AccessScheduleForm true "Informations sur la plage horaire"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/create [post]
+// @Security token
+func (ct *AccessPolicy) ScheduleCreate(c *gin.Context) {
+	f := &admin.AccessScheduleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if msg, ok := ct.checkScheduleForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	err := service.AllService.AccessPolicyService.CreateSchedule(f.ToAccessSchedule())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ScheduleUpdate Modifier
+// @Tags Politique d'accès
+// @Summary Modifier la plage horaire d'accès
+// @Description Modifier la plage horaire d'accès
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AccessScheduleForm true "Informations sur la plage horaire"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/update [post]
+// @Security token
+func (ct *AccessPolicy) ScheduleUpdate(c *gin.Context) {
+	f := &admin.AccessScheduleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if msg, ok := ct.checkScheduleForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	ex := service.AllService.AccessPolicyService.ScheduleInfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToAccessSchedule()
+	r.CreatedAt = ex.CreatedAt
+	err := service.AllService.AccessPolicyService.UpdateSchedule(r)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// ScheduleDelete Supprimer
+// @Tags Politique d'accès
+// @Summary Supprimer la plage horaire d'accès
+// @Description Supprimer la plage horaire d'accès
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AccessScheduleForm true "Informations sur la plage horaire"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/delete [post]
+// @Security token
+func (ct *AccessPolicy) ScheduleDelete(c *gin.Context) {
+	f := &admin.AccessScheduleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.AccessPolicyService.ScheduleInfoById(f.Id)
+	if r.Id > 0 {
+		err := service.AllService.AccessPolicyService.DeleteSchedule(r)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// TestSchedule Tester une date
+// @Tags Politique d'accès
+// @Summary Tester une date
+// @Description Indique si un utilisateur ou un groupe peut se connecter à cette date (maintenant par défaut)
+// @Accept  json
+// @Produce  json
+// @Param body body admin.ScheduleTestForm true "Utilisateur ou groupe et date"
+// @Success 200 {object} response.Response{data=service.ScheduleCheckResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/access_schedule/testTime [post]
+// @Security token
+func (ct *AccessPolicy) TestSchedule(c *gin.Context) {
+	f := &admin.ScheduleTestForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.UserId == 0 && f.GroupId == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	t := time.Now()
+	if f.Time != "" {
+		pt, err := time.Parse(time.RFC3339, f.Time)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+			return
+		}
+		t = pt
+	}
+	groupId := f.GroupId
+	if f.UserId > 0 {
+		u := service.AllService.UserService.InfoById(f.UserId)
+		if u.Id == 0 {
+			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+			return
+		}
+		groupId = u.GroupId
+	}
+	res := service.AllService.AccessPolicyService.CheckScheduleByIds(f.UserId, groupId, t)
+	response.Success(c, res)
+}
+
+func (ct *AccessPolicy) checkScheduleForm(c *gin.Context, f *admin.AccessScheduleForm) (string, bool) {
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		return errList[0], false
+	}
+	if err := service.AllService.AccessPolicyService.ValidateSchedule(f.Timezone, f.Windows, f.Holidays); err != nil {
+		return response.TranslateMsg(c, "InvalidSchedule") + err.Error(), false
+	}
+	if f.Type == model.AccessTargetTypeUser {
+		if service.AllService.UserService.InfoById(f.ToId).Id == 0 {
+			return response.TranslateMsg(c, "ItemNotFound"), false
+		}
+	} else if service.AllService.GroupService.InfoById(f.ToId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	return "", true
+}
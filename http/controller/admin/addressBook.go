@@ -7,6 +7,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"gorm.io/gorm"
 	"strconv"
@@ -69,6 +70,18 @@ func (ct *AddressBook) Create(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
+	if !service.AllService.AddressBookFolderService.Check(t.CollectionId, t.FolderId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.AddressBookService.CheckCollectionWritable(t.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTagsJson(t.CollectionId, t.Tags) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
 
 	ex := service.AllService.AddressBookService.InfoByUserIdAndIdAndCid(t.UserId, t.Id, t.CollectionId)
 	if ex.RowId > 0 {
@@ -76,7 +89,7 @@ func (ct *AddressBook) Create(c *gin.Context) {
 		return
 	}
 
-	err := service.AllService.AddressBookService.Create(t)
+	err := service.AllService.AddressBookService.Create(t, adminAbActor(c))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -141,7 +154,7 @@ func (ct *AddressBook) BatchCreate(c *gin.Context) {
 		}
 		ex := service.AllService.AddressBookService.InfoByUserIdAndIdAndCid(t.UserId, t.Id, t.CollectionId)
 		if ex.RowId == 0 {
-			service.AllService.AddressBookService.Create(t)
+			service.AllService.AddressBookService.Create(t, adminAbActor(c))
 		}
 	}
 
@@ -187,12 +200,16 @@ func (ct *AddressBook) List(c *gin.Context) {
 		if query.CollectionId != nil && *query.CollectionId >= 0 {
 			tx.Where("collection_id = ?", query.CollectionId)
 		}
+		if query.FolderId != nil && *query.FolderId >= 0 {
+			tx.Where("folder_id = ?", query.FolderId)
+		}
 	})
 
 	abCIds := make([]uint, 0)
 	for _, ab := range res.AddressBooks {
 		abCIds = append(abCIds, ab.CollectionId)
 	}
+	service.AllService.PresenceService.FillAddressBookOnline(res.AddressBooks)
 	response.Success(c, res)
 }
 
@@ -232,7 +249,19 @@ func (ct *AddressBook) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
-	err := service.AllService.AddressBookService.UpdateAll(t)
+	if !service.AllService.AddressBookFolderService.Check(t.CollectionId, t.FolderId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.AddressBookService.CheckCollectionWritable(t.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTagsJson(t.CollectionId, t.Tags) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.AddressBookService.UpdateAll(t, adminAbActor(c))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -268,7 +297,7 @@ func (ct *AddressBook) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
-	err := service.AllService.AddressBookService.Delete(t)
+	err := service.AllService.AddressBookService.Delete(t, adminAbActor(c))
 	if err == nil {
 		response.Success(c, nil)
 		return
@@ -335,6 +364,14 @@ func (ct *AddressBook) BatchCreateFromPeers(c *gin.Context) {
 			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 			return
 		}
+		if collection.IsDynamic() {
+			response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+			return
+		}
+		if !service.AllService.GlobalTagService.CheckTags(f.CollectionId, f.Tags) {
+			response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+			return
+		}
 	}
 
 	pl := int64(len(f.PeerIds))
@@ -356,7 +393,12 @@ func (ct *AddressBook) BatchCreateFromPeers(c *gin.Context) {
 		if ex.RowId != 0 {
 			continue
 		}
-		service.AllService.AddressBookService.Create(ab)
+		service.AllService.AddressBookService.Create(ab, adminAbActor(c))
 	}
 	response.Success(c, nil)
 }
+
+// adminAbActor opérateur des modifications de carnet d'adresses faites depuis l'administration
+func adminAbActor(c *gin.Context) *service.AbActor {
+	return service.NewAbActor(service.AllService.UserService.CurUser(c), model.AbChangeSourceAdmin)
+}
@@ -40,7 +40,7 @@ func (abc *AddressBookCollection) Detail(c *gin.Context) {
 // Create Créer une collection
 // @Tags Collection de carnets d'adresses
 // @Summary Créer une collection
-// @Description Créer une collection
+// @Description Créer une collection. Avec query (expression de recherche sur les appareils), la collection est dynamique et en lecture seule pour les clients
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollection true "Informations sur la collection"
@@ -64,6 +64,10 @@ func (abc *AddressBookCollection) Create(c *gin.Context) {
 		return
 	}
 	t := f
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(t); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	err := service.AllService.AddressBookService.CreateCollection(t)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -103,7 +107,7 @@ func (abc *AddressBookCollection) List(c *gin.Context) {
 // Update Modifier
 // @Tags Collection de carnets d'adresses
 // @Summary Modifier la collection
-// @Description Modifier la collection
+// @Description Modifier la collection. Une collection ne devient dynamique que si elle est vide
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollection true "Informations sur la collection"
@@ -127,6 +131,14 @@ func (abc *AddressBookCollection) Update(c *gin.Context) {
 		return
 	}
 	t := f //f.ToAddressBookCollection()
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(t); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	if err := service.AllService.AddressBookService.CheckCollectionCanBeDynamic(t); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
 	err := service.AllService.AddressBookService.UpdateCollection(t)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -163,10 +175,48 @@ func (abc *AddressBookCollection) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
-	err := service.AllService.AddressBookService.DeleteCollection(ex)
+	err := service.AllService.AddressBookService.DeleteCollection(ex, adminAbActor(c))
 	if err == nil {
 		response.Success(c, nil)
 		return
 	}
 	response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 }
+
+// Preview Aperçu d'une collection dynamique
+// @Tags Collection de carnets d'adresses
+// @Summary Aperçu d'une collection dynamique
+// @Description Appareils correspondant à l'expression, tels que les clients les verront pour le propriétaire user_id
+// @Accept  json
+// @Produce  json
+// @Param query query string true "Expression de recherche"
+// @Param user_id query int true "ID du propriétaire"
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Success 200 {object} response.Response{data=model.AddressBookList}
+// @Failure 500 {object} response.Response
+// @Router /admin/address_book_collection/preview [get]
+// @Security token
+func (abc *AddressBookCollection) Preview(c *gin.Context) {
+	query := &admin.AddressBookCollectionPreviewQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.UserId == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	t := &model.AddressBookCollection{UserId: query.UserId, Query: query.Query}
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(t); err != nil || !t.IsDynamic() {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery"))
+		return
+	}
+	res, err := service.AllService.AddressBookService.DynamicPeers(t, query.Page, query.PageSize)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	service.AllService.PresenceService.FillAddressBookOnline(res.AddressBooks)
+	response.Success(c, res)
+}
@@ -72,7 +72,7 @@ func (abcr *AddressBookCollectionRule) Detail(c *gin.Context) {
 // Create Créer une règle
 // @Tags Règles du carnet d'adresses
 // @Summary Créer une règle
-// @Description Créer une règle
+// @Description Créer une règle. start_at et end_at (timestamps, 0: sans limite) limitent la durée du partage
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollectionRule true "Informations sur la règle"
@@ -116,6 +116,10 @@ func (abcr *AddressBookCollectionRule) CheckForm(t *model.AddressBookCollectionR
 	if t.CollectionId > 0 && !service.AllService.AddressBookService.CheckCollectionOwner(t.UserId, t.CollectionId) {
 		return "ParamsError", false
 	}
+	// Règle sur un dossier: le dossier doit appartenir à la collection
+	if t.FolderId > 0 && !service.AllService.AddressBookFolderService.Check(t.CollectionId, t.FolderId) {
+		return "ParamsError", false
+	}
 
 	//check to_id
 	if t.Type == model.ShareAddressBookRuleTypePersonal {
@@ -134,8 +138,14 @@ func (abcr *AddressBookCollectionRule) CheckForm(t *model.AddressBookCollectionR
 	} else {
 		return "ParamsError", false
 	}
+	// Période de validité, notified_at est géré par la tâche d'expiration
+	if !service.AllService.AddressBookService.RuleWindowValid(t) {
+		return "InvalidRuleWindow", false
+	}
+	t.NotifiedAt = 0
+	t.ExpiredAt = 0
 	// Vérification des doublons
-	ex := service.AllService.AddressBookService.RuleInfoByToIdAndCid(t.Type, t.ToId, t.CollectionId)
+	ex := service.AllService.AddressBookService.RuleInfoByToIdAndFolder(t.Type, t.ToId, t.CollectionId, t.FolderId)
 	if t.Id == 0 && ex.Id > 0 {
 		return "ItemExists", false
 	}
@@ -148,7 +158,7 @@ func (abcr *AddressBookCollectionRule) CheckForm(t *model.AddressBookCollectionR
 // Update Modifier
 // @Tags Règles du carnet d'adresses
 // @Summary Modifier la règle
-// @Description Modifier la règle
+// @Description Modifier la règle. Changer end_at réactive une règle désactivée par l'expiration, sauf si status est fourni, et relance la notification
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollectionRule true "Informations sur la règle"
@@ -0,0 +1,111 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type AddressBookHistory struct {
+}
+
+// List Liste
+// @Tags Historique du carnet d'adresses
+// @Summary Historique des modifications
+// @Description Modifications des adresses et étiquettes, avec l'état avant et après. Les mots de passe sont masqués
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param user_id query int false "Propriétaire du carnet"
+// @Param collection_id query int false "Collection (0: carnet par défaut)"
+// @Param entity query string false "peer ou tag"
+// @Param entity_key query string false "ID de l'appareil ou de l'étiquette"
+// @Param source query string false "client, admin, web ou system"
+// @Param from query int false "Depuis (timestamp)"
+// @Param to query int false "Jusqu'à (timestamp)"
+// @Success 200 {object} response.Response{data=model.AddressBookChangeList}
+// @Failure 500 {object} response.Response
+// @Router /admin/address_book_history/list [get]
+// @Security token
+func (ct *AddressBookHistory) List(c *gin.Context) {
+	query := &admin.AddressBookHistoryQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.AddressBookHistoryService.List(query.Page, query.PageSize, addressBookHistoryWhere(query))
+	response.Success(c, res)
+}
+
+// addressBookHistoryWhere filtres de l'historique
+func addressBookHistoryWhere(query *admin.AddressBookHistoryQuery) func(tx *gorm.DB) {
+	return func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.CollectionId >= 0 {
+			tx.Where("collection_id = ?", query.CollectionId)
+		}
+		if query.Entity != "" {
+			tx.Where("entity = ?", query.Entity)
+		}
+		if query.EntityKey != "" {
+			tx.Where("entity_key = ?", query.EntityKey)
+		}
+		if query.Source != "" {
+			tx.Where("source = ?", query.Source)
+		}
+		if query.From > 0 {
+			tx.Where("changed_at >= ?", query.From)
+		}
+		if query.To > 0 {
+			tx.Where("changed_at <= ?", query.To)
+		}
+	}
+}
+
+// Restore Restaurer
+// @Tags Historique du carnet d'adresses
+// @Summary Restaurer un carnet à une date
+// @Description Remet les adresses et étiquettes d'un carnet (propriétaire + collection) dans leur état au timestamp donné. La restauration est elle-même enregistrée dans l'historique
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AddressBookRestoreForm true "Restauration"
+// @Success 200 {object} response.Response{data=model.AddressBookRestoreResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/address_book_history/restore [post]
+// @Security token
+func (ct *AddressBookHistory) Restore(c *gin.Context) {
+	f := &admin.AddressBookRestoreForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	errList = global.Validator.ValidVar(c, f.UserId, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if f.CollectionId > 0 && !service.AllService.AddressBookService.CheckCollectionOwner(f.UserId, f.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	res, err := service.AllService.AddressBookHistoryService.Restore(f.UserId, f.CollectionId, f.Timestamp, adminAbActor(c))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	audit.LogBulkOperation(c, u.Id, "address_book_restore", res.Peers+res.Tags)
+	response.Success(c, res)
+}
@@ -23,6 +23,9 @@ type Audit struct {
 // @Param page_size query int false "Taille de la page"
 // @Param peer_id query int false "Appareil cible"
 // @Param from_peer query int false "Appareil source"
+// @Param country query string false "Code pays (GeoIP)"
+// @Param q query string false "Expression de recherche"
+// @Param sort query string false "Tri sur un champ indexé, ex: -id"
 // @Success 200 {object} response.Response{data=model.AuditConnList}
 // @Failure 500 {object} response.Response
 // @Router /admin/audit_conn/list [get]
@@ -33,6 +36,11 @@ func (a *Audit) ConnList(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	search, err := service.AllService.SearchService.Compile(service.SearchResourceAuditConn, query.Q, query.Sort)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	res := service.AllService.AuditService.AuditConnList(query.Page, query.PageSize, func(tx *gorm.DB) {
 		if query.PeerId != "" {
 			tx.Where("peer_id like ?", "%"+query.PeerId+"%")
@@ -40,6 +48,12 @@ func (a *Audit) ConnList(c *gin.Context) {
 		if query.FromPeer != "" {
 			tx.Where("from_peer like ?", "%"+query.FromPeer+"%")
 		}
+		if query.Country != "" {
+			tx.Where("geo_country = ?", query.Country)
+		}
+		if search != nil {
+			search(tx)
+		}
 		tx.Order("id desc")
 	})
 	response.Success(c, res)
@@ -122,6 +136,8 @@ func (a *Audit) BatchConnDelete(c *gin.Context) {
 // @Param page_size query int false "Taille de la page"
 // @Param peer_id query int false "Appareil cible"
 // @Param from_peer query int false "Appareil source"
+// @Param q query string false "Expression de recherche"
+// @Param sort query string false "Tri sur un champ indexé, ex: -id"
 // @Success 200 {object} response.Response{data=model.AuditFileList}
 // @Failure 500 {object} response.Response
 // @Router /admin/audit_file/list [get]
@@ -132,6 +148,11 @@ func (a *Audit) FileList(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	search, err := service.AllService.SearchService.Compile(service.SearchResourceAuditFile, query.Q, query.Sort)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	res := service.AllService.AuditService.AuditFileList(query.Page, query.PageSize, func(tx *gorm.DB) {
 		if query.PeerId != "" {
 			tx.Where("peer_id like ?", "%"+query.PeerId+"%")
@@ -139,6 +160,9 @@ func (a *Audit) FileList(c *gin.Context) {
 		if query.FromPeer != "" {
 			tx.Where("from_peer like ?", "%"+query.FromPeer+"%")
 		}
+		if search != nil {
+			search(tx)
+		}
 		tx.Order("id desc")
 	})
 	response.Success(c, res)
@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+)
+
+type ClientVersion struct {
+}
+
+// Policies Politiques de version
+// @Tags Version client
+// @Summary Politiques de version par plateforme
+// @Description Versions minimale et recommandée du client RustDesk par plateforme
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]model.ClientVersionPolicy}
+// @Failure 500 {object} response.Response
+// @Router /admin/client_version/policies [get]
+// @Security token
+func (ct *ClientVersion) Policies(c *gin.Context) {
+	response.Success(c, service.AllService.ClientVersionService.Policies())
+}
+
+// Save Enregistrer une politique
+// @Tags Version client
+// @Summary Enregistrer la politique d'une plateforme
+// @Description Crée ou remplace la politique de la plateforme
+// @Accept  json
+// @Produce  json
+// @Param body body admin.ClientVersionPolicyForm true "Politique de version"
+// @Success 200 {object} response.Response{data=model.ClientVersionPolicy}
+// @Failure 500 {object} response.Response
+// @Router /admin/client_version/save [post]
+// @Security token
+func (ct *ClientVersion) Save(c *gin.Context) {
+	f := &admin.ClientVersionPolicyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	p := f.ToClientVersionPolicy()
+	if err := service.AllService.ClientVersionService.ValidatePolicy(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if err := service.AllService.ClientVersionService.SavePolicy(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, p)
+}
+
+// Delete Supprimer une politique
+// @Tags Version client
+// @Summary Supprimer une politique
+// @Description Supprimer une politique
+// @Accept  json
+// @Produce  json
+// @Param body body admin.ClientVersionPolicyForm true "Politique de version"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/client_version/delete [post]
+// @Security token
+func (ct *ClientVersion) Delete(c *gin.Context) {
+	f := &admin.ClientVersionPolicyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	p := service.AllService.ClientVersionService.PolicyInfoById(f.Id)
+	if p.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.ClientVersionService.DeletePolicy(p); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Report Rapport de conformité
+// @Tags Version client
+// @Summary Rapport de conformité des versions
+// @Description Répartition des appareils par plateforme et version, avec les versions obsolètes signalées
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=[]model.ClientVersionPlatformReport}
+// @Failure 500 {object} response.Response
+// @Router /admin/client_version/report [get]
+// @Security token
+func (ct *ClientVersion) Report(c *gin.Context) {
+	response.Success(c, service.AllService.ClientVersionService.Report())
+}
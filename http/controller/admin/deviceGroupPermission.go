@@ -0,0 +1,176 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type DeviceGroupPermission struct {
+}
+
+// List Liste
+// @Tags Groupe de périphériques
+// @Summary Liste des permissions sur les groupes de périphériques
+// @Description Liste des permissions sur les groupes de périphériques
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param device_group_id query int false "ID du groupe de périphériques"
+// @Param type query int false "Type (1: utilisateur, 2: groupe)"
+// @Param to_id query int false "ID de l'utilisateur ou du groupe"
+// @Success 200 {object} response.Response{data=model.DeviceGroupPermissionList}
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_permission/list [get]
+// @Security token
+func (ct *DeviceGroupPermission) List(c *gin.Context) {
+	query := &admin.DeviceGroupPermissionQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.DeviceGroupPermissionService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.DeviceGroupId > 0 {
+			tx.Where("device_group_id = ?", query.DeviceGroupId)
+		}
+		if query.Type > 0 {
+			tx.Where("type = ?", query.Type)
+		}
+		if query.ToId > 0 {
+			tx.Where("to_id = ?", query.ToId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// Create Créer une permission
+// @Tags Groupe de périphériques
+// @Summary Donner accès à un groupe de périphériques
+// @Description Donne à un utilisateur ou un groupe l'accès en lecture ou connexion aux appareils du groupe
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupPermissionForm true "Informations sur la permission"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_permission/create [post]
+// @Security token
+func (ct *DeviceGroupPermission) Create(c *gin.Context) {
+	f := &admin.DeviceGroupPermissionForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	f.Id = 0
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	err := service.AllService.DeviceGroupPermissionService.Create(f.ToDeviceGroupPermission())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Modifier
+// @Tags Groupe de périphériques
+// @Summary Modifier la permission
+// @Description Modifier la permission
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupPermissionForm true "Informations sur la permission"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_permission/update [post]
+// @Security token
+func (ct *DeviceGroupPermission) Update(c *gin.Context) {
+	f := &admin.DeviceGroupPermissionForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	ex := service.AllService.DeviceGroupPermissionService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToDeviceGroupPermission()
+	r.CreatedAt = ex.CreatedAt
+	err := service.AllService.DeviceGroupPermissionService.Update(r)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Groupe de périphériques
+// @Summary Supprimer la permission
+// @Description Supprimer la permission
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupPermissionForm true "Informations sur la permission"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_permission/delete [post]
+// @Security token
+func (ct *DeviceGroupPermission) Delete(c *gin.Context) {
+	f := &admin.DeviceGroupPermissionForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.DeviceGroupPermissionService.InfoById(f.Id)
+	if r.Id > 0 {
+		err := service.AllService.DeviceGroupPermissionService.Delete(r)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+func (ct *DeviceGroupPermission) checkForm(c *gin.Context, f *admin.DeviceGroupPermissionForm) (string, bool) {
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		return errList[0], false
+	}
+	if service.AllService.GroupService.DeviceGroupInfoById(f.DeviceGroupId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	if f.Type == model.AccessTargetTypeUser {
+		if service.AllService.UserService.InfoById(f.ToId).Id == 0 {
+			return response.TranslateMsg(c, "ItemNotFound"), false
+		}
+	} else if service.AllService.GroupService.InfoById(f.ToId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	if service.AllService.DeviceGroupPermissionService.Exists(f.DeviceGroupId, f.Type, f.ToId, f.Id) {
+		return response.TranslateMsg(c, "ItemExists"), false
+	}
+	return "", true
+}
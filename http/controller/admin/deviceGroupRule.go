@@ -0,0 +1,215 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+type DeviceGroupRule struct {
+}
+
+// List Liste
+// @Tags Groupe de périphériques
+// @Summary Liste des règles d'affectation automatique
+// @Description Les règles sont évaluées par ordre croissant, la première qui correspond s'applique
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param device_group_id query int false "ID du groupe de périphériques"
+// @Success 200 {object} response.Response{data=model.DeviceGroupRuleList}
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/list [get]
+// @Security token
+func (ct *DeviceGroupRule) List(c *gin.Context) {
+	query := &admin.DeviceGroupRuleQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.DeviceGroupRuleService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.DeviceGroupId > 0 {
+			tx.Where("device_group_id = ?", query.DeviceGroupId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// Detail Règle
+// @Tags Groupe de périphériques
+// @Summary Détails de la règle d'affectation
+// @Description Détails de la règle d'affectation
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response{data=model.DeviceGroupRule}
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/detail/{id} [get]
+// @Security token
+func (ct *DeviceGroupRule) Detail(c *gin.Context) {
+	id := c.Param("id")
+	iid, _ := strconv.Atoi(id)
+	r := service.AllService.DeviceGroupRuleService.InfoById(uint(iid))
+	if r.Id > 0 {
+		response.Success(c, r)
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// Create Créer une règle
+// @Tags Groupe de périphériques
+// @Summary Créer une règle d'affectation
+// @Description Créer une règle d'affectation
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupRuleForm true "Informations sur la règle"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/create [post]
+// @Security token
+func (ct *DeviceGroupRule) Create(c *gin.Context) {
+	f := &admin.DeviceGroupRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	err := service.AllService.DeviceGroupRuleService.Create(f.ToDeviceGroupRule())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Modifier
+// @Tags Groupe de périphériques
+// @Summary Modifier la règle d'affectation
+// @Description Modifier la règle d'affectation
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupRuleForm true "Informations sur la règle"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/update [post]
+// @Security token
+func (ct *DeviceGroupRule) Update(c *gin.Context) {
+	f := &admin.DeviceGroupRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	ex := service.AllService.DeviceGroupRuleService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToDeviceGroupRule()
+	r.CreatedAt = ex.CreatedAt
+	err := service.AllService.DeviceGroupRuleService.Update(r)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Groupe de périphériques
+// @Summary Supprimer la règle d'affectation
+// @Description Supprimer la règle d'affectation
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupRuleForm true "Informations sur la règle"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/delete [post]
+// @Security token
+func (ct *DeviceGroupRule) Delete(c *gin.Context) {
+	f := &admin.DeviceGroupRuleForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.DeviceGroupRuleService.InfoById(f.Id)
+	if r.Id > 0 {
+		err := service.AllService.DeviceGroupRuleService.Delete(r)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// Apply Appliquer à tous les appareils
+// @Tags Groupe de périphériques
+// @Summary Réappliquer les règles à tous les appareils
+// @Description Avec dry_run, renvoie uniquement les changements qui seraient effectués
+// @Accept  json
+// @Produce  json
+// @Param body body admin.DeviceGroupRuleApplyForm true "Options"
+// @Success 200 {object} response.Response{data=[]service.DeviceGroupAssignment}
+// @Failure 500 {object} response.Response
+// @Router /admin/device_group_rule/apply [post]
+// @Security token
+func (ct *DeviceGroupRule) Apply(c *gin.Context) {
+	f := &admin.DeviceGroupRuleApplyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res, err := service.AllService.DeviceGroupRuleService.ApplyAll(f.DryRun)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	if !f.DryRun && len(res) > 0 {
+		u := service.AllService.UserService.CurUser(c)
+		audit.LogBulkOperation(c, u.Id, "device_group_rule_apply", len(res))
+	}
+	response.Success(c, res)
+}
+
+func (ct *DeviceGroupRule) checkForm(c *gin.Context, f *admin.DeviceGroupRuleForm) (string, bool) {
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		return errList[0], false
+	}
+	if err := service.AllService.DeviceGroupRuleService.Validate(f.ToDeviceGroupRule()); err != nil {
+		return response.TranslateMsg(c, "ParamsError") + err.Error(), false
+	}
+	if service.AllService.GroupService.DeviceGroupInfoById(f.DeviceGroupId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	if f.UserGroupId > 0 && service.AllService.GroupService.InfoById(f.UserGroupId).Id == 0 {
+		return response.TranslateMsg(c, "ItemNotFound"), false
+	}
+	return "", true
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+)
+
+type Geoip struct {
+}
+
+// Status État
+// @Tags GeoIP
+// @Summary État des bases GeoIP
+// @Description Fichiers mmdb configurés, type de base et date de construction
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/geoip/status [get]
+// @Security token
+func (ct *Geoip) Status(c *gin.Context) {
+	response.Success(c, gin.H{
+		"enable": global.Config.Geoip.Enable,
+		"files":  service.AllService.GeoipService.Status(),
+	})
+}
+
+// Reload Recharger
+// @Tags GeoIP
+// @Summary Recharger les bases GeoIP
+// @Description Recharge les fichiers modifiés sans attendre la vérification périodique
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/geoip/reload [post]
+// @Security token
+func (ct *Geoip) Reload(c *gin.Context) {
+	if !global.Config.Geoip.Enable {
+		response.Fail(c, 101, response.TranslateMsg(c, "GeoipDisabled"))
+		return
+	}
+	service.AllService.GeoipService.Reload()
+	response.Success(c, service.AllService.GeoipService.Status())
+}
+
+// Lookup Rechercher
+// @Tags GeoIP
+// @Summary Tester une adresse IP
+// @Description Tester une adresse IP
+// @Accept  json
+// @Produce  json
+// @Param ip query string true "Adresse IP"
+// @Success 200 {object} response.Response{data=model.GeoInfo}
+// @Failure 500 {object} response.Response
+// @Router /admin/geoip/lookup [get]
+// @Security token
+func (ct *Geoip) Lookup(c *gin.Context) {
+	query := &admin.GeoipLookupQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if !global.Config.Geoip.Enable {
+		response.Fail(c, 101, response.TranslateMsg(c, "GeoipDisabled"))
+		return
+	}
+	response.Success(c, service.AllService.GeoipService.Lookup(query.Ip))
+}
+
+// Backfill Compléter
+// @Tags GeoIP
+// @Summary Compléter les enregistrements existants
+// @Description Renseigne pays, ville et ASN des appareils, journaux de connexion et connexions qui n'en ont pas encore
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=service.GeoipBackfillResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/geoip/backfill [post]
+// @Security token
+func (ct *Geoip) Backfill(c *gin.Context) {
+	res, err := service.AllService.GeoipService.Backfill()
+	if err == service.ErrGeoipDisabled {
+		response.Fail(c, 101, response.TranslateMsg(c, "GeoipDisabled"))
+		return
+	}
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, res)
+}
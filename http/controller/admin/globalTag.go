@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type GlobalTag struct {
+}
+
+// List Liste
+// @Tags Étiquettes globales
+// @Summary Liste des étiquettes globales
+// @Description Liste des étiquettes globales, disponibles dans tous les carnets d'adresses
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param name query string false "Nom"
+// @Success 200 {object} response.Response{data=model.GlobalTagList}
+// @Failure 500 {object} response.Response
+// @Router /admin/global_tag/list [get]
+// @Security token
+func (ct *GlobalTag) List(c *gin.Context) {
+	query := &admin.GlobalTagQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.GlobalTagService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Name != "" {
+			tx.Where("name like ?", "%"+query.Name+"%")
+		}
+		tx.Order("name asc")
+	})
+	response.Success(c, res)
+}
+
+// Create Créer
+// @Tags Étiquettes globales
+// @Summary Créer une étiquette globale
+// @Description Créer une étiquette globale. Sa couleur remplace celle des étiquettes de même nom dans les collections
+// @Accept  json
+// @Produce  json
+// @Param body body admin.GlobalTagForm true "Informations sur l'étiquette"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/global_tag/create [post]
+// @Security token
+func (ct *GlobalTag) Create(c *gin.Context) {
+	f := &admin.GlobalTagForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	t := f.ToGlobalTag()
+	if service.AllService.GlobalTagService.InfoByName(t.Name).Id > 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemExists"))
+		return
+	}
+	t.Id = 0
+	if err := service.AllService.GlobalTagService.Create(t); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Modifier
+// @Tags Étiquettes globales
+// @Summary Modifier l'étiquette globale
+// @Description Modifier la couleur et la remarque. Le nom, enregistré sur les appareils, ne peut pas être modifié
+// @Accept  json
+// @Produce  json
+// @Param body body admin.GlobalTagForm true "Informations sur l'étiquette"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/global_tag/update [post]
+// @Security token
+func (ct *GlobalTag) Update(c *gin.Context) {
+	f := &admin.GlobalTagForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	ex := service.AllService.GlobalTagService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if f.Name != ex.Name {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if err := service.AllService.GlobalTagService.Update(f.ToGlobalTag()); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Étiquettes globales
+// @Summary Supprimer l'étiquette globale
+// @Description Supprimer l'étiquette globale, les appareils gardent le nom de l'étiquette
+// @Accept  json
+// @Produce  json
+// @Param body body admin.GlobalTagForm true "Informations sur l'étiquette"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/global_tag/delete [post]
+// @Security token
+func (ct *GlobalTag) Delete(c *gin.Context) {
+	f := &admin.GlobalTagForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.GlobalTagService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.GlobalTagService.Delete(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Merge Fusionner
+// @Tags Étiquettes globales
+// @Summary Fusionner des étiquettes dans une étiquette globale
+// @Description Remplace sur les appareils les étiquettes choisies (tag_ids, ou toutes les étiquettes nommées names) par l'étiquette globale, puis les supprime
+// @Accept  json
+// @Produce  json
+// @Param body body admin.GlobalTagMergeForm true "Étiquettes à fusionner"
+// @Success 200 {object} response.Response{data=model.GlobalTagMergeReport}
+// @Failure 500 {object} response.Response
+// @Router /admin/global_tag/merge [post]
+// @Security token
+func (ct *GlobalTag) Merge(c *gin.Context) {
+	f := &admin.GlobalTagMergeForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if len(f.TagIds) == 0 && len(f.Names) == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	g := service.AllService.GlobalTagService.InfoById(f.GlobalTagId)
+	if g.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	report, err := service.AllService.GlobalTagService.Merge(g, f.TagIds, f.Names, adminAbActor(c))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	audit.LogBulkOperation(c, u.Id, "global_tag_merge", report.TagsMerged)
+	response.Success(c, report)
+}
@@ -88,6 +88,12 @@ func (ct *Login) Login(c *gin.Context) {
 		return
 	}
 
+	if err, reason := service.AllService.AccessPolicyService.CheckAccess(u, clientIp); err != nil {
+		audit.LogAccessDenied(c, u.Id, "admin_login", reason)
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
 	ut := service.AllService.UserService.Login(u, &model.LoginLog{
 		UserId:   u.Id,
 		Client:   model.LoginLogClientWebAdmin,
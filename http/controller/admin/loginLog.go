@@ -46,6 +46,8 @@ func (ct *LoginLog) Detail(c *gin.Context) {
 // @Param page query int false "Numéro de page"
 // @Param page_size query int false "Taille de la page"
 // @Param user_id query int false "ID utilisateur"
+// @Param country query string false "Code pays (GeoIP)"
+// @Param asn query int false "ASN (GeoIP)"
 // @Success 200 {object} response.Response{data=model.LoginLogList}
 // @Failure 500 {object} response.Response
 // @Router /admin/login_log/list [get]
@@ -60,6 +62,12 @@ func (ct *LoginLog) List(c *gin.Context) {
 		if query.UserId > 0 {
 			tx.Where("user_id = ?", query.UserId)
 		}
+		if query.Country != "" {
+			tx.Where("geo_country = ?", query.Country)
+		}
+		if query.Asn > 0 {
+			tx.Where("geo_asn = ?", query.Asn)
+		}
 		tx.Order("id desc")
 	})
 	response.Success(c, res)
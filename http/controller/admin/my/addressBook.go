@@ -6,6 +6,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"gorm.io/gorm"
 )
@@ -51,12 +52,16 @@ func (ct *AddressBook) List(c *gin.Context) {
 		if query.CollectionId != nil && *query.CollectionId >= 0 {
 			tx.Where("collection_id = ?", query.CollectionId)
 		}
+		if query.FolderId != nil && *query.FolderId >= 0 {
+			tx.Where("folder_id = ?", query.FolderId)
+		}
 	})
 
 	abCIds := make([]uint, 0)
 	for _, ab := range res.AddressBooks {
 		abCIds = append(abCIds, ab.CollectionId)
 	}
+	service.AllService.PresenceService.FillAddressBookOnline(res.AddressBooks)
 	response.Success(c, res)
 }
 
@@ -89,6 +94,18 @@ func (ct *AddressBook) Create(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
+	if !service.AllService.AddressBookFolderService.Check(t.CollectionId, t.FolderId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.AddressBookService.CheckCollectionWritable(t.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTagsJson(t.CollectionId, t.Tags) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
 
 	ex := service.AllService.AddressBookService.InfoByUserIdAndIdAndCid(t.UserId, t.Id, t.CollectionId)
 	if ex.RowId > 0 {
@@ -96,7 +113,7 @@ func (ct *AddressBook) Create(c *gin.Context) {
 		return
 	}
 
-	err := service.AllService.AddressBookService.Create(t)
+	err := service.AllService.AddressBookService.Create(t, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -150,7 +167,19 @@ func (ct *AddressBook) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
-	err := service.AllService.AddressBookService.UpdateAll(t)
+	if !service.AllService.AddressBookFolderService.Check(t.CollectionId, t.FolderId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.AddressBookService.CheckCollectionWritable(t.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTagsJson(t.CollectionId, t.Tags) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.AddressBookService.UpdateAll(t, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -191,7 +220,7 @@ func (ct *AddressBook) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
-	err := service.AllService.AddressBookService.Delete(ex)
+	err := service.AllService.AddressBookService.Delete(ex, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err == nil {
 		response.Success(c, nil)
 		return
@@ -213,6 +242,14 @@ func (ct *AddressBook) BatchCreateFromPeers(c *gin.Context) {
 			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 			return
 		}
+		if collection.IsDynamic() {
+			response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+			return
+		}
+		if !service.AllService.GlobalTagService.CheckTags(f.CollectionId, f.Tags) {
+			response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+			return
+		}
 		if collection.UserId != u.Id {
 			response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 			return
@@ -241,7 +278,7 @@ func (ct *AddressBook) BatchCreateFromPeers(c *gin.Context) {
 		if ex.RowId != 0 {
 			continue
 		}
-		service.AllService.AddressBookService.Create(ab)
+		service.AllService.AddressBookService.Create(ab, service.NewAbActor(u, model.AbChangeSourceWeb))
 	}
 	response.Success(c, nil)
 }
@@ -262,7 +299,18 @@ func (ct *AddressBook) BatchUpdateTags(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
-	err := service.AllService.AddressBookService.BatchUpdateTags(abs.AddressBooks, f.Tags)
+	checked := make(map[uint]bool)
+	for _, ab := range abs.AddressBooks {
+		if checked[ab.CollectionId] {
+			continue
+		}
+		checked[ab.CollectionId] = true
+		if !service.AllService.GlobalTagService.CheckTags(ab.CollectionId, f.Tags) {
+			response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+			return
+		}
+	}
+	err := service.AllService.AddressBookService.BatchUpdateTags(abs.AddressBooks, f.Tags, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -16,7 +16,7 @@ type AddressBookCollection struct {
 // Create Créer une collection
 // @Tags Ma collection
 // @Summary Créer une collection
-// @Description Créer une collection
+// @Description Créer une collection. Avec query (expression de recherche sur les appareils), la collection est dynamique et en lecture seule pour les clients
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollection true "Informations sur la collection"
@@ -37,6 +37,10 @@ func (abc *AddressBookCollection) Create(c *gin.Context) {
 	}
 	u := service.AllService.UserService.CurUser(c)
 	f.UserId = u.Id
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	err := service.AllService.AddressBookService.CreateCollection(f)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -74,7 +78,7 @@ func (abc *AddressBookCollection) List(c *gin.Context) {
 // Update Modifier
 // @Tags Ma collection
 // @Summary Modifier la collection
-// @Description Modifier la collection
+// @Description Modifier la collection. Une collection ne devient dynamique que si elle est vide
 // @Accept  json
 // @Produce  json
 // @Param body body model.AddressBookCollection true "Informations sur la collection"
@@ -111,7 +115,17 @@ func (abc *AddressBookCollection) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
+	// Le propriétaire détermine les appareils visibles d'une collection dynamique
+	f.UserId = u.Id
 
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	if err := service.AllService.AddressBookService.CheckCollectionCanBeDynamic(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
 	err := service.AllService.AddressBookService.UpdateCollection(f)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
@@ -153,10 +167,45 @@ func (abc *AddressBookCollection) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
-	err := service.AllService.AddressBookService.DeleteCollection(ex)
+	err := service.AllService.AddressBookService.DeleteCollection(ex, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err == nil {
 		response.Success(c, nil)
 		return
 	}
 	response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 }
+
+// Preview Aperçu d'une collection dynamique
+// @Tags Ma collection
+// @Summary Aperçu d'une collection dynamique
+// @Description Appareils correspondant à l'expression, tels que les clients les verront
+// @Accept  json
+// @Produce  json
+// @Param query query string true "Expression de recherche"
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Success 200 {object} response.Response{data=model.AddressBookList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_collection/preview [get]
+// @Security token
+func (abc *AddressBookCollection) Preview(c *gin.Context) {
+	query := &admin.AddressBookCollectionPreviewQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	query.UserId = u.Id
+	t := &model.AddressBookCollection{UserId: query.UserId, Query: query.Query}
+	if err := service.AllService.AddressBookService.ValidateCollectionQuery(t); err != nil || !t.IsDynamic() {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery"))
+		return
+	}
+	res, err := service.AllService.AddressBookService.DynamicPeers(t, query.Page, query.PageSize)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	service.AllService.PresenceService.FillAddressBookOnline(res.AddressBooks)
+	response.Success(c, res)
+}
@@ -0,0 +1,197 @@
+package my
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+)
+
+type AddressBookFolder struct {
+}
+
+// List Liste
+// @Tags Mes dossiers de carnet d'adresses
+// @Summary Liste des dossiers
+// @Description Tous les dossiers d'une collection, triés par chemin
+// @Accept  json
+// @Produce  json
+// @Param collection_id query int true "ID de la collection"
+// @Success 200 {object} response.Response{data=model.AddressBookFolderList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_folder/list [get]
+// @Security token
+func (abf *AddressBookFolder) List(c *gin.Context) {
+	query := &admin.AddressBookFolderQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if !service.AllService.AddressBookService.CheckCollectionOwner(u.Id, query.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	response.Success(c, service.AllService.AddressBookFolderService.ListByCollectionId(query.CollectionId))
+}
+
+// Create Créer un dossier
+// @Tags Mes dossiers de carnet d'adresses
+// @Summary Créer un dossier
+// @Description Créer un dossier. parent_id 0: à la racine de la collection
+// @Accept  json
+// @Produce  json
+// @Param body body model.AddressBookFolder true "Informations sur le dossier"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_folder/create [post]
+// @Security token
+func (abf *AddressBookFolder) Create(c *gin.Context) {
+	f := &model.AddressBookFolder{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if !service.AllService.AddressBookService.CheckCollectionOwner(u.Id, f.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	f.Id = 0
+	f.UserId = u.Id
+	if err := service.AllService.AddressBookFolderService.Create(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Renommer
+// @Tags Mes dossiers de carnet d'adresses
+// @Summary Renommer le dossier
+// @Description Renommer le dossier, l'étiquette vue par les clients change aussi
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AddressBookFolderRenameForm true "Nouveau nom"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_folder/update [post]
+// @Security token
+func (abf *AddressBookFolder) Update(c *gin.Context) {
+	f := &admin.AddressBookFolderRenameForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.AddressBookFolderService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	if err := service.AllService.AddressBookFolderService.Rename(ex, f.Name); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Move Déplacer
+// @Tags Mes dossiers de carnet d'adresses
+// @Summary Déplacer le dossier
+// @Description Déplacer le dossier dans un autre dossier de la même collection (parent_id 0: racine)
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AddressBookFolderMoveForm true "Dossier parent"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_folder/move [post]
+// @Security token
+func (abf *AddressBookFolder) Move(c *gin.Context) {
+	f := &admin.AddressBookFolderMoveForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.AddressBookFolderService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	if err := service.AllService.AddressBookFolderService.Move(ex, f.ParentId); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Mes dossiers de carnet d'adresses
+// @Summary Supprimer le dossier
+// @Description Supprimer le dossier. Les sous-dossiers et les adresses remontent dans le dossier parent, les règles du dossier sont supprimées
+// @Accept  json
+// @Produce  json
+// @Param body body model.AddressBookFolder true "Informations sur le dossier"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_folder/delete [post]
+// @Security token
+func (abf *AddressBookFolder) Delete(c *gin.Context) {
+	f := &model.AddressBookFolder{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.AddressBookFolderService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	if err := service.AllService.AddressBookFolderService.Delete(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,100 @@
+package my
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type AddressBookHistory struct {
+}
+
+// List Liste
+// @Tags Mon historique du carnet d'adresses
+// @Summary Historique des modifications de mes carnets
+// @Description Modifications des adresses et étiquettes de mes carnets. Les mots de passe sont masqués
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param collection_id query int false "Collection (0: carnet par défaut)"
+// @Param entity query string false "peer ou tag"
+// @Param entity_key query string false "ID de l'appareil ou de l'étiquette"
+// @Param source query string false "client, admin, web ou system"
+// @Param from query int false "Depuis (timestamp)"
+// @Param to query int false "Jusqu'à (timestamp)"
+// @Success 200 {object} response.Response{data=model.AddressBookChangeList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_history/list [get]
+// @Security token
+func (ct *AddressBookHistory) List(c *gin.Context) {
+	query := &admin.AddressBookHistoryQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	res := service.AllService.AddressBookHistoryService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Where("user_id = ?", u.Id)
+		if query.CollectionId >= 0 {
+			tx.Where("collection_id = ?", query.CollectionId)
+		}
+		if query.Entity != "" {
+			tx.Where("entity = ?", query.Entity)
+		}
+		if query.EntityKey != "" {
+			tx.Where("entity_key = ?", query.EntityKey)
+		}
+		if query.Source != "" {
+			tx.Where("source = ?", query.Source)
+		}
+		if query.From > 0 {
+			tx.Where("changed_at >= ?", query.From)
+		}
+		if query.To > 0 {
+			tx.Where("changed_at <= ?", query.To)
+		}
+	})
+	response.Success(c, res)
+}
+
+// Restore Restaurer
+// @Tags Mon historique du carnet d'adresses
+// @Summary Restaurer un de mes carnets à une date
+// @Description Remet les adresses et étiquettes du carnet dans leur état au timestamp donné
+// @Accept  json
+// @Produce  json
+// @Param body body admin.AddressBookRestoreForm true "Restauration"
+// @Success 200 {object} response.Response{data=model.AddressBookRestoreResult}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book_history/restore [post]
+// @Security token
+func (ct *AddressBookHistory) Restore(c *gin.Context) {
+	f := &admin.AddressBookRestoreForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if f.CollectionId > 0 && !service.AllService.AddressBookService.CheckCollectionOwner(u.Id, f.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	res, err := service.AllService.AddressBookHistoryService.Restore(u.Id, f.CollectionId, f.Timestamp, service.NewAbActor(u, model.AbChangeSourceWeb))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	audit.LogBulkOperation(c, u.Id, "address_book_restore", res.Peers+res.Tags)
+	response.Success(c, res)
+}
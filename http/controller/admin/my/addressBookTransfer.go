@@ -0,0 +1,137 @@
+package my
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// abImportMaxSize taille maximale du fichier importé
+const abImportMaxSize = 20 << 20
+
+// Export Exporter
+// @Tags Mon carnet d'adresses
+// @Summary Exporter un de mes carnets
+// @Description Export au format de l'API RustDesk (peers, tags, tag_colors) en JSON, ou en CSV. Les mots de passe ne sont exportés que sur demande
+// @Accept  json
+// @Produce  octet-stream
+// @Param collection_id query int false "Collection (0: carnet par défaut)"
+// @Param format query string false "Format (json, csv), json par défaut"
+// @Param include_secrets query bool false "Inclure les mots de passe et hash"
+// @Success 200 {file} file
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book/export [get]
+// @Security token
+func (ct *AddressBook) Export(c *gin.Context) {
+	query := &admin.AddressBookExportQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if query.CollectionId > 0 && !service.AllService.AddressBookService.CheckCollectionOwner(u.Id, query.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	format := query.Format
+	if format == "" {
+		format = service.PeerTransferFormatJson
+	}
+	data := service.AllService.AddressBookService.Export(u.Id, query.CollectionId, query.IncludeSecrets)
+	filename := fmt.Sprintf("address_book_%d_%s.%s", query.CollectionId, time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	if format == service.PeerTransferFormatCsv {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+	if err := service.WriteAbTransfer(format, c.Writer, data); err != nil {
+		global.Logger.Error("address book export failed: ", err)
+	}
+	audit.LogDataExported(c, u.Id, "address_book", format, len(data.Peers))
+}
+
+// Import Importer
+// @Tags Mon carnet d'adresses
+// @Summary Importer dans un de mes carnets
+// @Description Import d'un export JSON (format de l'API RustDesk, y compris la réponse de GET /api/ab) ou CSV. Stratégie pour les ID existants: skip (ignorer), overwrite (remplacer), duplicate (ajouter)
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "Fichier JSON ou CSV"
+// @Param collection_id formData int false "Collection (0: carnet par défaut)"
+// @Param format formData string false "Format (json, csv), déduit de l'extension si vide"
+// @Param strategy formData string false "skip, overwrite ou duplicate, skip par défaut"
+// @Param dry_run formData bool false "Simulation sans écriture"
+// @Success 200 {object} response.Response{data=model.AbImportReport}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/address_book/import [post]
+// @Security token
+func (ct *AddressBook) Import(c *gin.Context) {
+	f := &admin.AddressBookImportForm{}
+	if err := c.ShouldBind(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if f.CollectionId > 0 && !service.AllService.AddressBookService.CheckCollectionOwner(u.Id, f.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if file.Size > abImportMaxSize {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+"file too large")
+		return
+	}
+	format := f.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+	src, err := file.Open()
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	defer src.Close()
+	data, err := service.ReadAbTransfer(format, src)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if !service.AllService.AddressBookService.CheckCollectionWritable(f.CollectionId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "AbCollectionDynamic"))
+		return
+	}
+	report, err := service.AllService.AddressBookService.Import(u.Id, f.CollectionId, data, f.Strategy, f.DryRun, service.NewAbActor(u, model.AbChangeSourceWeb))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	if !f.DryRun {
+		audit.LogBulkOperation(c, u.Id, "address_book_import", report.Created+report.Updated)
+	}
+	response.Success(c, report)
+}
@@ -0,0 +1,25 @@
+package my
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+)
+
+type GlobalTag struct {
+}
+
+// List Liste
+// @Tags Mes étiquettes
+// @Summary Étiquettes globales
+// @Description Toutes les étiquettes globales, utilisables dans chaque carnet d'adresses
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.GlobalTagList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/global_tag/list [get]
+// @Security token
+func (ct *GlobalTag) List(c *gin.Context) {
+	response.Success(c, &model.GlobalTagList{GlobalTags: service.AllService.GlobalTagService.All()})
+}
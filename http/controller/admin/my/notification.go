@@ -0,0 +1,65 @@
+package my
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type Notification struct {
+}
+
+// List Liste
+// @Tags Mes notifications
+// @Summary Mes notifications
+// @Description Notifications, les plus récentes en premier, avec le nombre de non lues
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param unread query bool false "Non lues uniquement"
+// @Success 200 {object} response.Response{data=model.NotificationList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/notification/list [get]
+// @Security token
+func (ct *Notification) List(c *gin.Context) {
+	query := &admin.NotificationQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	res := service.AllService.NotificationService.List(u.Id, query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Unread {
+			tx.Where("read_at = 0")
+		}
+	})
+	response.Success(c, res)
+}
+
+// Read Marquer comme lu
+// @Tags Mes notifications
+// @Summary Marquer comme lu
+// @Description Marque les notifications indiquées comme lues, toutes si ids est vide
+// @Accept  json
+// @Produce  json
+// @Param body body admin.NotificationReadForm true "Notifications"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/notification/read [post]
+// @Security token
+func (ct *Notification) Read(c *gin.Context) {
+	f := &admin.NotificationReadForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.NotificationService.MarkRead(u.Id, f.Ids); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -2,11 +2,12 @@ package my
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	adminCtl "github.com/RobertLesgros/rustdesk-interface/v2/http/controller/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"gorm.io/gorm"
-	"time"
 )
 
 type Peer struct {
@@ -24,6 +25,12 @@ type Peer struct {
 // @Param id query string false "ID"
 // @Param hostname query string false "Nom d'hôte"
 // @Param uuids query string false "uuids séparés par des virgules"
+// @Param online query int false "En ligne (1: en ligne, 2: hors ligne)"
+// @Param version query string false "Version du client"
+// @Param compliance query string false "Conformité de version (ok, outdated, unsupported, unknown)"
+// @Param attr query string false "Champs personnalisés (JSON), ex: {\"cost\":\">=100\"}"
+// @Param q query string false "Expression de recherche"
+// @Param sort query string false "Tri sur un champ indexé, ex: -id"
 // @Success 200 {object} response.Response{data=model.PeerList}
 // @Failure 500 {object} response.Response
 // @Router /admin/my/peer/list [get]
@@ -34,26 +41,22 @@ func (ct *Peer) List(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	where, err := adminCtl.PeerQueryWhere(query)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	u := service.AllService.UserService.CurUser(c)
 	res := service.AllService.PeerService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
 		tx.Where("user_id = ?", u.Id)
-		if query.TimeAgo > 0 {
-			lt := time.Now().Unix() - int64(query.TimeAgo)
-			tx.Where("last_online_time < ?", lt)
-		}
-		if query.TimeAgo < 0 {
-			lt := time.Now().Unix() + int64(query.TimeAgo)
-			tx.Where("last_online_time > ?", lt)
-		}
-		if query.Id != "" {
-			tx.Where("id like ?", "%"+query.Id+"%")
-		}
-		if query.Hostname != "" {
-			tx.Where("hostname like ?", "%"+query.Hostname+"%")
-		}
-		if query.Uuids != "" {
-			tx.Where("uuid in (?)", query.Uuids)
-		}
+		where(tx)
 	})
+	service.AllService.PeerAttributeService.FillPeers(res.Peers)
+	service.AllService.ClientVersionService.FillPeers(res.Peers)
 	response.Success(c, res)
 }
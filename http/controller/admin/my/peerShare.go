@@ -0,0 +1,183 @@
+package my
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type PeerShare struct {
+}
+
+// List Liste
+// @Tags Mes partages d'appareils
+// @Summary Liste de mes partages d'appareils
+// @Description Partages que j'ai créés, ou avec received=1 les partages en cours reçus (directement ou par mon groupe)
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param peer_id query string false "ID de l'appareil"
+// @Param received query int false "1: partages reçus"
+// @Success 200 {object} response.Response{data=model.PeerShareList}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/peer_share/list [get]
+// @Security token
+func (ct *PeerShare) List(c *gin.Context) {
+	query := &admin.PeerShareQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	res := service.AllService.PeerShareService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Received == 1 {
+			tx.Where("(type = ? and to_id = ?) or (type = ? and to_id = ?)",
+				model.ShareAddressBookRuleTypePersonal, u.Id, model.ShareAddressBookRuleTypeGroup, u.GroupId)
+			tx.Where("end_at = 0 or end_at > ?", time.Now().Unix())
+		} else {
+			tx.Where("user_id = ?", u.Id)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id like ?", "%"+query.PeerId+"%")
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create Créer
+// @Tags Mes partages d'appareils
+// @Summary Partager un appareil
+// @Description Partager une de mes entrées de carnet d'adresses (ab_row_id) ou un de mes appareils (peer_id) avec un utilisateur ou un groupe.
+// @Description level 1: lecture, 2: connexion (avec le mot de passe enregistré). end_at (timestamp, 0: sans limite) fixe l'expiration
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response{data=model.PeerShare}
+// @Failure 500 {object} response.Response
+// @Router /admin/my/peer_share/create [post]
+// @Security token
+func (ct *PeerShare) Create(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	f.Id = 0
+	f.UserId = u.Id
+	if !service.AllService.PeerShareService.CanShare(u, f) {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	if err := service.AllService.PeerShareService.Check(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.PeerShareService.Create(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, f)
+}
+
+// Update Modifier
+// @Tags Mes partages d'appareils
+// @Summary Modifier le partage
+// @Description Modifier le niveau et l'expiration de mon partage
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/peer_share/update [post]
+// @Security token
+func (ct *PeerShare) Update(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	ex := service.AllService.PeerShareService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	ex.Level = f.Level
+	ex.EndAt = f.EndAt
+	errList := global.Validator.ValidStruct(c, ex)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := service.AllService.PeerShareService.Check(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.PeerShareService.Update(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Mes partages d'appareils
+// @Summary Supprimer le partage
+// @Description Supprimer mon partage
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/my/peer_share/delete [post]
+// @Security token
+func (ct *PeerShare) Delete(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	ex := service.AllService.PeerShareService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
+	if err := service.AllService.PeerShareService.Delete(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"gorm.io/gorm"
 )
@@ -70,7 +71,11 @@ func (ct *Tag) Create(c *gin.Context) {
 	t := f.ToTag()
 	u := service.AllService.UserService.CurUser(c)
 	t.UserId = u.Id
-	err := service.AllService.TagService.Create(t)
+	if !service.AllService.GlobalTagService.CheckTags(t.CollectionId, []string{t.Name}) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.TagService.Create(t, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -125,7 +130,11 @@ func (ct *Tag) Update(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
-	err := service.AllService.TagService.Update(t)
+	if !service.AllService.GlobalTagService.CheckTags(t.CollectionId, []string{t.Name}) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.TagService.Update(t, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -166,7 +175,7 @@ func (ct *Tag) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
-	err := service.AllService.TagService.Delete(ex)
+	err := service.AllService.TagService.Delete(ex, service.NewAbActor(u, model.AbChangeSourceWeb))
 	if err == nil {
 		response.Success(c, nil)
 		return
@@ -5,6 +5,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"gorm.io/gorm"
 	"strconv"
@@ -30,6 +31,8 @@ func (ct *Peer) Detail(c *gin.Context) {
 	iid, _ := strconv.Atoi(id)
 	u := service.AllService.PeerService.InfoByRowId(uint(iid))
 	if u.RowId > 0 {
+		u.SysinfoTimeline = service.AllService.PeerSysinfoService.Timeline(u.Id, 0, 0)
+		service.AllService.PeerAttributeService.FillPeers([]*model.Peer{u})
 		response.Success(c, u)
 		return
 	}
@@ -59,12 +62,20 @@ func (ct *Peer) Create(c *gin.Context) {
 		response.Fail(c, 101, errList[0])
 		return
 	}
+	if err := service.AllService.PeerAttributeService.ValidateValues(f.Attributes, true); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidAttributeValue")+err.Error())
+		return
+	}
 	p := f.ToPeer()
 	err := service.AllService.PeerService.Create(p)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
 	}
+	if err = service.AllService.PeerAttributeService.SetValues(p.Id, f.Attributes); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
 	response.Success(c, nil)
 }
 
@@ -80,6 +91,14 @@ func (ct *Peer) Create(c *gin.Context) {
 // @Param id query string false "ID"
 // @Param hostname query string false "Nom d'hôte"
 // @Param uuids query string false "uuids séparés par des virgules"
+// @Param online query int false "En ligne (1: en ligne, 2: hors ligne)"
+// @Param stale query int false "Inactif (1: inactif, 2: actif)"
+// @Param version query string false "Version du client"
+// @Param country query string false "Code pays (GeoIP)"
+// @Param compliance query string false "Conformité de version (ok, outdated, unsupported, unknown)"
+// @Param q query string false "Expression de recherche, ex: os:windows AND last_online<7d AND group:\"Finance\""
+// @Param sort query string false "Tri sur un champ indexé, ex: -last_online,id"
+// @Param attr query string false "Champs personnalisés (JSON), ex: {\"asset_tag\":\"AT-1\",\"cost\":\">=100\",\"bought\":\"2025-01-01..2025-12-31\"}"
 // @Success 200 {object} response.Response{data=model.PeerList}
 // @Failure 500 {object} response.Response
 // @Router /admin/peer/list [get]
@@ -90,34 +109,19 @@ func (ct *Peer) List(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
-	res := service.AllService.PeerService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
-		if query.TimeAgo > 0 {
-			lt := time.Now().Unix() - int64(query.TimeAgo)
-			tx.Where("last_online_time < ?", lt)
-		}
-		if query.TimeAgo < 0 {
-			lt := time.Now().Unix() + int64(query.TimeAgo)
-			tx.Where("last_online_time > ?", lt)
-		}
-		if query.Id != "" {
-			tx.Where("id like ?", "%"+query.Id+"%")
-		}
-		if query.Hostname != "" {
-			tx.Where("hostname like ?", "%"+query.Hostname+"%")
-		}
-		if query.Uuids != "" {
-			tx.Where("uuid in (?)", query.Uuids)
-		}
-		if query.Username != "" {
-			tx.Where("username like ?", "%"+query.Username+"%")
-		}
-		if query.Ip != "" {
-			tx.Where("last_online_ip like ?", "%"+query.Ip+"%")
-		}
-		if query.Alias != "" {
-			tx.Where("alias like ?", "%"+query.Alias+"%")
-		}
-	})
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	where, err := PeerQueryWhere(query)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	res := service.AllService.PeerService.List(query.Page, query.PageSize, where)
+	service.AllService.PeerAttributeService.FillPeers(res.Peers)
+	service.AllService.ClientVersionService.FillPeers(res.Peers)
 	response.Success(c, res)
 }
 
@@ -147,12 +151,23 @@ func (ct *Peer) Update(c *gin.Context) {
 		response.Fail(c, 101, errList[0])
 		return
 	}
+	if err := service.AllService.PeerAttributeService.ValidateValues(f.Attributes, false); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidAttributeValue")+err.Error())
+		return
+	}
 	u := f.ToPeer()
 	err := service.AllService.PeerService.Update(u)
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
 	}
+	if len(f.Attributes) > 0 {
+		ex := service.AllService.PeerService.InfoByRowId(f.RowId)
+		if err = service.AllService.PeerAttributeService.SetValues(ex.Id, f.Attributes); err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+			return
+		}
+	}
 	response.Success(c, nil)
 }
 
@@ -238,3 +253,145 @@ func (ct *Peer) SimpleData(c *gin.Context) {
 	})
 	response.Success(c, res)
 }
+
+// PresenceEvents Historique de présence
+// @Tags Appareil
+// @Summary Historique de connexion et déconnexion des appareils
+// @Description Historique de connexion et déconnexion des appareils
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param peer_id query string false "ID de l'appareil"
+// @Success 200 {object} response.Response{data=model.PeerPresenceEventList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/presenceEvents [get]
+// @Security token
+func (ct *Peer) PresenceEvents(c *gin.Context) {
+	query := &admin.PeerPresenceEventQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PresenceService.ListEvents(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+	})
+	response.Success(c, res)
+}
+
+// SysinfoHistory Historique des informations système
+// @Tags Appareil
+// @Summary Instantanés des informations système d'un appareil
+// @Description Un instantané est enregistré à chaque changement des informations système
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param peer_id query string true "ID de l'appareil"
+// @Success 200 {object} response.Response{data=model.PeerSysinfoList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/sysinfoHistory [get]
+// @Security token
+func (ct *Peer) SysinfoHistory(c *gin.Context) {
+	query := &admin.PeerSysinfoQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.PeerId == "" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	res := service.AllService.PeerSysinfoService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Where("peer_id = ?", query.PeerId)
+	})
+	response.Success(c, res)
+}
+
+// SysinfoDiff Changements des informations système
+// @Tags Appareil
+// @Summary Changements des informations système d'un appareil
+// @Description Liste des champs modifiés (ex: OS mis à jour de X vers Y) entre les instantanés
+// @Accept  json
+// @Produce  json
+// @Param peer_id query string true "ID de l'appareil"
+// @Param from query int false "Début (timestamp)"
+// @Param to query int false "Fin (timestamp)"
+// @Success 200 {object} response.Response{data=[]model.PeerSysinfoChange}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/sysinfoDiff [get]
+// @Security token
+func (ct *Peer) SysinfoDiff(c *gin.Context) {
+	query := &admin.PeerSysinfoQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if query.PeerId == "" {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	res := service.AllService.PeerSysinfoService.Timeline(query.PeerId, query.From, query.To)
+	response.Success(c, res)
+}
+
+// PeerQueryWhere construit les conditions de PeerQuery, partagées par la liste, l'export et mes appareils
+func PeerQueryWhere(query *admin.PeerQuery) (func(tx *gorm.DB), error) {
+	search, err := service.AllService.SearchService.Compile(service.SearchResourcePeer, query.Q, query.Sort)
+	if err != nil {
+		return nil, err
+	}
+	attr, err := service.AllService.PeerAttributeService.FilterWhere(query.Attr)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *gorm.DB) {
+		if query.TimeAgo > 0 {
+			lt := time.Now().Unix() - int64(query.TimeAgo)
+			tx.Where("last_online_time < ?", lt)
+		}
+		if query.TimeAgo < 0 {
+			lt := time.Now().Unix() + int64(query.TimeAgo)
+			tx.Where("last_online_time > ?", lt)
+		}
+		if query.Id != "" {
+			tx.Where("id like ?", "%"+query.Id+"%")
+		}
+		if query.Hostname != "" {
+			tx.Where("hostname like ?", "%"+query.Hostname+"%")
+		}
+		if query.Uuids != "" {
+			tx.Where("uuid in (?)", query.Uuids)
+		}
+		if query.Username != "" {
+			tx.Where("username like ?", "%"+query.Username+"%")
+		}
+		if query.Ip != "" {
+			tx.Where("last_online_ip like ?", "%"+query.Ip+"%")
+		}
+		if query.Alias != "" {
+			tx.Where("alias like ?", "%"+query.Alias+"%")
+		}
+		if query.Online > 0 {
+			tx.Where("online = ?", query.Online == 1)
+		}
+		if query.Stale > 0 {
+			tx.Where("stale = ?", query.Stale == 1)
+		}
+		if query.Version != "" {
+			tx.Where("version = ?", query.Version)
+		}
+		if query.Country != "" {
+			tx.Where("geo_country = ?", query.Country)
+		}
+		service.AllService.ClientVersionService.FilterWhere(tx, query.Compliance)
+		if attr != nil {
+			attr(tx)
+		}
+		if search != nil {
+			search(tx)
+		}
+	}, nil
+}
@@ -0,0 +1,131 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+	"time"
+)
+
+type PeerAction struct {
+}
+
+// List Liste
+// @Tags Actions à distance
+// @Summary Liste des actions à distance
+// @Description Statut: 1 en attente, 2 envoyée, 3 confirmée, 4 expirée, 5 annulée, 6 échec
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param peer_id query string false "ID de l'appareil"
+// @Param action query string false "Action (sysinfo, disconnect, unlink)"
+// @Param status query int false "Statut"
+// @Success 200 {object} response.Response{data=model.PeerActionList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_action/list [get]
+// @Security token
+func (ct *PeerAction) List(c *gin.Context) {
+	query := &admin.PeerActionQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerActionService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.PeerId != "" {
+			tx.Where("peer_id = ?", query.PeerId)
+		}
+		if query.Action != "" {
+			tx.Where("action = ?", query.Action)
+		}
+		if query.Status > 0 {
+			tx.Where("status = ?", query.Status)
+		}
+	})
+	response.Success(c, res)
+}
+
+// Create Ajouter une action
+// @Tags Actions à distance
+// @Summary Ajouter une action à distance
+// @Description L'action est envoyée au prochain heartbeat de chaque appareil
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerActionForm true "Action"
+// @Success 200 {object} response.Response{data=[]model.PeerAction}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_action/create [post]
+// @Security token
+func (ct *PeerAction) Create(c *gin.Context) {
+	f := &admin.PeerActionForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	for _, id := range f.PeerIds {
+		if service.AllService.PeerService.FindById(id).RowId == 0 {
+			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound")+": "+id)
+			return
+		}
+	}
+	u := service.AllService.UserService.CurUser(c)
+	params := &service.PeerActionParams{Conns: f.Conns}
+	ttl := time.Duration(f.TtlMinutes) * time.Minute
+	res := make([]*model.PeerAction, 0, len(f.PeerIds))
+	for _, id := range f.PeerIds {
+		a, err := service.AllService.PeerActionService.Enqueue(id, f.Action, params, ttl, u.Id)
+		if err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+			return
+		}
+		audit.LogRemoteAction(c, u.Id, id, f.Action, "queued")
+		res = append(res, a)
+	}
+	response.Success(c, res)
+}
+
+// Cancel Annuler
+// @Tags Actions à distance
+// @Summary Annuler une action en attente
+// @Description Annuler une action en attente
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerActionCancelForm true "Action"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_action/cancel [post]
+// @Security token
+func (ct *PeerAction) Cancel(c *gin.Context) {
+	f := &admin.PeerActionCancelForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	a := service.AllService.PeerActionService.InfoById(f.Id)
+	if a.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.PeerActionService.Cancel(a); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	audit.LogRemoteAction(c, u.Id, a.PeerId, a.Action, "canceled")
+	response.Success(c, nil)
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"strconv"
+)
+
+type PeerAttribute struct {
+}
+
+// List Liste
+// @Tags Champs personnalisés
+// @Summary Liste des champs personnalisés des appareils
+// @Description Liste des champs personnalisés des appareils
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Success 200 {object} response.Response{data=model.PeerAttributeDefList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_attribute/list [get]
+// @Security token
+func (ct *PeerAttribute) List(c *gin.Context) {
+	query := &admin.PeerAttributeDefQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerAttributeService.ListDefs(query.Page, query.PageSize, nil)
+	response.Success(c, res)
+}
+
+// Detail Champ personnalisé
+// @Tags Champs personnalisés
+// @Summary Détails du champ personnalisé
+// @Description Détails du champ personnalisé
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response{data=model.PeerAttributeDef}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_attribute/detail/{id} [get]
+// @Security token
+func (ct *PeerAttribute) Detail(c *gin.Context) {
+	id := c.Param("id")
+	iid, _ := strconv.Atoi(id)
+	d := service.AllService.PeerAttributeService.DefInfoById(uint(iid))
+	if d.Id > 0 {
+		response.Success(c, d)
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// Create Créer un champ personnalisé
+// @Tags Champs personnalisés
+// @Summary Créer un champ personnalisé
+// @Description Créer un champ personnalisé
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerAttributeDefForm true "Informations sur le champ"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_attribute/create [post]
+// @Security token
+func (ct *PeerAttribute) Create(c *gin.Context) {
+	f := &admin.PeerAttributeDefForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	f.Id = 0
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	err := service.AllService.PeerAttributeService.CreateDef(f.ToPeerAttributeDef())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Modifier
+// @Tags Champs personnalisés
+// @Summary Modifier le champ personnalisé
+// @Description Le nom et le type ne peuvent pas être modifiés. Refusé si des valeurs existantes ne respectent plus les options, le format ou les bornes
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerAttributeDefForm true "Informations sur le champ"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_attribute/update [post]
+// @Security token
+func (ct *PeerAttribute) Update(c *gin.Context) {
+	f := &admin.PeerAttributeDefForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	ex := service.AllService.PeerAttributeService.DefInfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	// Les valeurs existantes dépendent du nom et du type
+	f.Name = ex.Name
+	f.Type = ex.Type
+	if msg, ok := ct.checkForm(c, f); !ok {
+		response.Fail(c, 101, msg)
+		return
+	}
+	d := f.ToPeerAttributeDef()
+	d.CreatedAt = ex.CreatedAt
+	// Les valeurs déjà saisies doivent rester valides avec les nouvelles options, format ou bornes
+	if err := service.AllService.PeerAttributeService.CheckExistingValues(d); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidAttributeValue")+err.Error())
+		return
+	}
+	err := service.AllService.PeerAttributeService.UpdateDef(d)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Champs personnalisés
+// @Summary Supprimer le champ personnalisé
+// @Description Supprime aussi les valeurs de ce champ sur tous les appareils
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerAttributeDefForm true "Informations sur le champ"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_attribute/delete [post]
+// @Security token
+func (ct *PeerAttribute) Delete(c *gin.Context) {
+	f := &admin.PeerAttributeDefForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	d := service.AllService.PeerAttributeService.DefInfoById(f.Id)
+	if d.Id > 0 {
+		err := service.AllService.PeerAttributeService.DeleteDef(d)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+func (ct *PeerAttribute) checkForm(c *gin.Context, f *admin.PeerAttributeDefForm) (string, bool) {
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		return errList[0], false
+	}
+	if err := service.AllService.PeerAttributeService.ValidateDef(f.ToPeerAttributeDef()); err != nil {
+		return response.TranslateMsg(c, "ParamsError") + err.Error(), false
+	}
+	if f.Id == 0 && service.AllService.PeerAttributeService.DefInfoByName(f.Name).Id > 0 {
+		return response.TranslateMsg(c, "ItemExists"), false
+	}
+	return "", true
+}
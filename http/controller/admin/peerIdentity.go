@@ -0,0 +1,144 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type PeerIdentity struct {
+}
+
+// List Liste
+// @Tags Identité des appareils
+// @Summary Liste des changements d'identité
+// @Description Changements d'ID (même uuid) et d'uuid (même ID). Statut: 1 en attente, 2 fusionné, 3 ignoré
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param peer_id query string false "Ancien ou nouvel ID"
+// @Param kind query string false "Type (id, uuid)"
+// @Param status query int false "Statut"
+// @Success 200 {object} response.Response{data=model.PeerIdentityChangeList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_identity/list [get]
+// @Security token
+func (ct *PeerIdentity) List(c *gin.Context) {
+	query := &admin.PeerIdentityQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerIdentityService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.PeerId != "" {
+			tx.Where("old_value = ? or new_value = ?", query.PeerId, query.PeerId)
+		}
+		if query.Kind != "" {
+			tx.Where("kind = ?", query.Kind)
+		}
+		if query.Status > 0 {
+			tx.Where("status = ?", query.Status)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// History Historique des ID
+// @Tags Identité des appareils
+// @Summary Historique des ID d'un appareil
+// @Description Anciens et nouveaux ID RustDesk connus pour un appareil
+// @Accept  json
+// @Produce  json
+// @Param peer_id query string true "ID de l'appareil"
+// @Success 200 {object} response.Response{data=[]model.PeerIdentityChange}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_identity/history [get]
+// @Security token
+func (ct *PeerIdentity) History(c *gin.Context) {
+	query := &admin.PeerIdentityHistoryQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	response.Success(c, service.AllService.PeerIdentityService.History(query.PeerId))
+}
+
+// Merge Fusionner
+// @Tags Identité des appareils
+// @Summary Fusionner l'ancien appareil dans le nouveau
+// @Description Propriétaire, groupe, alias, carnets d'adresses, partages, champs personnalisés et historique passent au nouvel ID, puis l'ancien appareil est supprimé
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerIdentityResolveForm true "Changement"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_identity/merge [post]
+// @Security token
+func (ct *PeerIdentity) Merge(c *gin.Context) {
+	r, ok := ct.pending(c)
+	if !ok {
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.PeerIdentityService.Merge(r, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	audit.LogPeerMerged(c, u.Id, r.OldValue, r.NewValue)
+	response.Success(c, nil)
+}
+
+// Dismiss Ignorer
+// @Tags Identité des appareils
+// @Summary Ignorer un changement d'ID
+// @Description Les deux appareils sont conservés
+// @Accept  json
+// @Produce  json
+// @Param body body admin.PeerIdentityResolveForm true "Changement"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_identity/dismiss [post]
+// @Security token
+func (ct *PeerIdentity) Dismiss(c *gin.Context) {
+	r, ok := ct.pending(c)
+	if !ok {
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	if err := service.AllService.PeerIdentityService.Dismiss(r, u.Id); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+func (ct *PeerIdentity) pending(c *gin.Context) (*model.PeerIdentityChange, bool) {
+	f := &admin.PeerIdentityResolveForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return nil, false
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return nil, false
+	}
+	r := service.AllService.PeerIdentityService.InfoById(f.Id)
+	if r.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return nil, false
+	}
+	return r, true
+}
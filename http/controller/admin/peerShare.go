@@ -0,0 +1,187 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type PeerShare struct {
+}
+
+// List Liste
+// @Tags Partage d'appareils
+// @Summary Liste des partages d'appareils
+// @Description Liste des partages d'un appareil ou d'une entrée de carnet d'adresses avec un utilisateur ou un groupe
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param user_id query int false "ID de l'utilisateur qui partage"
+// @Param peer_id query string false "ID de l'appareil"
+// @Param type query int false "Type de destinataire, 1: utilisateur 2: groupe"
+// @Param to_id query int false "ID du destinataire"
+// @Success 200 {object} response.Response{data=model.PeerShareList}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_share/list [get]
+// @Security token
+func (ct *PeerShare) List(c *gin.Context) {
+	query := &admin.PeerShareQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.PeerShareService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.UserId > 0 {
+			tx.Where("user_id = ?", query.UserId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id like ?", "%"+query.PeerId+"%")
+		}
+		if query.Type > 0 {
+			tx.Where("type = ?", query.Type)
+		}
+		if query.ToId > 0 {
+			tx.Where("to_id = ?", query.ToId)
+		}
+		tx.Order("id desc")
+	})
+	response.Success(c, res)
+}
+
+// Create Créer
+// @Tags Partage d'appareils
+// @Summary Partager un appareil
+// @Description Partager une entrée de carnet d'adresses (ab_row_id) ou un appareil (peer_id) avec un utilisateur ou un groupe.
+// @Description level 1: lecture, 2: connexion (avec le mot de passe enregistré). end_at (timestamp, 0: sans limite) fixe l'expiration.
+// @Description Sans user_id, le partage est fait au nom du propriétaire de l'entrée, ou de l'administrateur pour un appareil
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response{data=model.PeerShare}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_share/create [post]
+// @Security token
+func (ct *PeerShare) Create(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	f.Id = 0
+	if f.AbRowId > 0 {
+		ab := service.AllService.AddressBookService.InfoByRowId(f.AbRowId)
+		if ab.RowId == 0 {
+			response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+			return
+		}
+		// Une entrée est toujours partagée au nom de son propriétaire
+		if f.UserId > 0 && f.UserId != ab.UserId {
+			response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+			return
+		}
+		f.UserId = ab.UserId
+	} else if f.UserId == 0 {
+		f.UserId = service.AllService.UserService.CurUser(c).Id
+	} else if service.AllService.UserService.InfoById(f.UserId).Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.PeerShareService.Check(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.PeerShareService.Create(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, f)
+}
+
+// Update Modifier
+// @Tags Partage d'appareils
+// @Summary Modifier le partage
+// @Description Modifier le niveau et l'expiration. L'appareil et le destinataire ne peuvent pas être modifiés
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_share/update [post]
+// @Security token
+func (ct *PeerShare) Update(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	ex := service.AllService.PeerShareService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	ex.Level = f.Level
+	ex.EndAt = f.EndAt
+	errList := global.Validator.ValidStruct(c, ex)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if err := service.AllService.PeerShareService.Check(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, err.Error()))
+		return
+	}
+	if err := service.AllService.PeerShareService.Update(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Partage d'appareils
+// @Summary Supprimer le partage
+// @Description Supprimer le partage
+// @Accept  json
+// @Produce  json
+// @Param body body model.PeerShare true "Informations sur le partage"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/peer_share/delete [post]
+// @Security token
+func (ct *PeerShare) Delete(c *gin.Context) {
+	f := &model.PeerShare{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.PeerShareService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.PeerShareService.Delete(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
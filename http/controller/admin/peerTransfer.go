@@ -0,0 +1,183 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// peerImportMaxSize taille maximale du fichier importé
+const peerImportMaxSize = 20 << 20
+
+// Export Exporter
+// @Tags Appareil
+// @Summary Exporter les appareils
+// @Description Export en flux CSV ou JSON avec les mêmes filtres que la liste et les mêmes colonnes, champs personnalisés inclus (colonnes attr.* en CSV, objet attributes en JSON)
+// @Accept  json
+// @Produce  octet-stream
+// @Param format query string false "Format (csv, json), csv par défaut"
+// @Param id query string false "ID"
+// @Param hostname query string false "Nom d'hôte"
+// @Param online query int false "En ligne (1: en ligne, 2: hors ligne)"
+// @Param compliance query string false "Conformité de version"
+// @Param q query string false "Expression de recherche"
+// @Success 200 {file} file
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/export [get]
+// @Security token
+func (ct *Peer) Export(c *gin.Context) {
+	query := &admin.PeerExportQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, query)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	format := query.Format
+	if format == "" {
+		format = service.PeerTransferFormatCsv
+	}
+	where, err := PeerQueryWhere(&query.PeerQuery)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	filename := fmt.Sprintf("peers_%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Status(http.StatusOK)
+
+	var total int
+	if format == service.PeerTransferFormatJson {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		columns := service.AllService.PeerService.ExportColumns()
+		first := true
+		_, _ = c.Writer.WriteString("[")
+		total, err = service.AllService.PeerService.ExportBatches(where, func(peers []*model.Peer) error {
+			for _, p := range peers {
+				b, err := service.PeerExportJSON(p, columns)
+				if err != nil {
+					return err
+				}
+				if !first {
+					_, _ = c.Writer.WriteString(",")
+				}
+				first = false
+				if _, err = c.Writer.Write(b); err != nil {
+					return err
+				}
+			}
+			c.Writer.Flush()
+			return nil
+		})
+		_, _ = c.Writer.WriteString("]")
+	} else {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		columns := service.AllService.PeerService.ExportColumns()
+		w := csv.NewWriter(c.Writer)
+		_ = w.Write(columns)
+		total, err = service.AllService.PeerService.ExportBatches(where, func(peers []*model.Peer) error {
+			for _, p := range peers {
+				if err := w.Write(service.PeerExportRecord(p, columns)); err != nil {
+					return err
+				}
+			}
+			w.Flush()
+			c.Writer.Flush()
+			return w.Error()
+		})
+		w.Flush()
+	}
+	if err != nil {
+		// L'en-tête est déjà envoyé, on ne peut que journaliser
+		global.Logger.Error("peer export failed: ", err)
+	}
+	u := service.AllService.UserService.CurUser(c)
+	audit.LogDataExported(c, u.Id, "peers", format, total)
+}
+
+// Import Importer
+// @Tags Appareil
+// @Summary Importer des appareils
+// @Description Import CSV ou JSON avec correspondance de colonnes, mise à jour par ID RustDesk, simulation (dry_run) et erreurs par ligne
+// @Accept  multipart/form-data
+// @Produce  json
+// @Param file formData file true "Fichier CSV ou JSON"
+// @Param format formData string false "Format (csv, json), déduit de l'extension si vide"
+// @Param mapping formData string false "Correspondance des colonnes en JSON, ex: {\"Nom\":\"hostname\",\"Inventaire\":\"attr.asset_tag\"}"
+// @Param dry_run formData bool false "Simulation sans écriture"
+// @Success 200 {object} response.Response{data=model.PeerImportReport}
+// @Failure 500 {object} response.Response
+// @Router /admin/peer/import [post]
+// @Security token
+func (ct *Peer) Import(c *gin.Context) {
+	f := &admin.PeerImportForm{}
+	if err := c.ShouldBind(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	file, err := c.FormFile("file")
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if file.Size > peerImportMaxSize {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+"file too large")
+		return
+	}
+	mapping := map[string]string{}
+	if f.Mapping != "" {
+		if err = json.Unmarshal([]byte(f.Mapping), &mapping); err != nil {
+			response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+			return
+		}
+	}
+	format := f.Format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Filename)), ".")
+	}
+	src, err := file.Open()
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	defer src.Close()
+	var data *service.PeerImportData
+	switch format {
+	case service.PeerTransferFormatCsv:
+		data, err = service.ReadPeerCSV(src, mapping)
+	case service.PeerTransferFormatJson:
+		data, err = service.ReadPeerJSON(src, mapping)
+	default:
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+"unknown format")
+		return
+	}
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	report := service.AllService.PeerService.Import(data, f.DryRun)
+	if !f.DryRun {
+		u := service.AllService.UserService.CurUser(c)
+		audit.LogBulkOperation(c, u.Id, "peer_import", report.Created+report.Updated)
+	}
+	response.Success(c, report)
+}
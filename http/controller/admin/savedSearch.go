@@ -0,0 +1,184 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+)
+
+type SavedSearch struct {
+}
+
+// Fields Champs de recherche
+// @Tags Recherche
+// @Summary Champs disponibles pour la recherche
+// @Description Champs utilisables dans l'expression de recherche d'une liste (peer, user, audit_conn, audit_file)
+// @Accept  json
+// @Produce  json
+// @Param resource query string true "Ressource"
+// @Success 200 {object} response.Response{data=[]search.FieldInfo}
+// @Failure 500 {object} response.Response
+// @Router /admin/saved_search/fields [get]
+// @Security token
+func (ct *SavedSearch) Fields(c *gin.Context) {
+	query := &admin.SearchFieldsQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res, err := service.AllService.SearchService.SearchFields(query.Resource)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	response.Success(c, res)
+}
+
+// List Liste
+// @Tags Recherche
+// @Summary Recherches enregistrées
+// @Description Recherches enregistrées de l'administrateur courant
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param resource query string false "Ressource"
+// @Success 200 {object} response.Response{data=model.SavedSearchList}
+// @Failure 500 {object} response.Response
+// @Router /admin/saved_search/list [get]
+// @Security token
+func (ct *SavedSearch) List(c *gin.Context) {
+	query := &admin.SavedSearchQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	res := service.AllService.SearchService.ListSaved(query.Page, query.PageSize, func(tx *gorm.DB) {
+		tx.Where("user_id = ?", u.Id)
+		if query.Resource != "" {
+			tx.Where("resource = ?", query.Resource)
+		}
+		tx.Order("name asc")
+	})
+	response.Success(c, res)
+}
+
+// Create Enregistrer une recherche
+// @Tags Recherche
+// @Summary Enregistrer une recherche
+// @Description L'expression et le tri sont validés avant l'enregistrement
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SavedSearchForm true "Recherche"
+// @Success 200 {object} response.Response{data=model.SavedSearch}
+// @Failure 500 {object} response.Response
+// @Router /admin/saved_search/create [post]
+// @Security token
+func (ct *SavedSearch) Create(c *gin.Context) {
+	f := &admin.SavedSearchForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := f.ToSavedSearch()
+	r.Id = 0
+	r.UserId = service.AllService.UserService.CurUser(c).Id
+	if err := service.AllService.SearchService.ValidateSaved(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	if err := service.AllService.SearchService.CreateSaved(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, r)
+}
+
+// Update Modifier
+// @Tags Recherche
+// @Summary Modifier une recherche enregistrée
+// @Description Modifier une recherche enregistrée
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SavedSearchForm true "Recherche"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/saved_search/update [post]
+// @Security token
+func (ct *SavedSearch) Update(c *gin.Context) {
+	f := &admin.SavedSearchForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	ex := service.AllService.SearchService.SavedInfoById(f.Id)
+	if ex.Id == 0 || ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToSavedSearch()
+	r.UserId = u.Id
+	if err := service.AllService.SearchService.ValidateSaved(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
+	if err := service.AllService.SearchService.UpdateSaved(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Recherche
+// @Summary Supprimer une recherche enregistrée
+// @Description Supprimer une recherche enregistrée
+// @Accept  json
+// @Produce  json
+// @Param body body admin.SavedSearchForm true "Recherche"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/saved_search/delete [post]
+// @Security token
+func (ct *SavedSearch) Delete(c *gin.Context) {
+	f := &admin.SavedSearchForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	u := service.AllService.UserService.CurUser(c)
+	ex := service.AllService.SearchService.SavedInfoById(f.Id)
+	if ex.Id == 0 || ex.UserId != u.Id {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.SearchService.DeleteSaved(ex); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+)
+
+type StaleDevice struct {
+}
+
+// Policy Politique de nettoyage
+// @Tags Appareils inactifs
+// @Summary Politique de nettoyage des appareils inactifs
+// @Description Politique de nettoyage des appareils inactifs
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.StaleDevicePolicy}
+// @Failure 500 {object} response.Response
+// @Router /admin/stale_device/policy [get]
+// @Security token
+func (ct *StaleDevice) Policy(c *gin.Context) {
+	response.Success(c, service.AllService.StaleDeviceService.GetPolicy())
+}
+
+// SavePolicy Enregistrer la politique
+// @Tags Appareils inactifs
+// @Summary Enregistrer la politique de nettoyage
+// @Description Enregistrer la politique de nettoyage
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StaleDevicePolicyForm true "Politique de nettoyage"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/stale_device/savePolicy [post]
+// @Security token
+func (ct *StaleDevice) SavePolicy(c *gin.Context) {
+	f, ok := ct.bindPolicyForm(c)
+	if !ok {
+		return
+	}
+	err := service.AllService.StaleDeviceService.SavePolicy(f.ToStaleDevicePolicy())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Preview Aperçu
+// @Tags Appareils inactifs
+// @Summary Aperçu du nettoyage
+// @Description Appareils qui seraient marqués inactifs ou supprimés avec cette politique
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StaleDevicePolicyForm true "Politique de nettoyage"
+// @Success 200 {object} response.Response{data=service.StaleDevicePreview}
+// @Failure 500 {object} response.Response
+// @Router /admin/stale_device/preview [post]
+// @Security token
+func (ct *StaleDevice) Preview(c *gin.Context) {
+	f, ok := ct.bindPolicyForm(c)
+	if !ok {
+		return
+	}
+	response.Success(c, service.AllService.StaleDeviceService.Preview(f.ToStaleDevicePolicy()))
+}
+
+// Run Exécuter
+// @Tags Appareils inactifs
+// @Summary Exécuter le nettoyage maintenant
+// @Description Exécute la politique enregistrée, même si elle est désactivée
+// @Accept  json
+// @Produce  json
+// @Success 200 {object} response.Response{data=model.StaleDeviceRun}
+// @Failure 500 {object} response.Response
+// @Router /admin/stale_device/run [post]
+// @Security token
+func (ct *StaleDevice) Run(c *gin.Context) {
+	run := service.AllService.StaleDeviceService.Run(model.StaleRunTriggerManual)
+	if run.Error != "" {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+run.Error)
+		return
+	}
+	response.Success(c, run)
+}
+
+// Runs Historique des exécutions
+// @Tags Appareils inactifs
+// @Summary Historique des exécutions du nettoyage
+// @Description Historique des exécutions du nettoyage
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Success 200 {object} response.Response{data=model.StaleDeviceRunList}
+// @Failure 500 {object} response.Response
+// @Router /admin/stale_device/runs [get]
+// @Security token
+func (ct *StaleDevice) Runs(c *gin.Context) {
+	query := &admin.StaleDeviceRunQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	response.Success(c, service.AllService.StaleDeviceService.ListRuns(query.Page, query.PageSize, nil))
+}
+
+func (ct *StaleDevice) bindPolicyForm(c *gin.Context) (*admin.StaleDevicePolicyForm, bool) {
+	f := &admin.StaleDevicePolicyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return nil, false
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return nil, false
+	}
+	if f.DeleteDays > 0 && f.DeleteDays < f.FlagDays {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+"delete_days < flag_days")
+		return nil, false
+	}
+	return f, true
+}
@@ -0,0 +1,317 @@
+package admin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
+	"strconv"
+)
+
+type Strategy struct {
+}
+
+// List Liste
+// @Tags Stratégie
+// @Summary Liste des stratégies
+// @Description Liste des stratégies de configuration client
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param name query string false "Nom"
+// @Success 200 {object} response.Response{data=model.StrategyList}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/list [get]
+// @Security token
+func (ct *Strategy) List(c *gin.Context) {
+	query := &admin.StrategyQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.StrategyService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.Name != "" {
+			tx.Where("name like ?", "%"+query.Name+"%")
+		}
+	})
+	response.Success(c, res)
+}
+
+// Detail Stratégie
+// @Tags Stratégie
+// @Summary Détails de la stratégie
+// @Description Détails de la stratégie
+// @Accept  json
+// @Produce  json
+// @Param id path int true "ID"
+// @Success 200 {object} response.Response{data=model.Strategy}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/detail/{id} [get]
+// @Security token
+func (ct *Strategy) Detail(c *gin.Context) {
+	id := c.Param("id")
+	iid, _ := strconv.Atoi(id)
+	r := service.AllService.StrategyService.InfoById(uint(iid))
+	if r.Id > 0 {
+		response.Success(c, r)
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// Create Créer une stratégie
+// @Tags Stratégie
+// @Summary Créer une stratégie
+// @Description Créer une stratégie
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyForm true "Informations sur la stratégie"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/create [post]
+// @Security token
+func (ct *Strategy) Create(c *gin.Context) {
+	f := &admin.StrategyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	err := service.AllService.StrategyService.Create(f.ToStrategy())
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Update Modifier
+// @Tags Stratégie
+// @Summary Modifier la stratégie
+// @Description Une modification des options crée une nouvelle version appliquée par les clients au prochain heartbeat
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyForm true "Informations sur la stratégie"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/update [post]
+// @Security token
+func (ct *Strategy) Update(c *gin.Context) {
+	f := &admin.StrategyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	if f.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	ex := service.AllService.StrategyService.InfoById(f.Id)
+	if ex.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	r := f.ToStrategy()
+	r.CreatedAt = ex.CreatedAt
+	err := service.AllService.StrategyService.Update(r, ex)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Delete Supprimer
+// @Tags Stratégie
+// @Summary Supprimer la stratégie
+// @Description Supprime aussi ses affectations
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyForm true "Informations sur la stratégie"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/delete [post]
+// @Security token
+func (ct *Strategy) Delete(c *gin.Context) {
+	f := &admin.StrategyForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	id := f.Id
+	errList := global.Validator.ValidVar(c, id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.StrategyService.InfoById(f.Id)
+	if r.Id > 0 {
+		err := service.AllService.StrategyService.Delete(r)
+		if err == nil {
+			response.Success(c, nil)
+			return
+		}
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+}
+
+// AssignmentList Affectations
+// @Tags Stratégie
+// @Summary Liste des affectations de stratégies
+// @Description Priorité: appareil > utilisateur > groupe de périphériques
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param strategy_id query int false "ID de la stratégie"
+// @Param type query int false "Type (1: groupe de périphériques, 2: utilisateur, 3: appareil)"
+// @Success 200 {object} response.Response{data=model.StrategyAssignmentList}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/assignments [get]
+// @Security token
+func (ct *Strategy) AssignmentList(c *gin.Context) {
+	query := &admin.StrategyAssignmentQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.StrategyService.ListAssignments(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.StrategyId > 0 {
+			tx.Where("strategy_id = ?", query.StrategyId)
+		}
+		if query.Type > 0 {
+			tx.Where("type = ?", query.Type)
+		}
+	})
+	response.Success(c, res)
+}
+
+// Assign Affecter
+// @Tags Stratégie
+// @Summary Affecter une stratégie
+// @Description Affecte une stratégie à un groupe de périphériques, un utilisateur ou un appareil (remplace l'affectation existante)
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyAssignForm true "Affectation"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/assign [post]
+// @Security token
+func (ct *Strategy) Assign(c *gin.Context) {
+	f := &admin.StrategyAssignForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	if service.AllService.StrategyService.InfoById(f.StrategyId).Id == 0 || !ct.targetExists(f.Type, f.ToId) {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	err := service.AllService.StrategyService.Assign(f.StrategyId, f.Type, f.ToId)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// Unassign Retirer
+// @Tags Stratégie
+// @Summary Retirer une affectation
+// @Description Retirer une affectation
+// @Accept  json
+// @Produce  json
+// @Param body body admin.StrategyAssignForm true "Affectation (id)"
+// @Success 200 {object} response.Response
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/unassign [post]
+// @Security token
+func (ct *Strategy) Unassign(c *gin.Context) {
+	f := &admin.StrategyAssignForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidVar(c, f.Id, "required,gt=0")
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	r := service.AllService.StrategyService.AssignmentInfoById(f.Id)
+	if r.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.StrategyService.DeleteAssignment(r); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	response.Success(c, nil)
+}
+
+// States Versions appliquées
+// @Tags Stratégie
+// @Summary Versions de stratégie appliquées par les appareils
+// @Description Version attendue et version appliquée (modified_at renvoyé par le client) pour chaque appareil
+// @Accept  json
+// @Produce  json
+// @Param page query int false "Numéro de page"
+// @Param page_size query int false "Taille de la page"
+// @Param strategy_id query int false "ID de la stratégie"
+// @Param peer_id query string false "ID de l'appareil"
+// @Param pending query bool false "Seulement les appareils qui n'ont pas appliqué la dernière version"
+// @Success 200 {object} response.Response{data=model.PeerStrategyStateList}
+// @Failure 500 {object} response.Response
+// @Router /admin/strategy/states [get]
+// @Security token
+func (ct *Strategy) States(c *gin.Context) {
+	query := &admin.PeerStrategyStateQuery{}
+	if err := c.ShouldBindQuery(query); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	res := service.AllService.StrategyService.ListStates(query.Page, query.PageSize, func(tx *gorm.DB) {
+		if query.StrategyId > 0 {
+			tx.Where("strategy_id = ?", query.StrategyId)
+		}
+		if query.PeerId != "" {
+			tx.Where("peer_id like ?", "%"+query.PeerId+"%")
+		}
+		if query.Pending {
+			tx.Where("strategy_id > 0 and applied_version != expected_version")
+		}
+	})
+	response.Success(c, res)
+}
+
+func (ct *Strategy) targetExists(t int, toId uint) bool {
+	switch t {
+	case model.StrategyTargetDeviceGroup:
+		return service.AllService.GroupService.DeviceGroupInfoById(toId).Id > 0
+	case model.StrategyTargetUser:
+		return service.AllService.UserService.InfoById(toId).Id > 0
+	case model.StrategyTargetPeer:
+		return service.AllService.PeerService.InfoByRowId(toId).RowId > 0
+	}
+	return false
+}
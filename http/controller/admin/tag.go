@@ -68,7 +68,11 @@ func (ct *Tag) Create(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError"))
 		return
 	}
-	err := service.AllService.TagService.Create(t)
+	if !service.AllService.GlobalTagService.CheckTags(t.CollectionId, []string{t.Name}) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.TagService.Create(t, adminAbActor(c))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -142,7 +146,11 @@ func (ct *Tag) Update(c *gin.Context) {
 		return
 	}
 	t := f.ToTag()
-	err := service.AllService.TagService.Update(t)
+	if !service.AllService.GlobalTagService.CheckTags(t.CollectionId, []string{t.Name}) {
+		response.Fail(c, 101, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	err := service.AllService.TagService.Update(t, adminAbActor(c))
 	if err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
 		return
@@ -178,7 +186,7 @@ func (ct *Tag) Delete(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
-	err := service.AllService.TagService.Delete(ex)
+	err := service.AllService.TagService.Delete(ex, adminAbActor(c))
 	if err == nil {
 		response.Success(c, nil)
 		return
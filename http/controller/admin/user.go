@@ -6,6 +6,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/admin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	adResp "github.com/RobertLesgros/rustdesk-interface/v2/http/response/admin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
@@ -79,6 +80,8 @@ func (ct *User) Create(c *gin.Context) {
 // @Param page query int false "Numéro de page"
 // @Param page_size query int false "Taille de la page"
 // @Param username query int false "Compte"
+// @Param q query string false "Expression de recherche"
+// @Param sort query string false "Tri sur un champ indexé, ex: -id"
 // @Success 200 {object} response.Response{data=model.UserList}
 // @Failure 500 {object} response.Response
 // @Router /admin/user/list [get]
@@ -89,10 +92,18 @@ func (ct *User) List(c *gin.Context) {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
 	}
+	search, err := service.AllService.SearchService.Compile(service.SearchResourceUser, query.Q, query.Sort)
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+		return
+	}
 	res := service.AllService.UserService.List(query.Page, query.PageSize, func(tx *gorm.DB) {
 		if query.Username != "" {
 			tx.Where("username like ?", "%"+query.Username+"%")
 		}
+		if search != nil {
+			search(tx)
+		}
 	})
 	response.Success(c, res)
 }
@@ -138,13 +149,14 @@ func (ct *User) Update(c *gin.Context) {
 // @Description Supprimer l'utilisateur
 // @Accept  json
 // @Produce  json
-// @Param body body admin.UserForm true "Informations sur l'utilisateur"
+// @Description Avec transfer_to, les appareils, carnets d'adresses, tags, partages et règles sont d'abord transférés à cet utilisateur
+// @Param body body admin.UserDeleteForm true "Informations sur l'utilisateur"
 // @Success 200 {object} response.Response
 // @Failure 500 {object} response.Response
 // @Router /admin/user/delete [post]
 // @Security token
 func (ct *User) Delete(c *gin.Context) {
-	f := &admin.UserForm{}
+	f := &admin.UserDeleteForm{}
 	if err := c.ShouldBindJSON(f); err != nil {
 		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
 		return
@@ -157,8 +169,19 @@ func (ct *User) Delete(c *gin.Context) {
 	}
 	u := service.AllService.UserService.InfoById(f.Id)
 	if u.Id > 0 {
-		err := service.AllService.UserService.Delete(u)
+		var to *model.User
+		if f.TransferTo > 0 {
+			to = service.AllService.UserService.InfoById(f.TransferTo)
+			if to.Id == 0 {
+				response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+				return
+			}
+		}
+		res, err := service.AllService.UserService.DeleteWithTransfer(u, to, adminAbActor(c))
 		if err == nil {
+			if res != nil {
+				audit.LogOwnershipTransferred(c, service.AllService.UserService.CurUser(c).Id, u.Id, to.Id, res.Peers, res.Collections)
+			}
 			response.Success(c, nil)
 			return
 		}
@@ -168,6 +191,45 @@ func (ct *User) Delete(c *gin.Context) {
 	response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
 }
 
+// Transfer Transférer
+// @Tags Utilisateur
+// @Summary Transférer les appareils et carnets d'adresses à un autre utilisateur
+// @Description Appareils, collections, carnets d'adresses, tags, partages et règles de partage sont transférés dans une seule transaction. Avec dry_run, seul l'aperçu est renvoyé
+// @Accept  json
+// @Produce  json
+// @Param body body admin.UserTransferForm true "Transfert"
+// @Success 200 {object} response.Response{data=model.OwnershipTransferReport}
+// @Failure 500 {object} response.Response
+// @Router /admin/user/transfer [post]
+// @Security token
+func (ct *User) Transfer(c *gin.Context) {
+	f := &admin.UserTransferForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Fail(c, 101, errList[0])
+		return
+	}
+	from := service.AllService.UserService.InfoById(f.FromUserId)
+	to := service.AllService.UserService.InfoById(f.ToUserId)
+	if from.Id == 0 || to.Id == 0 {
+		response.Fail(c, 101, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	res, err := service.AllService.UserService.TransferOwnership(from, to, f.DryRun, adminAbActor(c))
+	if err != nil {
+		response.Fail(c, 101, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
+	}
+	if !f.DryRun {
+		audit.LogOwnershipTransferred(c, service.AllService.UserService.CurUser(c).Id, from.Id, to.Id, res.Peers, res.Collections)
+	}
+	response.Success(c, res)
+}
+
 // UpdatePassword Modifier le mot de passe
 // @Tags Utilisateur
 // @Summary Modifier le mot de passe
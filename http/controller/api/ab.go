@@ -11,6 +11,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+	"gorm.io/gorm"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,7 +34,9 @@ func (a *Ab) Ab(c *gin.Context) {
 	user := service.AllService.UserService.CurUser(c)
 
 	al := service.AllService.AddressBookService.ListByUserIdAndCollectionId(user.Id, 0, 1, 1000)
+	service.AllService.PresenceService.FillAddressBookOnline(al.AddressBooks)
 	tags := service.AllService.TagService.ListByUserIdAndCollectionId(user.Id, 0)
+	tags.Tags = service.AllService.GlobalTagService.WithGlobalTags(user.Id, 0, tags.Tags)
 
 	tagColors := map[string]uint{}
 	// Convertir les noms des tags en une chaîne séparée par des virgules
@@ -49,8 +52,11 @@ func (a *Ab) Ab(c *gin.Context) {
 		TagColors: string(tgc),
 	}
 	data, _ := json.Marshal(res)
+	rev := service.AllService.AddressBookRevisionService.Get(user.Id, 0)
+	c.Header("ETag", service.AllService.AddressBookRevisionService.ETag(rev))
 	c.JSON(http.StatusOK, gin.H{
-		"data": string(data),
+		"data":     string(data),
+		"revision": rev,
 		//"licensed_devices": 999,
 	})
 }
@@ -58,11 +64,13 @@ func (a *Ab) Ab(c *gin.Context) {
 // UpAb
 // @Tags Adresse
 // @Summary Mise à jour d'adresse
-// @Description Mise à jour d'adresse
+// @Description Mise à jour d'adresse. Avec l'en-tête If-Match ou le champ revision, la mise à jour est refusée (409) si le carnet a changé depuis la lecture
 // @Accept  json
 // @Produce  json
+// @Param If-Match header string false "Révision lue (ETag)"
 // @Param body body requstform.AddressBookForm true "Formulaire d'adresse"
 // @Success 200 {string} string "null"
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab [post]
 // @Security BearerAuth
@@ -87,14 +95,21 @@ func (a *Ab) UpAb(c *gin.Context) {
 	}
 	user := service.AllService.UserService.CurUser(c)
 
-	err = service.AllService.AddressBookService.UpdateAddressBook(abd.Peers, user.Id)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" && abf.Revision != nil {
+		ifMatch = strconv.FormatUint(*abf.Revision, 10)
+	}
+	ok := a.guard(c, user.Id, 0, ifMatch, func() error {
+		if err := service.AllService.AddressBookService.UpdateAddressBook(abd.Peers, user.Id); err != nil {
+			return err
+		}
+		service.AllService.TagService.UpdateTags(user.Id, tc)
+		return nil
+	})
+	if !ok {
 		return
 	}
 
-	service.AllService.TagService.UpdateTags(user.Id, tc)
-
 	c.JSON(http.StatusOK, nil)
 }
 
@@ -119,11 +134,21 @@ func (a *Ab) PTags(c *gin.Context) {
 	}
 
 	//check privileges
-	if !service.AllService.AddressBookService.CheckUserReadPrivilege(u, uid, cid) {
+	all, folderIds := service.AllService.AddressBookService.UserReadableFolders(u, uid, cid)
+	if !all && len(folderIds) == 0 {
 		response.Error(c, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
 	tags := service.AllService.TagService.ListByUserIdAndCollectionId(uid, cid)
+	// Étiquettes globales, avec leur couleur
+	tags.Tags = service.AllService.GlobalTagService.WithGlobalTags(uid, cid, tags.Tags)
+	// Les dossiers sont présentés au client comme des étiquettes
+	if cid > 0 {
+		for _, name := range service.AllService.AddressBookFolderService.FolderTags(cid, all, folderIds) {
+			tags.Tags = append(tags.Tags, &model.Tag{Name: name, UserId: uid, CollectionId: cid})
+		}
+	}
+	c.Header("ETag", service.AllService.AddressBookRevisionService.ETag(service.AllService.AddressBookRevisionService.Get(uid, cid)))
 	c.JSON(http.StatusOK, tags.Tags)
 }
 
@@ -134,7 +159,9 @@ func (a *Ab) PTags(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/tag/add/{guid} [post]
 // @Security BearerAuth
@@ -161,6 +188,14 @@ func (a *Ab) TagAdd(c *gin.Context) {
 		return
 	}
 
+	if strings.HasPrefix(t.Name, service.AbFolderTagPrefix) {
+		response.Error(c, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTags(cid, []string{t.Name}) {
+		response.Error(c, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
 	tag := service.AllService.TagService.InfoByUserIdAndNameAndCollectionId(uid, t.Name, cid)
 	if tag != nil && tag.Id != 0 {
 		response.Error(c, response.TranslateMsg(c, "ItemExists"))
@@ -168,9 +203,10 @@ func (a *Ab) TagAdd(c *gin.Context) {
 	}
 	t.UserId = uid
 	t.CollectionId = cid
-	err = service.AllService.TagService.Create(t)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ok := a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		return service.AllService.TagService.Create(t, service.NewAbActor(u, model.AbChangeSourceClient))
+	})
+	if !ok {
 		return
 	}
 	c.String(http.StatusOK, "")
@@ -183,7 +219,9 @@ func (a *Ab) TagAdd(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/tag/rename/{guid} [put]
 // @Security BearerAuth
@@ -214,15 +252,24 @@ func (a *Ab) TagRename(c *gin.Context) {
 		response.Error(c, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
+	if strings.HasPrefix(t.New, service.AbFolderTagPrefix) {
+		response.Error(c, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTags(cid, []string{t.New}) {
+		response.Error(c, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
 	ntag := service.AllService.TagService.InfoByUserIdAndNameAndCollectionId(uid, t.New, cid)
 	if ntag != nil && ntag.Id != 0 {
 		response.Error(c, response.TranslateMsg(c, "ItemExists"))
 		return
 	}
 	tag.Name = t.New
-	err = service.AllService.TagService.Update(tag)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ok := a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		return service.AllService.TagService.Update(tag, service.NewAbActor(u, model.AbChangeSourceClient))
+	})
+	if !ok {
 		return
 	}
 	c.String(http.StatusOK, "")
@@ -235,7 +282,9 @@ func (a *Ab) TagRename(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/tag/update/{guid} [put]
 // @Security BearerAuth
@@ -266,9 +315,10 @@ func (a *Ab) TagUpdate(c *gin.Context) {
 		return
 	}
 	tag.Color = t.Color
-	err = service.AllService.TagService.Update(tag)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ok := a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		return service.AllService.TagService.Update(tag, service.NewAbActor(u, model.AbChangeSourceClient))
+	})
+	if !ok {
 		return
 	}
 	c.String(http.StatusOK, "")
@@ -281,7 +331,9 @@ func (a *Ab) TagUpdate(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/tag/{guid} [delete]
 // @Security BearerAuth
@@ -308,17 +360,25 @@ func (a *Ab) TagDel(c *gin.Context) {
 		return
 	}
 
+	tags := make([]*model.Tag, 0, len(*t))
 	for _, name := range *t {
 		tag := service.AllService.TagService.InfoByUserIdAndNameAndCollectionId(uid, name, cid)
 		if tag == nil || tag.Id == 0 {
 			response.Error(c, response.TranslateMsg(c, "ItemNotFound"))
 			return
 		}
-		err = service.AllService.TagService.Delete(tag)
-		if err != nil {
-			response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
-			return
+		tags = append(tags, tag)
+	}
+	ok := a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		for _, tag := range tags {
+			if err := service.AllService.TagService.Delete(tag, service.NewAbActor(u, model.AbChangeSourceClient)); err != nil {
+				return err
+			}
 		}
+		return nil
+	})
+	if !ok {
+		return
 	}
 	c.String(http.StatusOK, "")
 }
@@ -397,7 +457,7 @@ func (a *Ab) SharedProfiles(c *gin.Context) {
 			Guid:  a.ComposeGuid(user.GroupId, user.Id, ab.Id),
 			Name:  ab.Name,
 			Owner: user.Username,
-			Rule:  model.ShareAddressBookRuleRuleFullControl,
+			Rule:  a.clientRule(ab, model.ShareAddressBookRuleRuleFullControl),
 		})
 	}
 
@@ -436,7 +496,17 @@ func (a *Ab) SharedProfiles(c *gin.Context) {
 			Guid:  a.ComposeGuid(_u.GroupId, _u.Id, collection.Id),
 			Name:  collection.Name,
 			Owner: _u.Username,
-			Rule:  allAbIds[collection.Id],
+			Rule:  a.clientRule(collection, allAbIds[collection.Id]),
+		})
+	}
+
+	// Appareils partagés individuellement
+	if len(service.AllService.PeerShareService.ReceivedShares(user)) > 0 {
+		res = append(res, &api.SharedProfilesPayload{
+			Guid:  a.ComposeGuid(user.GroupId, user.Id, service.SharedWithMeCollectionId),
+			Name:  response.TranslateMsg(c, "SharedWithMe"),
+			Owner: user.Username,
+			Rule:  model.ShareAddressBookRuleRuleRead,
 		})
 	}
 
@@ -446,6 +516,14 @@ func (a *Ab) SharedProfiles(c *gin.Context) {
 	})
 }
 
+// clientRule les collections dynamiques sont en lecture seule pour les clients
+func (a *Ab) clientRule(collection *model.AddressBookCollection, rule int) int {
+	if collection.IsDynamic() && rule > model.ShareAddressBookRuleRuleRead {
+		return model.ShareAddressBookRuleRuleRead
+	}
+	return rule
+}
+
 // ParseGuid
 func (a *Ab) ParseGuid(guid string) (gid, uid, cid uint) {
 	// Couper guid avec -
@@ -506,6 +584,13 @@ func (a *Ab) CheckGuid(cu *model.User, guid string) (gid, uid, cid uint, err err
 		err = errors.New("ParamsError")
 		return
 	}
+	if cid == service.SharedWithMeCollectionId {
+		// Carnet virtuel "Partagés avec moi", visible uniquement par son destinataire
+		if cu.Id != uid {
+			err = errors.New("ParamsError")
+		}
+		return
+	}
 	if cid > 0 {
 		c := service.AllService.AddressBookService.CollectionInfoById(cid)
 		if c == nil || c.Id == 0 {
@@ -543,16 +628,49 @@ func (a *Ab) Peers(c *gin.Context) {
 	}
 
 	//check privileges
-	if !service.AllService.AddressBookService.CheckUserReadPrivilege(u, uid, cid) {
+	all, folderIds := service.AllService.AddressBookService.UserReadableFolders(u, uid, cid)
+	if !all && len(folderIds) == 0 {
 		response.Error(c, response.TranslateMsg(c, "NoAccess"))
 		return
 	}
 
-	al := service.AllService.AddressBookService.ListByUserIdAndCollectionId(uid, cid, 1, 1000)
+	var al *model.AddressBookList
+	dynamic := false
+	if cid == service.SharedWithMeCollectionId {
+		// Appareils partagés individuellement avec l'utilisateur
+		shared := service.AllService.PeerShareService.SharedWithMe(u)
+		al = &model.AddressBookList{AddressBooks: shared}
+		al.Total = int64(len(shared))
+	} else if collection := service.AllService.AddressBookService.CollectionInfoById(cid); cid > 0 && collection.IsDynamic() {
+		// Collection dynamique: contenu calculé à la lecture
+		dynamic = true
+		al, err = service.AllService.AddressBookService.DynamicPeers(collection, 1, service.DynamicAbMaxPeers)
+		if err != nil {
+			response.Error(c, response.TranslateMsg(c, "InvalidSearchQuery")+err.Error())
+			return
+		}
+	} else if all {
+		al = service.AllService.AddressBookService.ListByUserIdAndCollectionId(uid, cid, 1, 1000)
+	} else {
+		// Partage limité à certains dossiers
+		al = service.AllService.AddressBookService.List(1, 1000, func(tx *gorm.DB) {
+			tx.Where("user_id = ? and collection_id = ? and folder_id in ?", uid, cid, folderIds)
+		})
+	}
+	service.AllService.PresenceService.FillAddressBookOnline(al.AddressBooks)
+	service.AllService.AddressBookFolderService.FillFolderTags(cid, al.AddressBooks)
+	rev := service.AllService.AddressBookRevisionService.Get(uid, cid)
+	if dynamic {
+		// Les membres d'une collection dynamique changent sans modifier la révision
+		c.Header("ETag", service.AllService.AddressBookRevisionService.MembersETag(rev, al.AddressBooks))
+	} else {
+		c.Header("ETag", service.AllService.AddressBookRevisionService.ETag(rev))
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"total":            al.Total,
 		"data":             al.AddressBooks,
 		"licensed_devices": 99999,
+		"revision":         rev,
 	})
 }
 
@@ -563,7 +681,9 @@ func (a *Ab) Peers(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/peer/add/{guid} [post]
 // @Security BearerAuth
@@ -585,16 +705,29 @@ func (a *Ab) PeerAdd(c *gin.Context) {
 		return
 	}
 
-	//check privileges
-	if !service.AllService.AddressBookService.CheckUserWritePrivilege(u, uid, cid) {
-		response.Error(c, response.TranslateMsg(c, "NoAccess"))
-		return
-	}
-
 	//fmt.Println(f)
 	f.UserId = uid
 	ab := f.ToAddressBook()
 	ab.CollectionId = cid
+	var tags []string
+	_ = json.Unmarshal(ab.Tags, &tags)
+	tags, folderId, ok := a.folderFromTags(cid, tags)
+	if !ok {
+		response.Error(c, response.TranslateMsg(c, "ParamsError"))
+		return
+	}
+	if !service.AllService.GlobalTagService.CheckTags(cid, tags) {
+		response.Error(c, response.TranslateMsg(c, "TagNotGlobal"))
+		return
+	}
+	ab.Tags, _ = json.Marshal(tags)
+	ab.FolderId = folderId
+
+	//check privileges
+	if service.AllService.AddressBookService.UserFolderRule(u, uid, cid, folderId) < model.ShareAddressBookRuleRuleReadWrite {
+		response.Error(c, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
 	if ab.Platform == "" || ab.Username == "" || ab.Hostname == "" {
 		peer := service.AllService.PeerService.FindById(ab.Id)
 		if peer.RowId != 0 {
@@ -604,9 +737,10 @@ func (a *Ab) PeerAdd(c *gin.Context) {
 		}
 	}
 
-	err = service.AllService.AddressBookService.AddAddressBook(ab)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ok = a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		return service.AllService.AddressBookService.AddAddressBook(ab, service.NewAbActor(u, model.AbChangeSourceClient))
+	})
+	if !ok {
 		return
 	}
 	c.String(http.StatusOK, "")
@@ -619,7 +753,9 @@ func (a *Ab) PeerAdd(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/peer/add/{guid} [delete]
 // @Security BearerAuth
@@ -638,23 +774,30 @@ func (a *Ab) PeerDel(c *gin.Context) {
 		return
 	}
 
-	//check privileges
-	if !service.AllService.AddressBookService.CheckUserFullControlPrivilege(u, uid, cid) {
-		response.Error(c, response.TranslateMsg(c, "NoAccess"))
-		return
-	}
-
+	abs := make([]*model.AddressBook, 0, len(*f))
 	for _, id := range *f {
 		ab := service.AllService.AddressBookService.InfoByUserIdAndIdAndCid(uid, id, cid)
 		if ab == nil || ab.RowId == 0 {
 			response.Error(c, response.TranslateMsg(c, "ItemNotFound"))
 			return
 		}
-		err = service.AllService.AddressBookService.Delete(ab)
-		if err != nil {
-			response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		//check privileges
+		if service.AllService.AddressBookService.UserFolderRule(u, uid, cid, ab.FolderId) < model.ShareAddressBookRuleRuleFullControl {
+			response.Error(c, response.TranslateMsg(c, "NoAccess"))
 			return
 		}
+		abs = append(abs, ab)
+	}
+	ok := a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		for _, ab := range abs {
+			if err := service.AllService.AddressBookService.Delete(ab, service.NewAbActor(u, model.AbChangeSourceClient)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return
 	}
 
 	c.String(http.StatusOK, "")
@@ -667,7 +810,9 @@ func (a *Ab) PeerDel(c *gin.Context) {
 // @Accept  json
 // @Produce  json
 // @Param guid path string true "guid"
+// @Param If-Match header string false "Révision lue (ETag), 409 si le carnet a changé"
 // @Success 200 {string} string
+// @Failure 409 {object} api.AbConflictResponse
 // @Failure 500 {object} response.ErrorResponse
 // @Router /ab/peer/update/{guid} [put]
 // @Security BearerAuth
@@ -687,11 +832,6 @@ func (a *Ab) PeerUpdate(c *gin.Context) {
 		return
 	}
 
-	//check privileges
-	if !service.AllService.AddressBookService.CheckUserWritePrivilege(u, uid, cid) {
-		response.Error(c, response.TranslateMsg(c, "NoAccess"))
-		return
-	}
 	//fmt.Println(f)
 	// Vérifier si f["Id"] existe
 	fid, ok := f["id"]
@@ -706,6 +846,12 @@ func (a *Ab) PeerUpdate(c *gin.Context) {
 		response.Error(c, response.TranslateMsg(c, "ItemNotFound"))
 		return
 	}
+
+	//check privileges
+	if service.AllService.AddressBookService.UserFolderRule(u, uid, cid, ab.FolderId) < model.ShareAddressBookRuleRuleReadWrite {
+		response.Error(c, response.TranslateMsg(c, "NoAccess"))
+		return
+	}
 	// Champs autorisés
 	allowUp := []string{"password", "hash", "tags", "alias"}
 	// Supprimer les champs de f s'ils ne sont pas dans allowUp
@@ -716,12 +862,70 @@ func (a *Ab) PeerUpdate(c *gin.Context) {
 	}
 	//fmt.Println(f)
 	if tags, _ok := f["tags"]; _ok {
-		f["tags"], _ = json.Marshal(tags)
+		// L'étiquette de dossier indique le dossier de destination, sans étiquette de dossier l'entrée reste dans son dossier
+		var names []string
+		b, _ := json.Marshal(tags)
+		_ = json.Unmarshal(b, &names)
+		_, paths := service.SplitFolderTags(names)
+		names, folderId, _ok := a.folderFromTags(cid, names)
+		if !_ok {
+			response.Error(c, response.TranslateMsg(c, "ParamsError"))
+			return
+		}
+		if !service.AllService.GlobalTagService.CheckTags(cid, names) {
+			response.Error(c, response.TranslateMsg(c, "TagNotGlobal"))
+			return
+		}
+		if len(paths) > 0 && folderId != ab.FolderId {
+			if service.AllService.AddressBookService.UserFolderRule(u, uid, cid, folderId) < model.ShareAddressBookRuleRuleReadWrite {
+				response.Error(c, response.TranslateMsg(c, "NoAccess"))
+				return
+			}
+			f["folder_id"] = folderId
+		}
+		f["tags"], _ = json.Marshal(names)
 	}
-	err = service.AllService.AddressBookService.UpdateByMap(ab, f)
-	if err != nil {
-		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+	ok = a.guard(c, uid, cid, c.GetHeader("If-Match"), func() error {
+		return service.AllService.AddressBookService.UpdateByMap(ab, f, service.NewAbActor(u, model.AbChangeSourceClient))
+	})
+	if !ok {
 		return
 	}
 	c.String(http.StatusOK, "")
 }
+
+// folderFromTags sépare les étiquettes de dossier et renvoie le dossier indiqué (0: racine).
+// Une seule étiquette de dossier est acceptée, et seulement dans une collection
+func (a *Ab) folderFromTags(cid uint, tags []string) ([]string, uint, bool) {
+	plain, paths := service.SplitFolderTags(tags)
+	if len(paths) == 0 {
+		return plain, 0, true
+	}
+	if len(paths) > 1 || cid == 0 {
+		return nil, 0, false
+	}
+	folderId, ok := service.AllService.AddressBookFolderService.FolderByPath(cid, paths[0])
+	return plain, folderId, ok
+}
+
+// guard exécute fn si la révision If-Match est à jour et renvoie la nouvelle révision dans l'en-tête ETag.
+// En cas de conflit, répond 409 avec la révision actuelle pour que le client recharge le carnet
+func (a *Ab) guard(c *gin.Context, uid, cid uint, ifMatch string, fn func() error) bool {
+	rs := service.AllService.AddressBookRevisionService
+	rev, err := rs.Guard(uid, cid, ifMatch, fn)
+	if errors.Is(err, service.ErrAbRevisionConflict) {
+		cur := rs.Get(uid, cid)
+		c.Header("ETag", rs.ETag(cur))
+		c.JSON(http.StatusConflict, &api.AbConflictResponse{
+			Error:    response.TranslateMsg(c, "AbRevisionConflict"),
+			Revision: cur,
+		})
+		return false
+	}
+	if err != nil {
+		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return false
+	}
+	c.Header("ETag", rs.ETag(rev))
+	return true
+}
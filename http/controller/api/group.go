@@ -7,6 +7,7 @@ import (
 	apiResp "github.com/RobertLesgros/rustdesk-interface/v2/http/response/api"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"gorm.io/gorm"
 	"net/http"
 )
 
@@ -99,7 +100,20 @@ func (g *Group) Peers(c *gin.Context) {
 	for _, group := range allGroup.DeviceGroups {
 		dGroupNameById[group.Id] = group.Name
 	}
-	peerList := service.AllService.PeerService.ListByUserIds(userIds, q.Page, q.PageSize)
+	// Appareils des groupes accessibles en plus des appareils des utilisateurs
+	accessible := service.AllService.DeviceGroupPermissionService.AccessibleGroupIds(u)
+	peerList := service.AllService.PeerService.ListByUserIdsOrGroupIds(userIds, accessible, q.Page, q.PageSize)
+	missing := make([]uint, 0)
+	for _, peer := range peerList.Peers {
+		if _, ok := namesById[peer.UserId]; !ok && peer.UserId > 0 {
+			missing = append(missing, peer.UserId)
+		}
+	}
+	if len(missing) > 0 {
+		for _, user := range service.AllService.UserService.ListByIds(missing) {
+			namesById[user.Id] = user.Username
+		}
+	}
 	data := make([]*apiResp.GroupPeerPayload, 0, len(peerList.Peers))
 	for _, peer := range peerList.Peers {
 		uname, ok := namesById[peer.UserId]
@@ -137,14 +151,25 @@ func (g *Group) Peers(c *gin.Context) {
 // @Security BearerAuth
 func (g *Group) Device(c *gin.Context) {
 	u := service.AllService.UserService.CurUser(c)
-	if !service.AllService.UserService.IsAdmin(u) {
-		response.Error(c, "Permission denied")
-		return
+	accessible := service.AllService.DeviceGroupPermissionService.AccessibleGroups(u)
+	data := make([]*apiResp.DeviceGroupPayload, 0, len(accessible))
+	if len(accessible) > 0 {
+		ids := make([]uint, 0, len(accessible))
+		for id := range accessible {
+			ids = append(ids, id)
+		}
+		groups := service.AllService.GroupService.DeviceGroupList(1, 999, func(tx *gorm.DB) {
+			tx.Where("id in (?)", ids)
+		})
+		for _, dg := range groups.DeviceGroups {
+			dp := &apiResp.DeviceGroupPayload{}
+			dp.FromDeviceGroup(dg, accessible[dg.Id])
+			data = append(data, dp)
+		}
 	}
-	allGroup := service.AllService.GroupService.DeviceGroupList(1, 999, nil)
 
 	c.JSON(http.StatusOK, response.DataResponse{
-		Total: 0,
-		Data:  allGroup.DeviceGroups,
+		Total: uint(len(data)),
+		Data:  data,
 	})
 }
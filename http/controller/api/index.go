@@ -2,6 +2,7 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	requstform "github.com/RobertLesgros/rustdesk-interface/v2/http/request/api"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
@@ -58,6 +59,55 @@ func (i *Index) Heartbeat(c *gin.Context) {
 	if time.Now().Unix()-peer.LastOnlineTime >= 30 {
 		upp := &model.Peer{RowId: peer.RowId, LastOnlineTime: time.Now().Unix(), LastOnlineIp: c.ClientIP()}
 		service.AllService.PeerService.Update(upp)
+		service.AllService.GeoipService.RefreshPeer(peer, upp.LastOnlineIp)
+	}
+	service.AllService.PresenceService.Touch(peer, c.ClientIP())
+	// L'uuid doit correspondre, sinon n'importe qui connaissant l'ID lirait la stratégie et les actions de l'appareil
+	if peer.Uuid != info.Uuid {
+		c.JSON(http.StatusOK, gin.H{})
+		return
+	}
+	res := gin.H{}
+	// Stratégie de configuration, envoyée uniquement si la version appliquée diffère
+	for k, v := range service.AllService.StrategyService.HeartbeatPayload(peer, info.ModifiedAt) {
+		res[k] = v
+	}
+	// Actions en attente (sysinfo, disconnect, unlink)
+	for k, v := range service.AllService.PeerActionService.HeartbeatPayload(peer, info.Conns) {
+		res[k] = v
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ActionAck Confirmer une action
+// @Tags Accueil
+// @Summary Confirmer une action reçue par heartbeat
+// @Description Confirmer une action reçue par heartbeat
+// @Accept  json
+// @Produce  json
+// @Param body body requstform.PeerActionAckForm true "Résultat de l'action"
+// @Success 200 {object} nil
+// @Failure 500 {object} response.ErrorResponse
+// @Router /heartbeat/ack [post]
+func (i *Index) ActionAck(c *gin.Context) {
+	f := &requstform.PeerActionAckForm{}
+	if err := c.ShouldBindJSON(f); err != nil {
+		response.Error(c, response.TranslateMsg(c, "ParamsError")+err.Error())
+		return
+	}
+	errList := global.Validator.ValidStruct(c, f)
+	if len(errList) > 0 {
+		response.Error(c, errList[0])
+		return
+	}
+	peer := service.AllService.PeerService.FindById(f.Id)
+	if peer == nil || peer.RowId == 0 || peer.Uuid != f.Uuid {
+		response.Error(c, response.TranslateMsg(c, "ItemNotFound"))
+		return
+	}
+	if err := service.AllService.PeerActionService.Ack(peer.Id, f.ActionId, f.Success, f.Result); err != nil {
+		response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
+		return
 	}
 	c.JSON(http.StatusOK, gin.H{})
 }
@@ -8,8 +8,10 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/api"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	apiResp "github.com/RobertLesgros/rustdesk-interface/v2/http/response/api"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
 	"net/http"
 )
 
@@ -68,6 +70,12 @@ func (l *Login) Login(c *gin.Context) {
 		return
 	}
 
+	if err, reason := service.AllService.AccessPolicyService.CheckAccess(u, clientIp); err != nil {
+		audit.LogAccessDenied(c, u.Id, "client_login", reason)
+		response.Error(c, response.TranslateMsg(c, err.Error()))
+		return
+	}
+
 	// Déterminer s'il s'agit du client Web ou de l'application en fonction de referer
 	ref := c.GetHeader("referer")
 	if ref != "" {
@@ -84,6 +92,17 @@ func (l *Login) Login(c *gin.Context) {
 		Platform: f.DeviceInfo.Os,
 	})
 
+	if global.Config.App.ClientVersionAlert && f.Id != "" {
+		// Alerte d'audit si la version du client ne respecte pas la politique
+		peer := service.AllService.PeerService.FindById(f.Id)
+		if peer.RowId > 0 {
+			compliance := service.AllService.ClientVersionService.Evaluate(peer.Os, peer.Version)
+			if compliance == model.VersionComplianceOutdated || compliance == model.VersionComplianceUnsupported {
+				audit.LogClientVersionAlert(c, u.Id, peer.Id, utils.NormalizePlatform(peer.Os), peer.Version, compliance)
+			}
+		}
+	}
+
 	c.JSON(http.StatusOK, apiResp.LoginRes{
 		AccessToken: ut.Token,
 		Type:        "access_token",
@@ -8,6 +8,7 @@ import (
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/request/api"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	apiResp "github.com/RobertLesgros/rustdesk-interface/v2/http/response/api"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
@@ -92,6 +93,14 @@ func (o *Oauth) OidcAuthQueryPre(c *gin.Context) (*model.User, *model.UserToken)
 		return nil, nil
 	}
 
+	// Vérifier la politique d'accès pour l'IP du client
+	if err, reason := service.AllService.AccessPolicyService.CheckAccess(u, c.ClientIP()); err != nil {
+		service.AllService.OauthService.DeleteOauthCache(q.Code)
+		audit.LogAccessDenied(c, u.Id, "oauth_login", reason)
+		response.Error(c, response.TranslateMsg(c, err.Error()))
+		return nil, nil
+	}
+
 	// Supprimer le cache OAuth
 	service.AllService.OauthService.DeleteOauthCache(q.Code)
 
@@ -240,6 +249,14 @@ func (o *Oauth) OauthCallback(c *gin.Context) {
 				return
 			}
 		}
+		if err, reason := service.AllService.AccessPolicyService.CheckAccess(user, c.ClientIP()); err != nil {
+			oauthService.DeleteOauthCache(cacheKey)
+			audit.LogAccessDenied(c, user.Id, "oauth_callback", reason)
+			c.HTML(http.StatusOK, "oauth_fail.html", gin.H{
+				"message": err.Error(),
+			})
+			return
+		}
 		oauthCache.UserId = user.Id
 		oauthService.SetOauthCache(cacheKey, oauthCache, 0)
 		// Si c'est webadmin, rediriger vers webadmin après une connexion réussie
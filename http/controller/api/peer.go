@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/RobertLesgros/rustdesk-interface/v2/global"
 	requstform "github.com/RobertLesgros/rustdesk-interface/v2/http/request/api"
 	"github.com/RobertLesgros/rustdesk-interface/v2/http/response"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
@@ -40,7 +41,10 @@ func (p *Peer) SysInfo(c *gin.Context) {
 			response.Error(c, response.TranslateMsg(c, "OperationFailed")+err.Error())
 			return
 		}
+		// Même uuid avec un autre ID : réinstallation ou changement d'ID
+		service.AllService.PeerIdentityService.DetectIdChange(pe)
 	} else {
+		service.AllService.PeerIdentityService.RecordUuidChange(pe, fpe.Uuid)
 		if pe.UserId == 0 {
 			pe.UserId = service.AllService.UserService.FindLatestUserIdFromLoginLogByUuid(pe.Uuid, pe.Id)
 		}
@@ -52,6 +56,14 @@ func (p *Peer) SysInfo(c *gin.Context) {
 			return
 		}
 	}
+	// Les champs vides ne sont pas mis à jour, l'instantané est pris sur l'appareil enregistré
+	saved := service.AllService.PeerService.FindById(f.Id)
+	if err = service.AllService.PeerSysinfoService.Record(saved); err != nil {
+		global.Logger.Warn("Record sysinfo history of peer ", f.Id, " failed: ", err)
+	}
+	// Affectation automatique au groupe d'appareils
+	service.AllService.DeviceGroupRuleService.Apply(saved, c.ClientIP())
+	service.AllService.PeerActionService.AckSysinfo(f.Id)
 	//SYSINFO_UPDATED Téléchargement réussi
 	//ID_NOT_FOUND Sera téléchargé au prochain battement de cœur
 	// Répondre directement avec du texte
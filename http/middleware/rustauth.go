@@ -3,6 +3,7 @@ package middleware
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/RobertLesgros/rustdesk-interface/v2/global"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
 	"github.com/RobertLesgros/rustdesk-interface/v2/service"
 )
 
@@ -59,6 +60,15 @@ func RustAuth() gin.HandlerFunc {
 			return
 		}
 
+		if err, reason := service.AllService.AccessPolicyService.CheckAccess(user, c.ClientIP()); err != nil {
+			audit.LogAccessDenied(c, user.Id, "token", reason)
+			c.JSON(401, gin.H{
+				"error": "Unauthorized",
+			})
+			c.Abort()
+			return
+		}
+
 		c.Set("curUser", user)
 		c.Set("token", token)
 
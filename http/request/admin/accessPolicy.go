@@ -0,0 +1,82 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+)
+
+type IpAccessRuleForm struct {
+	Id     uint   `json:"id"`
+	Type   int    `json:"type" validate:"required,gte=1,lte=2"` // 1: 个人 2: 群组
+	ToId   uint   `json:"to_id" validate:"required,gt=0"`
+	Action int    `json:"action" validate:"required,gte=1,lte=2"` // 1: 允许 2: 拒绝
+	Cidr   string `json:"cidr" validate:"required"`
+	Remark string `json:"remark"`
+}
+
+func (f *IpAccessRuleForm) ToIpAccessRule() *model.IpAccessRule {
+	r := &model.IpAccessRule{}
+	r.Id = f.Id
+	r.Type = f.Type
+	r.ToId = f.ToId
+	r.Action = f.Action
+	r.Cidr = f.Cidr
+	r.Remark = f.Remark
+	return r
+}
+
+type IpAccessRuleQuery struct {
+	Type int  `form:"type"`
+	ToId uint `form:"to_id"`
+	PageQuery
+}
+
+type IpTestForm struct {
+	UserId  uint   `json:"user_id"`
+	GroupId uint   `json:"group_id"`
+	Ip      string `json:"ip" validate:"required,ip"`
+}
+
+type AccessScheduleForm struct {
+	Id           uint                 `json:"id"`
+	Name         string               `json:"name" validate:"required"`
+	Type         int                  `json:"type" validate:"required,gte=1,lte=2"` // 1: 个人 2: 群组
+	ToId         uint                 `json:"to_id" validate:"required,gt=0"`
+	Timezone     string               `json:"timezone"`
+	Windows      []utils.WeeklyWindow `json:"windows" validate:"required,min=1"`
+	Holidays     []string             `json:"holidays"`
+	RevokeTokens bool                 `json:"revoke_tokens"`
+	Status       model.StatusCode     `json:"status" validate:"required,gte=1,lte=2"`
+}
+
+func (f *AccessScheduleForm) ToAccessSchedule() *model.AccessSchedule {
+	r := &model.AccessSchedule{}
+	r.Id = f.Id
+	r.Name = f.Name
+	r.Type = f.Type
+	r.ToId = f.ToId
+	r.Timezone = f.Timezone
+	windows, _ := json.Marshal(f.Windows)
+	r.Windows = windows
+	if f.Holidays == nil {
+		f.Holidays = []string{}
+	}
+	holidays, _ := json.Marshal(f.Holidays)
+	r.Holidays = holidays
+	r.RevokeTokens = f.RevokeTokens
+	r.Status = f.Status
+	return r
+}
+
+type AccessScheduleQuery struct {
+	Type int  `form:"type"`
+	ToId uint `form:"to_id"`
+	PageQuery
+}
+
+type ScheduleTestForm struct {
+	UserId  uint   `json:"user_id"`
+	GroupId uint   `json:"group_id"`
+	Time    string `json:"time"` // RFC3339, 为空时使用当前时间
+}
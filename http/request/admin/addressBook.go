@@ -24,6 +24,7 @@ type AddressBookForm struct {
 	LoginName        string   `json:"loginName" `
 	SameServer       bool     `json:"sameServer"`
 	CollectionId     uint     `json:"collection_id"`
+	FolderId         uint     `json:"folder_id"`
 }
 
 func (a AddressBookForm) ToAddressBook() *model.AddressBook {
@@ -48,6 +49,7 @@ func (a AddressBookForm) ToAddressBook() *model.AddressBook {
 		LoginName:        a.LoginName,
 		SameServer:       a.SameServer,
 		CollectionId:     a.CollectionId,
+		FolderId:         a.FolderId,
 	}
 
 }
@@ -75,6 +77,7 @@ func (a AddressBookForm) ToAddressBooks() []*model.AddressBook {
 			LoginName:        a.LoginName,
 			SameServer:       a.SameServer,
 			CollectionId:     a.CollectionId,
+			FolderId:         a.FolderId,
 		})
 	}
 	return abs
@@ -83,6 +86,7 @@ func (a AddressBookForm) ToAddressBooks() []*model.AddressBook {
 type AddressBookQuery struct {
 	UserId       int    `form:"user_id"`
 	CollectionId *int   `form:"collection_id"`
+	FolderId     *int   `form:"folder_id"`
 	IsMy         int    `form:"is_my"`
 	Username     string `form:"username"`
 	Hostname     string `form:"hostname"`
@@ -133,3 +137,23 @@ type BatchUpdateTagsForm struct {
 	RowIds []uint   `json:"row_ids"`
 	Tags   []string `json:"tags"`
 }
+
+type AddressBookFolderQuery struct {
+	CollectionId uint `form:"collection_id" validate:"required,gt=0"`
+}
+
+type AddressBookFolderRenameForm struct {
+	Id   uint   `json:"id" validate:"required,gt=0"`
+	Name string `json:"name" validate:"required,max=100,excludes=/"`
+}
+
+type AddressBookFolderMoveForm struct {
+	Id       uint `json:"id" validate:"required,gt=0"`
+	ParentId uint `json:"parent_id"` // 0 为集合根目录
+}
+
+type AddressBookCollectionPreviewQuery struct {
+	UserId uint   `form:"user_id"` // 集合所有者, 决定可以匹配的设备
+	Query  string `form:"query"`
+	PageQuery
+}
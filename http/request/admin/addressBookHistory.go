@@ -0,0 +1,18 @@
+package admin
+
+type AddressBookHistoryQuery struct {
+	UserId       uint   `form:"user_id"`
+	CollectionId int    `form:"collection_id,default=-1"` // 0 为默认地址簿, -1 不筛选
+	Entity       string `form:"entity"`                   // peer / tag
+	EntityKey    string `form:"entity_key"`               // 设备 id 或标签 id
+	Source       string `form:"source"`                   // client / admin / web
+	From         int64  `form:"from"`
+	To           int64  `form:"to"`
+	PageQuery
+}
+
+type AddressBookRestoreForm struct {
+	UserId       uint  `json:"user_id"` // 仅管理员接口使用
+	CollectionId uint  `json:"collection_id"`
+	Timestamp    int64 `json:"timestamp" validate:"required,gt=0"` // 恢复到该时间点的状态
+}
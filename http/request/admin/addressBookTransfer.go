@@ -0,0 +1,17 @@
+package admin
+
+type AddressBookExportQuery struct {
+	UserId         uint   `form:"user_id"` // 仅管理员接口使用
+	CollectionId   uint   `form:"collection_id"`
+	Format         string `form:"format" validate:"omitempty,oneof=csv json"` // 默认 json
+	IncludeSecrets bool   `form:"include_secrets"`                            // 是否导出密码和 hash
+}
+
+// AddressBookImportForm multipart 表单, 文件字段为 file
+type AddressBookImportForm struct {
+	UserId       uint   `form:"user_id"` // 仅管理员接口使用
+	CollectionId uint   `form:"collection_id"`
+	Format       string `form:"format" validate:"omitempty,oneof=csv json"` // 为空时按文件扩展名判断
+	Strategy     string `form:"strategy,default=skip" validate:"oneof=skip overwrite duplicate"`
+	DryRun       bool   `form:"dry_run"`
+}
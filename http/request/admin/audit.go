@@ -3,7 +3,9 @@ package admin
 type AuditQuery struct {
 	PeerId   string `form:"peer_id"`
 	FromPeer string `form:"from_peer"`
+	Country  string `form:"country"` // 地理信息的国家代码, 仅连接日志
 	PageQuery
+	SearchQuery
 }
 
 type AuditConnLogIds struct {
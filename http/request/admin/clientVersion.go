@@ -0,0 +1,20 @@
+package admin
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model"
+
+type ClientVersionPolicyForm struct {
+	Id                 uint   `json:"id"`
+	Platform           string `json:"platform" validate:"required,oneof=windows macos linux android ios"`
+	MinVersion         string `json:"min_version" validate:"max=32"`
+	RecommendedVersion string `json:"recommended_version" validate:"max=32"`
+	Remark             string `json:"remark"`
+}
+
+func (f *ClientVersionPolicyForm) ToClientVersionPolicy() *model.ClientVersionPolicy {
+	return &model.ClientVersionPolicy{
+		Platform:           f.Platform,
+		MinVersion:         f.MinVersion,
+		RecommendedVersion: f.RecommendedVersion,
+		Remark:             f.Remark,
+	}
+}
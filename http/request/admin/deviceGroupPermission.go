@@ -0,0 +1,28 @@
+package admin
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model"
+
+type DeviceGroupPermissionForm struct {
+	Id            uint `json:"id"`
+	DeviceGroupId uint `json:"device_group_id" validate:"required,gt=0"`
+	Type          int  `json:"type" validate:"required,gte=1,lte=2"` // 1: 个人 2: 群组
+	ToId          uint `json:"to_id" validate:"required,gt=0"`
+	Permission    int  `json:"permission" validate:"required,gte=1,lte=2"` // 1: 查看 2: 连接
+}
+
+func (f *DeviceGroupPermissionForm) ToDeviceGroupPermission() *model.DeviceGroupPermission {
+	r := &model.DeviceGroupPermission{}
+	r.Id = f.Id
+	r.DeviceGroupId = f.DeviceGroupId
+	r.Type = f.Type
+	r.ToId = f.ToId
+	r.Permission = f.Permission
+	return r
+}
+
+type DeviceGroupPermissionQuery struct {
+	DeviceGroupId uint `form:"device_group_id"`
+	Type          int  `form:"type"`
+	ToId          uint `form:"to_id"`
+	PageQuery
+}
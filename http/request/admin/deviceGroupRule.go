@@ -0,0 +1,40 @@
+package admin
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model"
+
+type DeviceGroupRuleForm struct {
+	Id            uint             `json:"id"`
+	Name          string           `json:"name" validate:"required"`
+	Sort          int              `json:"sort"`
+	DeviceGroupId uint             `json:"device_group_id" validate:"required,gt=0"`
+	HostnameRegex string           `json:"hostname_regex"`
+	Os            string           `json:"os"`
+	Version       string           `json:"version"`
+	IpCidr        string           `json:"ip_cidr"`
+	UserGroupId   uint             `json:"user_group_id"`
+	Status        model.StatusCode `json:"status" validate:"required,gte=1,lte=2"`
+}
+
+func (f *DeviceGroupRuleForm) ToDeviceGroupRule() *model.DeviceGroupRule {
+	r := &model.DeviceGroupRule{}
+	r.Id = f.Id
+	r.Name = f.Name
+	r.Sort = f.Sort
+	r.DeviceGroupId = f.DeviceGroupId
+	r.HostnameRegex = f.HostnameRegex
+	r.Os = f.Os
+	r.Version = f.Version
+	r.IpCidr = f.IpCidr
+	r.UserGroupId = f.UserGroupId
+	r.Status = f.Status
+	return r
+}
+
+type DeviceGroupRuleQuery struct {
+	DeviceGroupId uint `form:"device_group_id"`
+	PageQuery
+}
+
+type DeviceGroupRuleApplyForm struct {
+	DryRun bool `json:"dry_run"`
+}
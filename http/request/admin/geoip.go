@@ -0,0 +1,5 @@
+package admin
+
+type GeoipLookupQuery struct {
+	Ip string `form:"ip" validate:"required,ip"`
+}
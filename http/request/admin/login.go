@@ -9,8 +9,10 @@ type Login struct {
 }
 
 type LoginLogQuery struct {
-	UserId int `form:"user_id"`
-	IsMy   int `form:"is_my"`
+	UserId  int    `form:"user_id"`
+	IsMy    int    `form:"is_my"`
+	Country string `form:"country"` // 地理信息的国家代码
+	Asn     uint   `form:"asn"`
 	PageQuery
 }
 type LoginTokenQuery struct {
@@ -0,0 +1,10 @@
+package admin
+
+type NotificationQuery struct {
+	Unread bool `form:"unread"`
+	PageQuery
+}
+
+type NotificationReadForm struct {
+	Ids []uint `json:"ids"` // 为空时全部标记为已读
+}
@@ -14,6 +14,8 @@ type PeerForm struct {
 	Version  string `json:"version"`
 	GroupId  uint   `json:"group_id"`
 	Alias    string `json:"alias"`
+	// Attributes 自定义字段, 为空时不修改
+	Attributes map[string]string `json:"attributes"`
 }
 
 type PeerBatchDeleteForm struct {
@@ -46,8 +48,42 @@ type PeerQuery struct {
 	Ip       string `json:"ip" form:"ip"`
 	Username string `json:"username" form:"username"`
 	Alias    string `json:"alias" form:"alias"`
+	Online   int    `json:"online" form:"online"` // 1: 在线 2: 离线
+	Stale    int    `json:"stale" form:"stale"`   // 1: 不活跃 2: 活跃
+	Version  string `json:"version" form:"version"`
+	Country  string `json:"country" form:"country"` // 地理信息的国家代码
+	// Compliance 版本合规状态: ok/outdated/unsupported/unknown
+	Compliance string `json:"compliance" form:"compliance" validate:"omitempty,oneof=ok outdated unsupported unknown"`
+	// Attr 自定义字段过滤, JSON 对象 name => 值, 如 {"asset_tag":"AT-1","cost":">=100","bought":"2025-01-01..2025-12-31"}
+	Attr map[string]string `json:"attr" form:"attr"`
+	SearchQuery
+}
+
+type PeerSysinfoQuery struct {
+	PageQuery
+	PeerId string `json:"peer_id" form:"peer_id"`
+	From   int64  `json:"from" form:"from"`
+	To     int64  `json:"to" form:"to"`
+}
+
+type PeerPresenceEventQuery struct {
+	PageQuery
+	PeerId string `json:"peer_id" form:"peer_id"`
 }
 
 type SimpleDataQuery struct {
 	Ids []string `json:"ids" form:"ids"`
 }
+
+type PeerExportQuery struct {
+	PeerQuery
+	Format string `json:"format" form:"format" validate:"omitempty,oneof=csv json"` // 默认 csv
+}
+
+// PeerImportForm multipart 表单, 文件字段为 file
+type PeerImportForm struct {
+	Format string `form:"format" validate:"omitempty,oneof=csv json"` // 为空时按文件扩展名判断
+	// Mapping 列映射 JSON, 源列名 => 字段名, 字段名为空表示忽略该列
+	Mapping string `form:"mapping"`
+	DryRun  bool   `form:"dry_run"`
+}
@@ -0,0 +1,19 @@
+package admin
+
+type PeerActionForm struct {
+	PeerIds    []string `json:"peer_ids" validate:"required,min=1"`
+	Action     string   `json:"action" validate:"required,oneof=sysinfo disconnect unlink"`
+	Conns      []int    `json:"conns"`       // disconnect: 会话id, 为空时断开全部
+	TtlMinutes int      `json:"ttl_minutes"` // 有效期, 0 使用默认值
+}
+
+type PeerActionCancelForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
+
+type PeerActionQuery struct {
+	PeerId string `form:"peer_id"`
+	Action string `form:"action"`
+	Status int    `form:"status"`
+	PageQuery
+}
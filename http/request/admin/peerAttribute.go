@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+type PeerAttributeDefForm struct {
+	Id       uint     `json:"id"`
+	Name     string   `json:"name" validate:"required,max=64"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type" validate:"required,oneof=string number date enum"`
+	Options  []string `json:"options"`
+	Required bool     `json:"required"`
+	Pattern  string   `json:"pattern"`
+	Min      *float64 `json:"min"`
+	Max      *float64 `json:"max"`
+	Sort     int      `json:"sort"`
+}
+
+func (f *PeerAttributeDefForm) ToPeerAttributeDef() *model.PeerAttributeDef {
+	d := &model.PeerAttributeDef{}
+	d.Id = f.Id
+	d.Name = f.Name
+	d.Label = f.Label
+	d.Type = f.Type
+	if f.Options == nil {
+		f.Options = []string{}
+	}
+	opts, _ := json.Marshal(f.Options)
+	d.Options = opts
+	d.Required = f.Required
+	d.Pattern = f.Pattern
+	d.Min = f.Min
+	d.Max = f.Max
+	d.Sort = f.Sort
+	return d
+}
+
+type PeerAttributeDefQuery struct {
+	PageQuery
+}
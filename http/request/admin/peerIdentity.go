@@ -0,0 +1,16 @@
+package admin
+
+type PeerIdentityQuery struct {
+	PeerId string `form:"peer_id"` // 旧 id 或新 id
+	Kind   string `form:"kind"`
+	Status int    `form:"status"`
+	PageQuery
+}
+
+type PeerIdentityHistoryQuery struct {
+	PeerId string `form:"peer_id" validate:"required"`
+}
+
+type PeerIdentityResolveForm struct {
+	Id uint `json:"id" validate:"required,gt=0"`
+}
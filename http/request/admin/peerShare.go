@@ -0,0 +1,10 @@
+package admin
+
+type PeerShareQuery struct {
+	UserId   int    `form:"user_id"`
+	PeerId   string `form:"peer_id"`
+	Type     int    `form:"type"`
+	ToId     int    `form:"to_id"`
+	Received int    `form:"received"` // my: 1 列出共享给我的
+	PageQuery
+}
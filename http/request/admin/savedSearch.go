@@ -0,0 +1,30 @@
+package admin
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model"
+
+type SavedSearchForm struct {
+	Id       uint   `json:"id"`
+	Resource string `json:"resource" validate:"required,oneof=peer user audit_conn audit_file"`
+	Name     string `json:"name" validate:"required,max=64"`
+	Query    string `json:"query" validate:"max=1024"`
+	Sort     string `json:"sort" validate:"max=128"`
+}
+
+func (f *SavedSearchForm) ToSavedSearch() *model.SavedSearch {
+	r := &model.SavedSearch{}
+	r.Id = f.Id
+	r.Resource = f.Resource
+	r.Name = f.Name
+	r.Query = f.Query
+	r.Sort = f.Sort
+	return r
+}
+
+type SavedSearchQuery struct {
+	Resource string `form:"resource"`
+	PageQuery
+}
+
+type SearchFieldsQuery struct {
+	Resource string `form:"resource" validate:"required"`
+}
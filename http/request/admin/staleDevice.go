@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+type StaleDevicePolicyForm struct {
+	Enable          bool   `json:"enable"`
+	FlagDays        int    `json:"flag_days" validate:"required,gt=0"`
+	DeleteDays      int    `json:"delete_days" validate:"gte=0"`
+	ExcludeGroupIds []uint `json:"exclude_group_ids"`
+}
+
+func (f *StaleDevicePolicyForm) ToStaleDevicePolicy() *model.StaleDevicePolicy {
+	p := &model.StaleDevicePolicy{}
+	p.Enable = f.Enable
+	p.FlagDays = f.FlagDays
+	p.DeleteDays = f.DeleteDays
+	if f.ExcludeGroupIds == nil {
+		f.ExcludeGroupIds = []uint{}
+	}
+	ids, _ := json.Marshal(f.ExcludeGroupIds)
+	p.ExcludeGroupIds = ids
+	return p
+}
+
+type StaleDeviceRunQuery struct {
+	PageQuery
+}
@@ -0,0 +1,53 @@
+package admin
+
+import (
+	"encoding/json"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+type StrategyForm struct {
+	Id      uint              `json:"id"`
+	Name    string            `json:"name" validate:"required"`
+	Options map[string]string `json:"options"`
+	Status  model.StatusCode  `json:"status" validate:"required,gte=1,lte=2"`
+	Remark  string            `json:"remark"`
+}
+
+func (f *StrategyForm) ToStrategy() *model.Strategy {
+	r := &model.Strategy{}
+	r.Id = f.Id
+	r.Name = f.Name
+	if f.Options == nil {
+		f.Options = map[string]string{}
+	}
+	options, _ := json.Marshal(f.Options)
+	r.Options = options
+	r.Status = f.Status
+	r.Remark = f.Remark
+	return r
+}
+
+type StrategyQuery struct {
+	Name string `form:"name"`
+	PageQuery
+}
+
+type StrategyAssignForm struct {
+	Id         uint `json:"id"`
+	StrategyId uint `json:"strategy_id" validate:"required,gt=0"`
+	Type       int  `json:"type" validate:"required,gte=1,lte=3"` // 1: 设备组 2: 用户 3: 设备
+	ToId       uint `json:"to_id" validate:"required,gt=0"`
+}
+
+type StrategyAssignmentQuery struct {
+	StrategyId uint `form:"strategy_id"`
+	Type       int  `form:"type"`
+	PageQuery
+}
+
+type PeerStrategyStateQuery struct {
+	StrategyId uint   `form:"strategy_id"`
+	PeerId     string `form:"peer_id"`
+	Pending    bool   `form:"pending"` // 只显示还未应用最新版本的设备
+	PageQuery
+}
@@ -35,3 +35,30 @@ type TagQuery struct {
 	CollectionId *int `form:"collection_id"`
 	PageQuery
 }
+
+type GlobalTagForm struct {
+	Id     uint   `json:"id"`
+	Name   string `json:"name" validate:"required,max=100"`
+	Color  uint   `json:"color" validate:"required"`
+	Remark string `json:"remark"`
+}
+
+func (f *GlobalTagForm) ToGlobalTag() *model.GlobalTag {
+	t := &model.GlobalTag{}
+	t.Id = f.Id
+	t.Name = f.Name
+	t.Color = f.Color
+	t.Remark = f.Remark
+	return t
+}
+
+type GlobalTagQuery struct {
+	Name string `form:"name"`
+	PageQuery
+}
+
+type GlobalTagMergeForm struct {
+	GlobalTagId uint     `json:"global_tag_id" validate:"required,gt=0"`
+	TagIds      []uint   `json:"tag_ids"` // 要合并的集合标签
+	Names       []string `json:"names"`   // 合并所有集合中这些名称的标签
+}
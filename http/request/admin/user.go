@@ -48,10 +48,29 @@ type PageQuery struct {
 	PageSize uint `form:"page_size"`
 }
 
+// SearchQuery 筛选表达式和排序, 如 q=os:windows AND last_online<7d&sort=-last_online
+type SearchQuery struct {
+	Q    string `json:"q" form:"q"`
+	Sort string `json:"sort" form:"sort"`
+}
+
 type UserQuery struct {
 	PageQuery
 	Username string `form:"username"`
+	SearchQuery
+}
+type UserDeleteForm struct {
+	Id uint `json:"id"`
+	// TransferTo 删除前将设备、地址簿等转给该用户, 0 表示不转移
+	TransferTo uint `json:"transfer_to"`
 }
+
+type UserTransferForm struct {
+	FromUserId uint `json:"from_user_id" validate:"required,gt=0"`
+	ToUserId   uint `json:"to_user_id" validate:"required,gt=0,nefield=FromUserId"`
+	DryRun     bool `json:"dry_run"` // 仅预览
+}
+
 type UserPasswordForm struct {
 	Id       uint   `json:"id" validate:"required"`
 	Password string `json:"password" validate:"required,gte=4,lte=32"`
@@ -9,7 +9,8 @@ type AddressBookFormData struct {
 }
 
 type AddressBookForm struct {
-	Data string `json:"data" example:"{\"tags\":[\"tag1\",\"tag2\",\"tag3\"],\"peers\":[{\"id\":\"abc\",\"username\":\"abv-l\",\"hostname\":\"\",\"platform\":\"Windows\",\"alias\":\"\",\"tags\":[\"tag1\",\"tag2\"],\"hash\":\"hash\"}],\"tag_colors\":\"{\\\"tag1\\\":4288585374,\\\"tag2\\\":4278238420,\\\"tag3\\\":4291681337}\"}"`
+	Data     string  `json:"data" example:"{\"tags\":[\"tag1\",\"tag2\",\"tag3\"],\"peers\":[{\"id\":\"abc\",\"username\":\"abv-l\",\"hostname\":\"\",\"platform\":\"Windows\",\"alias\":\"\",\"tags\":[\"tag1\",\"tag2\"],\"hash\":\"hash\"}],\"tag_colors\":\"{\\\"tag1\\\":4288585374,\\\"tag2\\\":4278238420,\\\"tag3\\\":4291681337}\"}"`
+	Revision *uint64 `json:"revision"` // 可选, 与 If-Match 相同, 版本号不是最新时拒绝
 }
 
 type PeerForm struct {
@@ -76,4 +77,17 @@ type PeerInfoInHeartbeat struct {
 	Id   string `json:"id"`
 	Uuid string `json:"uuid"`
 	Ver  int    `json:"ver"`
+	// ModifiedAt 客户端已应用的策略版本
+	ModifiedAt int64 `json:"modified_at"`
+	// Conns 当前会话
+	Conns []int `json:"conns"`
+}
+
+// PeerActionAckForm 客户端确认操作结果
+type PeerActionAckForm struct {
+	Id       string `json:"id" validate:"required"`
+	Uuid     string `json:"uuid" validate:"required"`
+	ActionId uint   `json:"action_id" validate:"required,gt=0"`
+	Success  bool   `json:"success"`
+	Result   string `json:"result"`
 }
@@ -15,3 +15,9 @@ type SharedProfilesPayload struct {
 	Note  string `json:"note"`
 	Rule  int    `json:"rule"`
 }
+
+// AbConflictResponse 409, revision 为地址簿当前版本号
+type AbConflictResponse struct {
+	Error    string `json:"error"`
+	Revision uint64 `json:"revision"`
+}
@@ -39,6 +39,8 @@ type GroupPeerPayload struct {
 	UserName        string           `json:"user_name"`
 	Note            string           `json:"note"`
 	DeviceGroupName string           `json:"device_group_name"`
+	Online          bool             `json:"online"`
+	OnlineSince     int64            `json:"online_since"`
 }
 type PeerPayloadInfo struct {
 	DeviceName string `json:"device_name"`
@@ -70,4 +72,22 @@ func (gpp *GroupPeerPayload) FromPeer(p *model.Peer, username string, dGroupName
 	gpp.Note = ""
 	gpp.UserName = username
 	gpp.DeviceGroupName = dGroupName
+	gpp.Online = p.Online
+	gpp.OnlineSince = p.OnlineSince
+}
+
+// DeviceGroupPayload 用户可访问的设备组
+type DeviceGroupPayload struct {
+	Id         uint   `json:"id"`
+	Name       string `json:"name"`
+	Permission string `json:"permission"` // read, connect
+}
+
+func (dgp *DeviceGroupPayload) FromDeviceGroup(dg *model.DeviceGroup, permission int) {
+	dgp.Id = dg.Id
+	dgp.Name = dg.Name
+	dgp.Permission = "read"
+	if permission == model.DeviceGroupPermissionConnect {
+		dgp.Permission = "connect"
+	}
 }
@@ -37,6 +37,9 @@ func Init(g *gin.Engine) {
 	AuditBind(adg)
 	AddressBookCollectionBind(adg)
 	AddressBookCollectionRuleBind(adg)
+	AddressBookFolderBind(adg)
+	GlobalTagBind(adg)
+	PeerShareBind(adg)
 	UserTokenBind(adg)
 
 	//deprecated by ConfigBind
@@ -50,6 +53,18 @@ func Init(g *gin.Engine) {
 
 	RustdeskCmdBind(adg)
 	DeviceGroupBind(adg)
+	AccessPolicyBind(adg)
+	StaleDeviceBind(adg)
+	PeerAttributeBind(adg)
+	DeviceGroupRuleBind(adg)
+	DeviceGroupPermissionBind(adg)
+	StrategyBind(adg)
+	PeerActionBind(adg)
+	ClientVersionBind(adg)
+	SavedSearchBind(adg)
+	GeoipBind(adg)
+	PeerIdentityBind(adg)
+	AddressBookHistoryBind(adg)
 	//访问静态文件
 	//g.StaticFS("/upload", http.Dir(global.Config.Gin.ResourcesPath+"/upload"))
 }
@@ -91,6 +106,7 @@ func UserBind(rg *gin.RouterGroup) {
 		aRP.POST("/create", middleware.SensitiveOperationLimiter(), cont.Create)
 		aRP.POST("/update", middleware.SensitiveOperationLimiter(), cont.Update)
 		aRP.POST("/delete", middleware.SensitiveOperationLimiter(), cont.Delete)
+		aRP.POST("/transfer", middleware.SensitiveOperationLimiter(), cont.Transfer)
 		aRP.POST("/changePwd", middleware.SensitiveOperationLimiter(), cont.UpdatePassword)
 	}
 }
@@ -119,6 +135,113 @@ func DeviceGroupBind(rg *gin.RouterGroup) {
 	}
 }
 
+func DeviceGroupRuleBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/device_group_rule").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.DeviceGroupRule{}
+		aR.GET("/list", cont.List)
+		aR.GET("/detail/:id", cont.Detail)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+		aR.POST("/apply", cont.Apply)
+	}
+}
+
+func DeviceGroupPermissionBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/device_group_permission").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.DeviceGroupPermission{}
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+	}
+}
+
+func StrategyBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/strategy").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.Strategy{}
+		aR.GET("/list", cont.List)
+		aR.GET("/detail/:id", cont.Detail)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+		aR.GET("/assignments", cont.AssignmentList)
+		aR.POST("/assign", cont.Assign)
+		aR.POST("/unassign", cont.Unassign)
+		aR.GET("/states", cont.States)
+	}
+}
+
+func GeoipBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/geoip").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.Geoip{}
+		aR.GET("/status", cont.Status)
+		aR.POST("/reload", cont.Reload)
+		aR.GET("/lookup", cont.Lookup)
+		aR.POST("/backfill", cont.Backfill)
+	}
+}
+
+func SavedSearchBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/saved_search").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.SavedSearch{}
+		aR.GET("/fields", cont.Fields)
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+	}
+}
+
+func ClientVersionBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/client_version").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.ClientVersion{}
+		aR.GET("/policies", cont.Policies)
+		aR.POST("/save", cont.Save)
+		aR.POST("/delete", cont.Delete)
+		aR.GET("/report", cont.Report)
+	}
+}
+
+func PeerActionBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/peer_action").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.PeerAction{}
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/cancel", cont.Cancel)
+	}
+}
+
+func AccessPolicyBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/ip_access_rule").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.AccessPolicy{}
+		aR.GET("/list", cont.IpRuleList)
+		aR.GET("/detail/:id", cont.IpRuleDetail)
+		aR.POST("/create", cont.IpRuleCreate)
+		aR.POST("/update", cont.IpRuleUpdate)
+		aR.POST("/delete", cont.IpRuleDelete)
+		aR.POST("/testIp", cont.TestIp)
+	}
+	sR := rg.Group("/access_schedule").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.AccessPolicy{}
+		sR.GET("/list", cont.ScheduleList)
+		sR.GET("/detail/:id", cont.ScheduleDetail)
+		sR.POST("/create", cont.ScheduleCreate)
+		sR.POST("/update", cont.ScheduleUpdate)
+		sR.POST("/delete", cont.ScheduleDelete)
+		sR.POST("/testTime", cont.TestSchedule)
+	}
+}
+
 func TagBind(rg *gin.RouterGroup) {
 	aR := rg.Group("/tag").Use(middleware.AdminPrivilege())
 	{
@@ -145,6 +268,8 @@ func AddressBookBind(rg *gin.RouterGroup) {
 		arp.POST("/delete", cont.Delete)
 		arp.POST("/batchCreate", cont.BatchCreate)
 		arp.POST("/batchCreateFromPeers", cont.BatchCreateFromPeers)
+		arp.GET("/export", cont.Export)
+		arp.POST("/import", cont.Import)
 
 	}
 }
@@ -160,6 +285,35 @@ func PeerBind(rg *gin.RouterGroup) {
 		aR.POST("/update", cont.Update)
 		aR.POST("/delete", cont.Delete)
 		aR.POST("/batchDelete", cont.BatchDelete)
+		aR.GET("/presenceEvents", cont.PresenceEvents)
+		aR.GET("/sysinfoHistory", cont.SysinfoHistory)
+		aR.GET("/sysinfoDiff", cont.SysinfoDiff)
+		aR.GET("/export", cont.Export)
+		aR.POST("/import", cont.Import)
+	}
+}
+
+func StaleDeviceBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/stale_device").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.StaleDevice{}
+		aR.GET("/policy", cont.Policy)
+		aR.POST("/savePolicy", cont.SavePolicy)
+		aR.POST("/preview", cont.Preview)
+		aR.POST("/run", cont.Run)
+		aR.GET("/runs", cont.Runs)
+	}
+}
+
+func PeerAttributeBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/peer_attribute").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.PeerAttribute{}
+		aR.GET("/list", cont.List)
+		aR.GET("/detail/:id", cont.Detail)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
 	}
 }
 
@@ -208,6 +362,7 @@ func AddressBookCollectionBind(rg *gin.RouterGroup) {
 	{
 		cont := &admin.AddressBookCollection{}
 		aR.GET("/list", cont.List)
+		aR.GET("/preview", cont.Preview)
 		aR.GET("/detail/:id", cont.Detail)
 		aR.POST("/create", cont.Create)
 		aR.POST("/update", cont.Update)
@@ -226,6 +381,38 @@ func AddressBookCollectionRuleBind(rg *gin.RouterGroup) {
 		aR.POST("/delete", cont.Delete)
 	}
 }
+func AddressBookFolderBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/address_book_folder").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.AddressBookFolder{}
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/move", cont.Move)
+		aR.POST("/delete", cont.Delete)
+	}
+}
+func GlobalTagBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/global_tag").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.GlobalTag{}
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+		aR.POST("/merge", cont.Merge)
+	}
+}
+func PeerShareBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/peer_share").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.PeerShare{}
+		aR.GET("/list", cont.List)
+		aR.POST("/create", cont.Create)
+		aR.POST("/update", cont.Update)
+		aR.POST("/delete", cont.Delete)
+	}
+}
 func UserTokenBind(rg *gin.RouterGroup) {
 	aR := rg.Group("/user_token").Use(middleware.AdminPrivilege())
 	cont := &admin.UserToken{}
@@ -274,6 +461,8 @@ func MyBind(rg *gin.RouterGroup) {
 		rg.POST("/my/address_book/delete", cont.Delete)
 		rg.POST("/my/address_book/batchCreateFromPeers", cont.BatchCreateFromPeers)
 		rg.POST("/my/address_book/batchUpdateTags", cont.BatchUpdateTags)
+		rg.GET("/my/address_book/export", cont.Export)
+		rg.POST("/my/address_book/import", cont.Import)
 	}
 
 	{
@@ -283,10 +472,22 @@ func MyBind(rg *gin.RouterGroup) {
 		rg.POST("/my/tag/update", cont.Update)
 		rg.POST("/my/tag/delete", cont.Delete)
 	}
+	{
+		cont := &my.GlobalTag{}
+		rg.GET("/my/global_tag/list", cont.List)
+	}
+	{
+		cont := &my.PeerShare{}
+		rg.GET("/my/peer_share/list", cont.List)
+		rg.POST("/my/peer_share/create", cont.Create)
+		rg.POST("/my/peer_share/update", cont.Update)
+		rg.POST("/my/peer_share/delete", cont.Delete)
+	}
 
 	{
 		cont := &my.AddressBookCollection{}
 		rg.GET("/my/address_book_collection/list", cont.List)
+		rg.GET("/my/address_book_collection/preview", cont.Preview)
 		rg.POST("/my/address_book_collection/create", cont.Create)
 		rg.POST("/my/address_book_collection/update", cont.Update)
 		rg.POST("/my/address_book_collection/delete", cont.Delete)
@@ -299,6 +500,24 @@ func MyBind(rg *gin.RouterGroup) {
 		rg.POST("/my/address_book_collection_rule/update", cont.Update)
 		rg.POST("/my/address_book_collection_rule/delete", cont.Delete)
 	}
+	{
+		cont := &my.AddressBookFolder{}
+		rg.GET("/my/address_book_folder/list", cont.List)
+		rg.POST("/my/address_book_folder/create", cont.Create)
+		rg.POST("/my/address_book_folder/update", cont.Update)
+		rg.POST("/my/address_book_folder/move", cont.Move)
+		rg.POST("/my/address_book_folder/delete", cont.Delete)
+	}
+	{
+		cont := &my.AddressBookHistory{}
+		rg.GET("/my/address_book_history/list", cont.List)
+		rg.POST("/my/address_book_history/restore", cont.Restore)
+	}
+	{
+		cont := &my.Notification{}
+		rg.GET("/my/notification/list", cont.List)
+		rg.POST("/my/notification/read", cont.Read)
+	}
 	{
 		cont := &my.Peer{}
 		rg.GET("/my/peer/list", cont.List)
@@ -323,3 +542,23 @@ func ShareRecordBind(rg *gin.RouterGroup) {
 	}
 
 }
+
+func PeerIdentityBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/peer_identity").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.PeerIdentity{}
+		aR.GET("/list", cont.List)
+		aR.GET("/history", cont.History)
+		aR.POST("/merge", cont.Merge)
+		aR.POST("/dismiss", cont.Dismiss)
+	}
+}
+
+func AddressBookHistoryBind(rg *gin.RouterGroup) {
+	aR := rg.Group("/address_book_history").Use(middleware.AdminPrivilege())
+	{
+		cont := &admin.AddressBookHistory{}
+		aR.GET("/list", cont.List)
+		aR.POST("/restore", cont.Restore)
+	}
+}
@@ -29,6 +29,8 @@ func ApiInit(g *gin.Engine) {
 		frg.GET("/version", i.Version)
 
 		frg.POST("/heartbeat", i.Heartbeat)
+		//确认心跳下发的操作
+		frg.POST("/heartbeat/ack", i.ActionAck)
 	}
 
 	{
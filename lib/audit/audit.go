@@ -30,6 +30,7 @@ const (
 	EventUserDeleted       EventType = "USER_DELETED"
 	EventUserDisabled      EventType = "USER_DISABLED"
 	EventUserEnabled       EventType = "USER_ENABLED"
+	EventOwnershipTransfer EventType = "OWNERSHIP_TRANSFERRED"
 
 	// Access control events
 	EventAccessDenied      EventType = "ACCESS_DENIED"
@@ -42,6 +43,9 @@ const (
 	EventGroupCreated      EventType = "GROUP_CREATED"
 	EventGroupUpdated      EventType = "GROUP_UPDATED"
 	EventGroupDeleted      EventType = "GROUP_DELETED"
+	EventRemoteAction      EventType = "REMOTE_ACTION"
+	EventPeerMerged        EventType = "PEER_MERGED"
+	EventShareRuleExpired  EventType = "SHARE_RULE_EXPIRED"
 
 	// Data access events
 	EventDataExported      EventType = "DATA_EXPORTED"
@@ -387,3 +391,135 @@ func LogBulkOperation(c *gin.Context, userID uint, operation string, count int)
 		},
 	})
 }
+
+// LogDataExported logs a bulk data export
+func LogDataExported(c *gin.Context, userID uint, resource, format string, count int) {
+	GetLogger().Log(&AuditEvent{
+		EventType: EventDataExported,
+		Severity:  SeverityInfo,
+		UserID:    userID,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Message:   "Data exported: " + resource,
+		Success:   true,
+		Details: map[string]interface{}{
+			"resource": resource,
+			"format":   format,
+			"count":    count,
+		},
+	})
+}
+
+// LogClientVersionAlert logs a login from a client that does not meet the version policy
+func LogClientVersionAlert(c *gin.Context, userID uint, peerID, platform, version, compliance string) {
+	GetLogger().Log(&AuditEvent{
+		EventType: EventSecurityAlert,
+		Severity:  SeverityWarning,
+		UserID:    userID,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Message:   "Client version out of policy: " + compliance,
+		Success:   true,
+		Details: map[string]interface{}{
+			"peer_id":    peerID,
+			"platform":   platform,
+			"version":    version,
+			"compliance": compliance,
+		},
+	})
+}
+
+// LogRemoteAction logs a remote action queued, cancelled, delivered, completed or expired for a peer.
+// c is nil when the state change comes from the server itself (heartbeat delivery, expiry job)
+func LogRemoteAction(c *gin.Context, userID uint, peerID, action, state string) {
+	event := &AuditEvent{
+		EventType: EventRemoteAction,
+		Severity:  SeverityInfo,
+		UserID:    userID,
+		ClientIP:  "system",
+		Message:   "Remote action " + action + " " + state + " for peer " + peerID,
+		Success:   state != "failed",
+		Details: map[string]interface{}{
+			"peer_id": peerID,
+			"action":  action,
+			"state":   state,
+		},
+	}
+	if c != nil {
+		event.ClientIP = c.ClientIP()
+		event.UserAgent = c.Request.UserAgent()
+		event.Method = c.Request.Method
+		event.Path = c.Request.URL.Path
+	}
+	GetLogger().Log(event)
+}
+
+// LogPeerMerged logs the merge of a peer whose RustDesk ID changed into its new record.
+// c is nil for automatic merges done by the server
+func LogPeerMerged(c *gin.Context, userID uint, oldPeerID, newPeerID string) {
+	event := &AuditEvent{
+		EventType: EventPeerMerged,
+		Severity:  SeverityInfo,
+		UserID:    userID,
+		ClientIP:  "system",
+		Message:   "Peer " + oldPeerID + " merged into " + newPeerID,
+		Success:   true,
+		Details: map[string]interface{}{
+			"old_peer_id": oldPeerID,
+			"new_peer_id": newPeerID,
+			"auto":        c == nil,
+		},
+	}
+	if c != nil {
+		event.ClientIP = c.ClientIP()
+		event.UserAgent = c.Request.UserAgent()
+		event.Method = c.Request.Method
+		event.Path = c.Request.URL.Path
+	}
+	GetLogger().Log(event)
+}
+
+// LogOwnershipTransferred logs the transfer of peers and address books from one user to another
+func LogOwnershipTransferred(c *gin.Context, userID, fromUserID, toUserID uint, peers, collections int64) {
+	GetLogger().Log(&AuditEvent{
+		EventType: EventOwnershipTransfer,
+		Severity:  SeverityWarning,
+		UserID:    userID,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Message:   "Ownership transferred between users",
+		Success:   true,
+		Details: map[string]interface{}{
+			"from_user_id": fromUserID,
+			"to_user_id":   toUserID,
+			"peers":        peers,
+			"collections":  collections,
+		},
+	})
+}
+
+// LogShareRuleExpired logs an address book sharing rule disabled or deleted by the expiry job.
+// It runs in the background, so there is no request to take the client details from
+func LogShareRuleExpired(ruleID, ownerID, collectionID uint, ruleType int, toID uint, action string) {
+	GetLogger().Log(&AuditEvent{
+		EventType: EventShareRuleExpired,
+		Severity:  SeverityInfo,
+		UserID:    ownerID,
+		ClientIP:  "system",
+		Message:   "Address book sharing rule expired and " + action,
+		Success:   true,
+		Details: map[string]interface{}{
+			"rule_id":       ruleID,
+			"collection_id": collectionID,
+			"type":          ruleType,
+			"to_id":         toID,
+			"action":        action,
+		},
+	})
+}
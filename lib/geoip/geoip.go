@@ -0,0 +1,168 @@
+// Package geoip 使用本地 MaxMind/DB-IP mmdb 文件查询 IP 的国家、城市和 ASN, 不访问网络.
+// 文件整体读入内存而不是 mmap, 这样可以直接覆盖磁盘上的文件再热加载.
+package geoip
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// Result 查询结果, 未命中的字段为空
+type Result struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2
+	City    string `json:"city"`
+	Asn     uint   `json:"asn"`
+	AsnOrg  string `json:"asn_org"`
+}
+
+// cityRecord 兼容 GeoLite2-City/Country 和 DB-IP city/country lite
+type cityRecord struct {
+	Country struct {
+		IsoCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+}
+
+// asnRecord 兼容 GeoLite2-ASN 和 DB-IP asn lite
+type asnRecord struct {
+	Number uint   `maxminddb:"autonomous_system_number"`
+	Org    string `maxminddb:"autonomous_system_organization"`
+}
+
+// FileStatus 数据库文件状态
+type FileStatus struct {
+	Path         string `json:"path"`
+	Loaded       bool   `json:"loaded"`
+	DatabaseType string `json:"database_type"`
+	BuildTime    int64  `json:"build_time"`
+	ModTime      int64  `json:"mod_time"`
+	Error        string `json:"error"`
+}
+
+type dbFile struct {
+	path    string
+	reader  *maxminddb.Reader
+	modTime time.Time
+	err     error
+}
+
+// load 文件修改时间变化时重新读取, 读取失败时保留旧的数据
+func (f *dbFile) load() (bool, error) {
+	if f.path == "" {
+		return false, nil
+	}
+	st, err := os.Stat(f.path)
+	if err != nil {
+		f.err = err
+		return false, err
+	}
+	if f.reader != nil && st.ModTime().Equal(f.modTime) {
+		return false, nil
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		f.err = err
+		return false, err
+	}
+	r, err := maxminddb.FromBytes(b)
+	if err != nil {
+		f.err = err
+		return false, err
+	}
+	f.reader, f.modTime, f.err = r, st.ModTime(), nil
+	return true, nil
+}
+
+func (f *dbFile) status() *FileStatus {
+	s := &FileStatus{Path: f.path, Loaded: f.reader != nil}
+	if f.reader != nil {
+		s.DatabaseType = f.reader.Metadata.DatabaseType
+		s.BuildTime = int64(f.reader.Metadata.BuildEpoch)
+		s.ModTime = f.modTime.Unix()
+	}
+	if f.err != nil {
+		s.Error = f.err.Error()
+	}
+	return s
+}
+
+// Locator 可以热加载的查询器, 并发安全
+type Locator struct {
+	mu   sync.RWMutex
+	city *dbFile
+	asn  *dbFile
+}
+
+// New cityPath 为城市或国家库, asnPath 为 ASN 库, 都可以为空
+func New(cityPath, asnPath string) *Locator {
+	return &Locator{city: &dbFile{path: cityPath}, asn: &dbFile{path: asnPath}}
+}
+
+// Reload 检查文件是否有变化并重新加载, 返回是否有文件被重新加载
+func (l *Locator) Reload() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	c1, err1 := l.city.load()
+	c2, err2 := l.asn.load()
+	if err1 != nil {
+		return c1 || c2, err1
+	}
+	return c1 || c2, err2
+}
+
+// Ready 至少有一个数据库可用
+func (l *Locator) Ready() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.city.reader != nil || l.asn.reader != nil
+}
+
+// Lookup 查询 IP, 无法解析或未命中时返回 nil
+func (l *Locator) Lookup(ip string) *Result {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return nil
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	res := &Result{}
+	found := false
+	if l.city.reader != nil {
+		rec := &cityRecord{}
+		if err := l.city.reader.Lookup(parsed, rec); err == nil && rec.Country.IsoCode != "" {
+			res.Country = rec.Country.IsoCode
+			res.City = rec.City.Names["en"]
+			found = true
+		}
+	}
+	if l.asn.reader != nil {
+		rec := &asnRecord{}
+		if err := l.asn.reader.Lookup(parsed, rec); err == nil && rec.Number > 0 {
+			res.Asn = rec.Number
+			res.AsnOrg = rec.Org
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return res
+}
+
+func (l *Locator) Status() []*FileStatus {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var res []*FileStatus
+	for _, f := range []*dbFile{l.city, l.asn} {
+		if f.path != "" {
+			res = append(res, f.status())
+		}
+	}
+	return res
+}
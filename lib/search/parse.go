@@ -0,0 +1,269 @@
+package search
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type NodeKind int
+
+const (
+	NodeTerm NodeKind = iota
+	NodeAnd
+	NodeOr
+	NodeNot
+)
+
+// Node 语法树, Field 为空的 NodeTerm 表示全文词
+type Node struct {
+	Kind     NodeKind
+	Children []*Node
+	Field    string
+	Op       string
+	Value    string
+}
+
+type tokenKind int
+
+const (
+	tokWord tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	// text 去掉引号后的内容, quoteAt 为第一个引号在 text 中的位置, 没有引号时为 -1
+	text    string
+	quoteAt int
+	neg     bool // -field:value 简写
+}
+
+// lex 切分词, 支持 "..." 引号和 \" 转义; AND/OR/NOT 只在大写且不带引号时作为关键字
+func lex(q string) ([]token, error) {
+	var tokens []token
+	rs := []rune(q)
+	for i := 0; i < len(rs); {
+		ch := rs[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r':
+			i++
+			continue
+		case ch == '(':
+			tokens = append(tokens, token{kind: tokLParen})
+			i++
+			continue
+		case ch == ')':
+			tokens = append(tokens, token{kind: tokRParen})
+			i++
+			continue
+		}
+		var sb strings.Builder
+		t := token{kind: tokWord, quoteAt: -1}
+		if ch == '-' && i+1 < len(rs) && rs[i+1] != ' ' {
+			t.neg = true
+			i++
+		}
+		for i < len(rs) {
+			ch = rs[i]
+			if ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '(' || ch == ')' {
+				break
+			}
+			if ch != '"' {
+				sb.WriteRune(ch)
+				i++
+				continue
+			}
+			if t.quoteAt < 0 {
+				t.quoteAt = len(sb.String())
+			}
+			i++
+			closed := false
+			for i < len(rs) {
+				if rs[i] == '\\' && i+1 < len(rs) {
+					sb.WriteRune(rs[i+1])
+					i += 2
+					continue
+				}
+				if rs[i] == '"' {
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(rs[i])
+				i++
+			}
+			if !closed {
+				return nil, errors.New("unterminated quote")
+			}
+		}
+		t.text = sb.String()
+		if t.quoteAt < 0 && !t.neg {
+			switch t.text {
+			case "AND":
+				t.kind = tokAnd
+			case "OR":
+				t.kind = tokOr
+			case "NOT":
+				t.kind = tokNot
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+var termOps = []string{"!=", "<=", ">=", ":", "=", "<", ">"}
+
+// splitTerm 拆分 field op value, 字段名只能由小写字母、数字和下划线组成, 且在引号之前
+func splitTerm(t token) *Node {
+	end := 0
+	for end < len(t.text) {
+		c := t.text[end]
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' {
+			end++
+			continue
+		}
+		break
+	}
+	if end > 0 && (t.quoteAt < 0 || end < t.quoteAt) {
+		rest := t.text[end:]
+		for _, op := range termOps {
+			if strings.HasPrefix(rest, op) && (t.quoteAt < 0 || end+len(op) <= t.quoteAt) {
+				return &Node{Kind: NodeTerm, Field: t.text[:end], Op: op, Value: rest[len(op):]}
+			}
+		}
+	}
+	return &Node{Kind: NodeTerm, Value: t.text}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	terms  int
+}
+
+// Parse 解析表达式, 相邻的词默认为 AND, 优先级 NOT > AND > OR
+func Parse(q string) (*Node, error) {
+	if len(q) > MaxQueryLength {
+		return nil, fmt.Errorf("query longer than %d characters", MaxQueryLength)
+	}
+	tokens, err := lex(q)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("empty query")
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, errors.New("unexpected )")
+	}
+	return n, nil
+}
+
+func (p *parser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *parser) parseOr(depth int) (*Node, error) {
+	n, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	children := []*Node{n}
+	for t := p.peek(); t != nil && t.kind == tokOr; t = p.peek() {
+		p.pos++
+		n, err = p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &Node{Kind: NodeOr, Children: children}, nil
+}
+
+func (p *parser) parseAnd(depth int) (*Node, error) {
+	var children []*Node
+	for {
+		t := p.peek()
+		if t == nil || t.kind == tokOr || t.kind == tokRParen {
+			break
+		}
+		if t.kind == tokAnd {
+			if len(children) == 0 {
+				return nil, errors.New("unexpected AND")
+			}
+			p.pos++
+			continue
+		}
+		n, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, n)
+	}
+	if len(children) == 0 {
+		return nil, errors.New("missing term")
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &Node{Kind: NodeAnd, Children: children}, nil
+}
+
+func (p *parser) parseUnary(depth int) (*Node, error) {
+	if depth > MaxDepth {
+		return nil, fmt.Errorf("query nested deeper than %d", MaxDepth)
+	}
+	t := p.peek()
+	switch t.kind {
+	case tokNot:
+		p.pos++
+		if p.peek() == nil {
+			return nil, errors.New("missing term after NOT")
+		}
+		n, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &Node{Kind: NodeNot, Children: []*Node{n}}, nil
+	case tokLParen:
+		p.pos++
+		n, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if t := p.peek(); t == nil || t.kind != tokRParen {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return n, nil
+	case tokWord:
+		p.pos++
+		p.terms++
+		if p.terms > MaxTerms {
+			return nil, fmt.Errorf("more than %d terms", MaxTerms)
+		}
+		n := splitTerm(*t)
+		if t.neg {
+			return &Node{Kind: NodeNot, Children: []*Node{n}}, nil
+		}
+		return n, nil
+	}
+	return nil, errors.New("unexpected token")
+}
@@ -0,0 +1,381 @@
+// Package search 解析管理后台列表的筛选表达式, 如 os:windows AND last_online<7d AND group:"Finance",
+// 并按资源的字段白名单转换为 gorm 条件. 列名只来自白名单, 用户输入全部作为参数绑定.
+package search
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MaxQueryLength = 1024
+	MaxTerms       = 32
+	MaxDepth       = 8
+	MaxSortFields  = 3
+)
+
+type FieldType int
+
+const (
+	TypeString FieldType = iota
+	TypeNumber
+	TypeBool
+	TypeUnix // unix 秒
+	TypeTime // datetime 列, 如 created_at
+)
+
+// Ref 按关联表字段匹配, 如 group:"Finance" => group_id IN (SELECT id FROM device_groups WHERE name LIKE ?)
+type Ref struct {
+	Model  interface{}
+	Column string
+}
+
+type Field struct {
+	Column   string
+	Type     FieldType
+	Sortable bool // 只允许按有索引的列排序
+	Ref      *Ref
+}
+
+// Resource 一个列表可以使用的字段
+type Resource struct {
+	Fields map[string]*Field
+	// Text 不带字段名的词在这些字段中模糊匹配
+	Text []string
+}
+
+var (
+	ErrEmptyValue      = errors.New("empty value")
+	ErrUnknownResource = errors.New("unknown resource")
+)
+
+// FieldInfo 字段说明, 供前端提示
+type FieldInfo struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"` // string/number/bool/time
+	Sortable bool   `json:"sortable"`
+}
+
+// Describe 按字段名排序列出可用字段
+func (r *Resource) Describe() []*FieldInfo {
+	names := make([]string, 0, len(r.Fields))
+	for name := range r.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	res := make([]*FieldInfo, 0, len(names))
+	for _, name := range names {
+		f := r.Fields[name]
+		t := "string"
+		switch {
+		case f.Ref != nil:
+		case f.Type == TypeNumber:
+			t = "number"
+		case f.Type == TypeBool:
+			t = "bool"
+		case f.Type == TypeUnix, f.Type == TypeTime:
+			t = "time"
+		}
+		res = append(res, &FieldInfo{Name: name, Type: t, Sortable: f.Sortable})
+	}
+	return res
+}
+
+// Compile 解析并校验表达式和排序, 返回可以直接用于 List 的 where 函数
+func (r *Resource) Compile(q, sort string, now time.Time) (func(tx *gorm.DB), error) {
+	var c *cond
+	if strings.TrimSpace(q) != "" {
+		n, err := Parse(q)
+		if err != nil {
+			return nil, err
+		}
+		if c, err = r.compile(n, now); err != nil {
+			return nil, err
+		}
+	}
+	orders, err := r.ParseSort(sort)
+	if err != nil {
+		return nil, err
+	}
+	return func(tx *gorm.DB) {
+		if c != nil {
+			var sb strings.Builder
+			var args []interface{}
+			c.render(tx, &sb, &args)
+			tx.Where(sb.String(), args...)
+		}
+		for _, o := range orders {
+			tx.Order(o)
+		}
+	}, nil
+}
+
+// ParseSort 解析排序, 如 "-last_online,id", 前缀 - 表示倒序
+func (r *Resource) ParseSort(sort string) ([]string, error) {
+	var orders []string
+	for _, s := range strings.Split(sort, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		dir := "asc"
+		if strings.HasPrefix(s, "-") {
+			dir = "desc"
+			s = s[1:]
+		} else if strings.HasPrefix(s, "+") {
+			s = s[1:]
+		}
+		f, ok := r.Fields[s]
+		if !ok || !f.Sortable {
+			return nil, fmt.Errorf("cannot sort by %s", s)
+		}
+		orders = append(orders, f.Column+" "+dir)
+	}
+	if len(orders) > MaxSortFields {
+		return nil, fmt.Errorf("at most %d sort fields", MaxSortFields)
+	}
+	return orders, nil
+}
+
+// cond 校验后的条件树, 关联表子查询在 render 时用当前连接生成
+type cond struct {
+	kind     string // and, or, not, expr, ref
+	children []*cond
+	sql      string
+	args     []interface{}
+	ref      *Ref
+	negate   bool
+}
+
+func (c *cond) render(tx *gorm.DB, sb *strings.Builder, args *[]interface{}) {
+	switch c.kind {
+	case "and", "or":
+		sb.WriteString("(")
+		for i, ch := range c.children {
+			if i > 0 {
+				sb.WriteString(" " + strings.ToUpper(c.kind) + " ")
+			}
+			ch.render(tx, sb, args)
+		}
+		sb.WriteString(")")
+	case "not":
+		sb.WriteString("NOT ")
+		c.children[0].render(tx, sb, args)
+	case "ref":
+		sub := tx.Session(&gorm.Session{NewDB: true}).Model(c.ref.Model).Select("id").Where(c.sql, c.args...)
+		sb.WriteString(c.children[0].sql)
+		if c.negate {
+			sb.WriteString(" NOT")
+		}
+		sb.WriteString(" IN (?)")
+		*args = append(*args, sub)
+	default:
+		sb.WriteString("(" + c.sql + ")")
+		*args = append(*args, c.args...)
+	}
+}
+
+func (r *Resource) compile(n *Node, now time.Time) (*cond, error) {
+	switch n.Kind {
+	case NodeAnd, NodeOr:
+		c := &cond{kind: "and"}
+		if n.Kind == NodeOr {
+			c.kind = "or"
+		}
+		for _, ch := range n.Children {
+			cc, err := r.compile(ch, now)
+			if err != nil {
+				return nil, err
+			}
+			c.children = append(c.children, cc)
+		}
+		return c, nil
+	case NodeNot:
+		cc, err := r.compile(n.Children[0], now)
+		if err != nil {
+			return nil, err
+		}
+		return &cond{kind: "not", children: []*cond{cc}}, nil
+	}
+	if n.Field == "" {
+		return r.compileText(n.Value)
+	}
+	f, ok := r.Fields[n.Field]
+	if !ok {
+		return nil, fmt.Errorf("unknown field %s", n.Field)
+	}
+	if n.Value == "" {
+		return nil, fmt.Errorf("%s: %w", n.Field, ErrEmptyValue)
+	}
+	return compileTerm(f, n, now)
+}
+
+func (r *Resource) compileText(v string) (*cond, error) {
+	if len(r.Text) == 0 {
+		return nil, errors.New("a field name is required")
+	}
+	parts := make([]string, 0, len(r.Text))
+	args := make([]interface{}, 0, len(r.Text))
+	pattern := likePattern(v, true)
+	for _, name := range r.Text {
+		parts = append(parts, r.Fields[name].Column+" LIKE ? ESCAPE '!'")
+		args = append(args, pattern)
+	}
+	return &cond{kind: "expr", sql: strings.Join(parts, " OR "), args: args}, nil
+}
+
+// likePattern 转义 LIKE 通配符, * 作为用户通配符; contains 为 true 且没有 * 时两边加 %
+func likePattern(v string, contains bool) string {
+	r := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+	v = r.Replace(v)
+	if strings.Contains(v, "*") {
+		return strings.ReplaceAll(v, "*", "%")
+	}
+	if contains {
+		return "%" + v + "%"
+	}
+	return v
+}
+
+func compileTerm(f *Field, n *Node, now time.Time) (*cond, error) {
+	if f.Ref != nil {
+		return compileRef(f, n)
+	}
+	switch f.Type {
+	case TypeString:
+		switch n.Op {
+		case ":":
+			return &cond{kind: "expr", sql: f.Column + " LIKE ? ESCAPE '!'", args: []interface{}{likePattern(n.Value, true)}}, nil
+		case "=":
+			return &cond{kind: "expr", sql: f.Column + " = ?", args: []interface{}{n.Value}}, nil
+		case "!=":
+			return &cond{kind: "expr", sql: f.Column + " <> ?", args: []interface{}{n.Value}}, nil
+		}
+	case TypeNumber:
+		v, err := strconv.ParseFloat(n.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a number", n.Field)
+		}
+		op := n.Op
+		if op == ":" {
+			op = "="
+		}
+		if op == "!=" {
+			op = "<>"
+		}
+		return &cond{kind: "expr", sql: f.Column + " " + op + " ?", args: []interface{}{v}}, nil
+	case TypeBool:
+		v, err := parseBool(n.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be true or false", n.Field)
+		}
+		switch n.Op {
+		case ":", "=":
+			return &cond{kind: "expr", sql: f.Column + " = ?", args: []interface{}{v}}, nil
+		case "!=":
+			return &cond{kind: "expr", sql: f.Column + " <> ?", args: []interface{}{v}}, nil
+		}
+	case TypeUnix, TypeTime:
+		return compileTime(f, n, now)
+	}
+	return nil, fmt.Errorf("operator %s is not supported for %s", n.Op, n.Field)
+}
+
+func compileRef(f *Field, n *Node) (*cond, error) {
+	c := &cond{kind: "ref", ref: f.Ref, children: []*cond{{sql: f.Column}}}
+	switch n.Op {
+	case ":":
+		c.sql, c.args = f.Ref.Column+" LIKE ? ESCAPE '!'", []interface{}{likePattern(n.Value, true)}
+	case "=":
+		c.sql, c.args = f.Ref.Column+" = ?", []interface{}{n.Value}
+	case "!=":
+		c.sql, c.args, c.negate = f.Ref.Column+" = ?", []interface{}{n.Value}, true
+	default:
+		return nil, fmt.Errorf("operator %s is not supported for %s", n.Op, n.Field)
+	}
+	return c, nil
+}
+
+func parseBool(v string) (bool, error) {
+	switch strings.ToLower(v) {
+	case "true", "yes", "1", "on":
+		return true, nil
+	case "false", "no", "0", "off":
+		return false, nil
+	}
+	return false, errors.New("invalid bool")
+}
+
+// ParseAge 解析相对时长, 支持 m/h/d/w 单位, 如 30m, 12h, 7d, 2w
+func ParseAge(v string) (time.Duration, bool) {
+	if len(v) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v[:len(v)-1])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	unit := map[byte]time.Duration{'m': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}[v[len(v)-1]]
+	if unit == 0 {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// compileTime 时间字段: 相对时长表示距今多久, last_online<7d 即最近7天内;
+// 日期(YYYY-MM-DD)表示当天, last_online<2026-01-01 即该日之前
+func compileTime(f *Field, n *Node, now time.Time) (*cond, error) {
+	value := func(t time.Time) interface{} {
+		if f.Type == TypeUnix {
+			return t.Unix()
+		}
+		return t
+	}
+	between := func(from, to time.Time) *cond {
+		return &cond{kind: "expr", sql: f.Column + " >= ? AND " + f.Column + " < ?", args: []interface{}{value(from), value(to)}}
+	}
+	cmp := func(op string, t time.Time) *cond {
+		return &cond{kind: "expr", sql: f.Column + " " + op + " ?", args: []interface{}{value(t)}}
+	}
+	if age, ok := ParseAge(n.Value); ok {
+		at := now.Add(-age)
+		// 时长越小时间越新, 比较方向相反
+		switch n.Op {
+		case ":", "<":
+			return cmp(">", at), nil
+		case "<=":
+			return cmp(">=", at), nil
+		case ">":
+			return cmp("<", at), nil
+		case ">=":
+			return cmp("<=", at), nil
+		}
+		return nil, fmt.Errorf("operator %s is not supported for %s", n.Op, n.Field)
+	}
+	day, err := time.ParseInLocation("2006-01-02", n.Value, now.Location())
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a duration (7d) or a date (YYYY-MM-DD)", n.Field)
+	}
+	next := day.AddDate(0, 0, 1)
+	switch n.Op {
+	case ":", "=":
+		return between(day, next), nil
+	case "!=":
+		return &cond{kind: "not", children: []*cond{between(day, next)}}, nil
+	case "<":
+		return cmp("<", day), nil
+	case "<=":
+		return cmp("<", next), nil
+	case ">":
+		return cmp(">=", next), nil
+	case ">=":
+		return cmp(">=", day), nil
+	}
+	return nil, fmt.Errorf("operator %s is not supported for %s", n.Op, n.Field)
+}
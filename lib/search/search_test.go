@@ -0,0 +1,123 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testResource = &Resource{
+	Fields: map[string]*Field{
+		"id":          {Column: "id", Type: TypeString, Sortable: true},
+		"os":          {Column: "os", Type: TypeString},
+		"hostname":    {Column: "hostname", Type: TypeString},
+		"online":      {Column: "online", Type: TypeBool, Sortable: true},
+		"last_online": {Column: "last_online_time", Type: TypeUnix, Sortable: true},
+		"group_id":    {Column: "group_id", Type: TypeNumber},
+	},
+	Text: []string{"id", "hostname"},
+}
+
+func compileSQL(t *testing.T, q string, now time.Time) (string, []interface{}) {
+	t.Helper()
+	n, err := Parse(q)
+	if err != nil {
+		t.Fatalf("%s: %v", q, err)
+	}
+	c, err := testResource.compile(n, now)
+	if err != nil {
+		t.Fatalf("%s: %v", q, err)
+	}
+	var sb strings.Builder
+	var args []interface{}
+	c.render(nil, &sb, &args)
+	return sb.String(), args
+}
+
+func TestParse(t *testing.T) {
+	n, err := Parse(`os:windows AND (online:true OR hostname:"pc (compta)") -id=123`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Kind != NodeAnd || len(n.Children) != 3 {
+		t.Fatalf("unexpected root %+v", n)
+	}
+	if c := n.Children[0]; c.Field != "os" || c.Op != ":" || c.Value != "windows" {
+		t.Errorf("first term %+v", c)
+	}
+	or := n.Children[1]
+	if or.Kind != NodeOr || or.Children[1].Value != "pc (compta)" {
+		t.Errorf("or node %+v", or)
+	}
+	if not := n.Children[2]; not.Kind != NodeNot || not.Children[0].Op != "=" {
+		t.Errorf("negated term %+v", not)
+	}
+
+	// 引号中的冒号不作为运算符
+	n, _ = Parse(`"a:b"`)
+	if n.Field != "" || n.Value != "a:b" {
+		t.Errorf("quoted text %+v", n)
+	}
+
+	for _, q := range []string{`os:"windows`, `(os:windows`, `os:windows)`, `AND os:windows`, `NOT`, `a OR`} {
+		if _, err := Parse(q); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	sql, args := compileSQL(t, `os:windows last_online<7d`, now)
+	if sql != "((os LIKE ? ESCAPE '!') AND (last_online_time > ?))" {
+		t.Errorf("sql = %s", sql)
+	}
+	if !reflect.DeepEqual(args, []interface{}{"%windows%", now.Add(-7 * 24 * time.Hour).Unix()}) {
+		t.Errorf("args = %v", args)
+	}
+
+	sql, args = compileSQL(t, `50%_off OR group_id>=3`, now)
+	if sql != "((id LIKE ? ESCAPE '!' OR hostname LIKE ? ESCAPE '!') OR (group_id >= ?))" {
+		t.Errorf("sql = %s", sql)
+	}
+	if args[0] != "%50!%!_off%" || args[2] != float64(3) {
+		t.Errorf("args = %v", args)
+	}
+
+	_, args = compileSQL(t, `hostname:pc-*`, now)
+	if args[0] != "pc-%" {
+		t.Errorf("wildcard args = %v", args)
+	}
+
+	sql, args = compileSQL(t, `last_online:2026-10-01`, now)
+	if sql != "(last_online_time >= ? AND last_online_time < ?)" || args[1].(int64)-args[0].(int64) != 86400 {
+		t.Errorf("date: %s %v", sql, args)
+	}
+
+	for _, q := range []string{`password:x`, `online:maybe`, `group_id:abc`, `os<windows`, `last_online:soon`, `os:`} {
+		n, err := Parse(q)
+		if err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+		if _, err := testResource.compile(n, now); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	orders, err := testResource.ParseSort("-last_online, id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(orders, []string{"last_online_time desc", "id asc"}) {
+		t.Errorf("orders = %v", orders)
+	}
+	if _, err := testResource.ParseSort("os"); err == nil {
+		t.Error("expected error for non sortable field")
+	}
+	if _, err := testResource.ParseSort("id; drop table peers"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+}
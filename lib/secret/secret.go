@@ -0,0 +1,310 @@
+// Package secret 字段级信封加密.
+// 每个值使用随机的数据密钥(DEK)以 AES-256-GCM 加密, DEK 再由主密钥(KEK)加密后与密文一起保存:
+//
+//	enc:v1:<主密钥id>:<base64(加密的DEK)>:<base64(密文)>
+//
+// 轮换主密钥时只需用新主密钥重新加密 DEK, 数据密文不变. 没有前缀的值视为明文, 读取时原样返回
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	Prefix  = "enc:v1:"
+	KeySize = 32
+)
+
+var (
+	ErrNoKey      = errors.New("secret: value is encrypted but no master key is configured")
+	ErrUnknownKey = errors.New("secret: value is encrypted with an unknown master key")
+	ErrMalformed  = errors.New("secret: malformed encrypted value")
+	ErrKeySize    = errors.New("secret: master key must be 32 bytes, base64 or hex encoded")
+)
+
+type Config struct {
+	MasterKey     string
+	MasterKeyFile string
+	OldKeys       []string
+}
+
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// Keyring 当前主密钥用于加密, 旧主密钥只用于解密
+type Keyring struct {
+	current *key
+	keys    map[string]*key
+}
+
+// ParseKey 解析 base64 或 hex 编码的 32 字节主密钥
+func ParseKey(s string) ([]byte, error) {
+	s = strings.TrimSpace(s)
+	if b, err := base64.StdEncoding.DecodeString(s); err == nil && len(b) == KeySize {
+		return b, nil
+	}
+	if b, err := hex.DecodeString(s); err == nil && len(b) == KeySize {
+		return b, nil
+	}
+	return nil, ErrKeySize
+}
+
+// GenerateKey 生成 base64 编码的随机主密钥
+func GenerateKey() string {
+	b := make([]byte, KeySize)
+	_, _ = rand.Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+func newKey(b []byte) (*key, error) {
+	if len(b) != KeySize {
+		return nil, ErrKeySize
+	}
+	aead, err := newAead(b)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(b)
+	return &key{id: hex.EncodeToString(sum[:4]), aead: aead}, nil
+}
+
+func newAead(b []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(b)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func NewKeyring(current []byte, old ...[]byte) (*Keyring, error) {
+	k, err := newKey(current)
+	if err != nil {
+		return nil, err
+	}
+	r := &Keyring{current: k, keys: map[string]*key{k.id: k}}
+	for _, o := range old {
+		ok, err := newKey(o)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := r.keys[ok.id]; !exists {
+			r.keys[ok.id] = ok
+		}
+	}
+	return r, nil
+}
+
+// KeyId 当前主密钥的id
+func (r *Keyring) KeyId() string {
+	return r.current.id
+}
+
+func seal(aead cipher.AEAD, plain []byte) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	return aead.Seal(nonce, nonce, plain, nil)
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	n := aead.NonceSize()
+	if len(data) < n {
+		return nil, ErrMalformed
+	}
+	return aead.Open(nil, data[:n], data[n:], nil)
+}
+
+// Encrypt 空字符串不加密
+func (r *Keyring) Encrypt(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	dek := make([]byte, KeySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	aead, err := newAead(dek)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.StdEncoding
+	return Prefix + r.current.id + ":" + enc.EncodeToString(seal(r.current.aead, dek)) + ":" + enc.EncodeToString(seal(aead, []byte(plain))), nil
+}
+
+type parts struct {
+	keyId string
+	dek   []byte
+	data  []byte
+}
+
+func split(v string) (*parts, error) {
+	f := strings.Split(strings.TrimPrefix(v, Prefix), ":")
+	if len(f) != 3 {
+		return nil, ErrMalformed
+	}
+	dek, err := base64.StdEncoding.DecodeString(f[1])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	data, err := base64.StdEncoding.DecodeString(f[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return &parts{keyId: f[0], dek: dek, data: data}, nil
+}
+
+func (r *Keyring) unwrap(p *parts) ([]byte, error) {
+	k, ok := r.keys[p.keyId]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	dek, err := open(k.aead, p.dek)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return dek, nil
+}
+
+// Decrypt 明文原样返回
+func (r *Keyring) Decrypt(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	p, err := split(v)
+	if err != nil {
+		return "", err
+	}
+	dek, err := r.unwrap(p)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAead(dek)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(aead, p.data)
+	if err != nil {
+		return "", ErrMalformed
+	}
+	return string(plain), nil
+}
+
+// Rewrap 用当前主密钥重新加密 DEK, 明文直接加密; 已使用当前主密钥的值原样返回
+func (r *Keyring) Rewrap(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return r.Encrypt(v)
+	}
+	p, err := split(v)
+	if err != nil {
+		return "", err
+	}
+	if p.keyId == r.current.id {
+		return v, nil
+	}
+	dek, err := r.unwrap(p)
+	if err != nil {
+		return "", err
+	}
+	enc := base64.StdEncoding
+	return Prefix + r.current.id + ":" + enc.EncodeToString(seal(r.current.aead, dek)) + ":" + enc.EncodeToString(p.data), nil
+}
+
+// NeedsRewrap 明文或使用旧主密钥加密的值
+func (r *Keyring) NeedsRewrap(v string) bool {
+	if v == "" {
+		return false
+	}
+	return !strings.HasPrefix(v, Prefix+r.current.id+":")
+}
+
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, Prefix)
+}
+
+var (
+	mu      sync.RWMutex
+	keyring *Keyring
+)
+
+// Init 从配置加载主密钥, 未配置主密钥时不加密
+func Init(c *Config) error {
+	s := c.MasterKey
+	if s == "" && c.MasterKeyFile != "" {
+		b, err := os.ReadFile(c.MasterKeyFile)
+		if err != nil {
+			return err
+		}
+		s = string(b)
+	}
+	if strings.TrimSpace(s) == "" {
+		SetKeyring(nil)
+		return nil
+	}
+	cur, err := ParseKey(s)
+	if err != nil {
+		return err
+	}
+	old := make([][]byte, 0, len(c.OldKeys))
+	for _, o := range c.OldKeys {
+		b, err := ParseKey(o)
+		if err != nil {
+			return err
+		}
+		old = append(old, b)
+	}
+	r, err := NewKeyring(cur, old...)
+	if err != nil {
+		return err
+	}
+	SetKeyring(r)
+	return nil
+}
+
+func SetKeyring(r *Keyring) {
+	mu.Lock()
+	keyring = r
+	mu.Unlock()
+}
+
+// Current 未配置主密钥时为 nil
+func Current() *Keyring {
+	mu.RLock()
+	defer mu.RUnlock()
+	return keyring
+}
+
+// Enabled 是否配置了主密钥
+func Enabled() bool {
+	return Current() != nil
+}
+
+// Encrypt 使用全局主密钥加密, 未配置主密钥时返回明文
+func Encrypt(plain string) (string, error) {
+	r := Current()
+	if r == nil {
+		return plain, nil
+	}
+	return r.Encrypt(plain)
+}
+
+// Decrypt 使用全局主密钥解密
+func Decrypt(v string) (string, error) {
+	if !IsEncrypted(v) {
+		return v, nil
+	}
+	r := Current()
+	if r == nil {
+		return "", ErrNoKey
+	}
+	return r.Decrypt(v)
+}
@@ -0,0 +1,98 @@
+package secret
+
+import (
+	"strings"
+	"testing"
+)
+
+func testKeyring(t *testing.T, old ...string) (*Keyring, string) {
+	t.Helper()
+	s := GenerateKey()
+	cur, err := ParseKey(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	olds := make([][]byte, 0, len(old))
+	for _, o := range old {
+		b, err := ParseKey(o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		olds = append(olds, b)
+	}
+	r, err := NewKeyring(cur, olds...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r, s
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	r, _ := testKeyring(t)
+	enc, err := r.Encrypt("p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) || strings.Contains(enc, "p@ss") {
+		t.Fatalf("not encrypted: %s", enc)
+	}
+	if enc2, _ := r.Encrypt("p@ss"); enc2 == enc {
+		t.Fatal("same ciphertext for two encryptions")
+	}
+	plain, err := r.Decrypt(enc)
+	if err != nil || plain != "p@ss" {
+		t.Fatalf("decrypt = %q, %v", plain, err)
+	}
+	if enc, _ := r.Encrypt(""); enc != "" {
+		t.Fatalf("empty value encrypted: %s", enc)
+	}
+	if plain, _ := r.Decrypt("legacy"); plain != "legacy" {
+		t.Fatalf("plaintext not passed through: %s", plain)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	old, oldKey := testKeyring(t)
+	enc, _ := old.Encrypt("secret")
+	r, _ := testKeyring(t, oldKey)
+	if !r.NeedsRewrap(enc) || !r.NeedsRewrap("plain") || r.NeedsRewrap("") {
+		t.Fatal("NeedsRewrap mismatch")
+	}
+	re, err := r.Rewrap(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.NeedsRewrap(re) {
+		t.Fatal("rewrapped value still needs rewrap")
+	}
+	// 数据密文不变, 只重新加密 DEK
+	if re[strings.LastIndex(re, ":"):] != enc[strings.LastIndex(enc, ":"):] {
+		t.Fatal("data ciphertext changed")
+	}
+	if plain, err := r.Decrypt(re); err != nil || plain != "secret" {
+		t.Fatalf("decrypt = %q, %v", plain, err)
+	}
+	other, _ := testKeyring(t)
+	if _, err := other.Decrypt(re); err != ErrUnknownKey {
+		t.Fatalf("err = %v, want ErrUnknownKey", err)
+	}
+}
+
+func TestParseKey(t *testing.T) {
+	if _, err := ParseKey(strings.Repeat("ab", 32)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseKey("short"); err != ErrKeySize {
+		t.Fatalf("err = %v, want ErrKeySize", err)
+	}
+}
+
+func TestGlobalWithoutKey(t *testing.T) {
+	SetKeyring(nil)
+	if v, _ := Encrypt("x"); v != "x" {
+		t.Fatalf("Encrypt without key = %q", v)
+	}
+	if _, err := Decrypt(Prefix + "a:b:c"); err != ErrNoKey {
+		t.Fatalf("err = %v, want ErrNoKey", err)
+	}
+}
@@ -0,0 +1,43 @@
+package secret
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm/schema"
+)
+
+// Serializer gorm 字段序列化, 用法: `gorm:"serializer:secret"`, 字段类型为 string.
+// 写入时加密, 读取时解密; 注意 map 更新不经过序列化, 需要自行调用 Encrypt
+type Serializer struct{}
+
+func init() {
+	schema.RegisterSerializer("secret", Serializer{})
+}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var v string
+	switch d := dbValue.(type) {
+	case nil:
+	case []byte:
+		v = string(d)
+	case string:
+		v = d
+	default:
+		return fmt.Errorf("secret: unsupported column value %T", dbValue)
+	}
+	plain, err := Decrypt(v)
+	if err != nil {
+		return err
+	}
+	return field.Set(ctx, dst, plain)
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	v, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("secret: unsupported field type %T", fieldValue)
+	}
+	return Encrypt(v)
+}
@@ -0,0 +1,48 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	AccessTargetTypeUser  = 1 // 个人
+	AccessTargetTypeGroup = 2 // 群组
+)
+
+const (
+	IpAccessActionAllow = 1 // 允许
+	IpAccessActionDeny  = 2 // 拒绝
+)
+
+// IpAccessRule 登录及token使用的IP/CIDR限制, 拒绝优先, 存在允许规则时只有匹配的IP可以访问
+type IpAccessRule struct {
+	IdModel
+	Type   int    `json:"type" gorm:"default:1;not null;index"` // 1: 个人 2: 群组
+	ToId   uint   `json:"to_id" gorm:"default:0;not null;index"`
+	Action int    `json:"action" gorm:"default:1;not null;"` // 1: 允许 2: 拒绝
+	Cidr   string `json:"cidr" gorm:"default:'';not null;"`
+	Remark string `json:"remark" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type IpAccessRuleList struct {
+	IpAccessRules []*IpAccessRule `json:"list"`
+	Pagination
+}
+
+// AccessSchedule 登录及token使用的时间窗口限制, 启用后只有在时间窗口内才能访问
+type AccessSchedule struct {
+	IdModel
+	Name         string                `json:"name" gorm:"default:'';not null;"`
+	Type         int                   `json:"type" gorm:"default:1;not null;index"` // 1: 个人 2: 群组
+	ToId         uint                  `json:"to_id" gorm:"default:0;not null;index"`
+	Timezone     string                `json:"timezone" gorm:"default:'';not null;"`                 // 为空时使用配置的默认时区
+	Windows      custom_types.AutoJson `json:"windows" gorm:"not null;" swaggertype:"array,object"`  // [{weekday,start,end}]
+	Holidays     custom_types.AutoJson `json:"holidays" gorm:"not null;" swaggertype:"array,string"` // 例外日期 YYYY-MM-DD, 全天禁止
+	RevokeTokens bool                  `json:"revoke_tokens" gorm:"default:0;not null;"`             // 时间窗口关闭时吊销已有token
+	Status       StatusCode            `json:"status" gorm:"default:1;not null;"`
+	TimeModel
+}
+
+type AccessScheduleList struct {
+	AccessSchedules []*AccessSchedule `json:"list"`
+	Pagination
+}
@@ -1,6 +1,9 @@
 package model
 
-import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+import (
+	_ "github.com/RobertLesgros/rustdesk-interface/v2/lib/secret" // 注册 secret 序列化
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+)
 
 // final String id;
 // String hash; // personal ab hash password
@@ -22,12 +25,12 @@ type AddressBook struct {
 	RowId            uint                   `gorm:"primaryKey" json:"row_id"`
 	Id               string                 `json:"id" gorm:"default:0;not null;index"`
 	Username         string                 `json:"username" gorm:"default:'';not null;"`
-	Password         string                 `json:"password" gorm:"default:'';not null;"`
+	Password         string                 `json:"password" gorm:"default:'';not null;serializer:secret"`
 	Hostname         string                 `json:"hostname" gorm:"default:'';not null;"`
 	Alias            string                 `json:"alias" gorm:"default:'';not null;"`
 	Platform         string                 `json:"platform" gorm:"default:'';not null;"`
 	Tags             custom_types.AutoJson  `json:"tags" gorm:"not null;" swaggertype:"array,string"`
-	Hash             string                 `json:"hash" gorm:"default:'';not null;"`
+	Hash             string                 `json:"hash" gorm:"default:'';not null;serializer:secret"`
 	UserId           uint                   `json:"user_id" gorm:"default:0;not null;index"`
 	ForceAlwaysRelay bool                   `json:"forceAlwaysRelay" gorm:"default:0;not null;"`
 	RdpPort          string                 `json:"rdpPort" gorm:"default:'';not null;"`
@@ -36,6 +39,7 @@ type AddressBook struct {
 	LoginName        string                 `json:"loginName" gorm:"default:'';not null;"`
 	SameServer       bool                   `json:"sameServer" gorm:"default:0;not null;"`
 	CollectionId     uint                   `json:"collection_id" gorm:"default:0;not null;index"`
+	FolderId         uint                   `json:"folder_id" gorm:"default:0;not null;index"` // 集合内的文件夹, 0 为根目录
 	Collection       *AddressBookCollection `json:"collection,omitempty"`
 	TimeModel
 }
@@ -49,8 +53,19 @@ type AddressBookCollection struct {
 	IdModel
 	UserId uint   `json:"user_id" gorm:"default:0;not null;index"`
 	Name   string `json:"name" gorm:"default:'';not null;" validate:"required"`
+	// Query 动态集合的设备筛选表达式, 语法同设备列表的 q 参数, 如 group:"Finance" AND os:windows.
+	// 不为空时集合内容在读取时按设备表计算, 客户端只读
+	Query string `json:"query" gorm:"type:text;"`
+	// TagPolicy 标签策略, 见 AbTagPolicyAny / AbTagPolicyGlobalOnly
+	TagPolicy int `json:"tag_policy" gorm:"default:0;not null;" validate:"gte=0,lte=1"`
 	TimeModel
 }
+
+// IsDynamic 是否为动态集合
+func (c *AddressBookCollection) IsDynamic() bool {
+	return c.Query != ""
+}
+
 type AddressBookCollectionList struct {
 	AddressBookCollection []*AddressBookCollection `json:"list"`
 	Pagination
@@ -62,6 +77,13 @@ type AddressBookCollectionRule struct {
 	Rule         int  `json:"rule" gorm:"default:0;not null;" validate:"required,gte=1,lte=3"` // 0: 无 1: 读 2: 读写  3: 完全控制
 	Type         int  `json:"type" gorm:"default:1;not null;" validate:"required,gte=1,lte=2"` // 1: 个人 2: 群组
 	ToId         uint `json:"to_id" gorm:"default:0;not null;" validate:"required,gt=0"`
+	FolderId     uint `json:"folder_id" gorm:"default:0;not null;index"` // 只共享该文件夹及其子文件夹, 0 为整个集合
+	// StartAt 生效时间, 0 为立即生效; EndAt 到期时间, 0 为永久有效. 到期后由定时任务停用或删除
+	StartAt    int64      `json:"start_at" gorm:"default:0;not null;" validate:"gte=0"`
+	EndAt      int64      `json:"end_at" gorm:"default:0;not null;index" validate:"gte=0"`
+	NotifiedAt int64      `json:"notified_at" gorm:"default:0;not null;"` // 到期提醒的发送时间
+	ExpiredAt  int64      `json:"expired_at" gorm:"default:0;not null;"`  // 定时任务到期停用的时间, 手动停用为 0
+	Status     StatusCode `json:"status" gorm:"default:1;not null;"`      // 1: 有效 2: 停用
 	TimeModel
 }
 type AddressBookCollectionRuleList struct {
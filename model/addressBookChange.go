@@ -0,0 +1,45 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	AbChangeEntityPeer = "peer"
+	AbChangeEntityTag  = "tag"
+
+	AbChangeActionCreate = "create"
+	AbChangeActionUpdate = "update"
+	AbChangeActionDelete = "delete"
+
+	AbChangeSourceClient = "client" // RustDesk 客户端同步
+	AbChangeSourceAdmin  = "admin"  // 管理后台
+	AbChangeSourceWeb    = "web"    // 用户自己的 web 页面
+	AbChangeSourceSystem = "system" // 系统自动处理, 如设备自动合并
+)
+
+// AddressBookChange 地址簿和标签的变更记录, 按地址簿(所有者 + 集合, 默认地址簿集合为 0)记录.
+// Before/After 为变更前后的快照, 新增时 Before 为 null, 删除时 After 为 null; 快照中的密码和 hash 按 secret 加密
+type AddressBookChange struct {
+	IdModel
+	UserId       uint                  `json:"user_id" gorm:"default:0;not null;index:idx_ab_change_book"`
+	CollectionId uint                  `json:"collection_id" gorm:"default:0;not null;index:idx_ab_change_book"`
+	Entity       string                `json:"entity" gorm:"default:'';not null;"`
+	EntityKey    string                `json:"entity_key" gorm:"default:'';not null;index"` // 设备 id 或标签 id
+	Action       string                `json:"action" gorm:"default:'';not null;"`
+	ActorId      uint                  `json:"actor_id" gorm:"default:0;not null;"`
+	Source       string                `json:"source" gorm:"default:'';not null;"`
+	Before       custom_types.AutoJson `json:"before" gorm:"type:text" swaggertype:"object"`
+	After        custom_types.AutoJson `json:"after" gorm:"type:text" swaggertype:"object"`
+	ChangedAt    int64                 `json:"changed_at" gorm:"default:0;not null;index"`
+	TimeModel
+}
+
+type AddressBookChangeList struct {
+	AddressBookChanges []*AddressBookChange `json:"list"`
+	Pagination
+}
+
+// AddressBookRestoreResult 恢复到某个时间点的结果
+type AddressBookRestoreResult struct {
+	Peers int `json:"peers"`
+	Tags  int `json:"tags"`
+}
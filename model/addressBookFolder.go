@@ -0,0 +1,17 @@
+package model
+
+// AddressBookFolder 集合内的文件夹, ParentId 为 0 表示在集合根目录.
+// 共享规则可以设置到文件夹, 对其下所有子文件夹生效
+type AddressBookFolder struct {
+	IdModel
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;index"`
+	CollectionId uint   `json:"collection_id" gorm:"default:0;not null;index" validate:"required,gt=0"`
+	ParentId     uint   `json:"parent_id" gorm:"default:0;not null;index"`
+	Name         string `json:"name" gorm:"default:'';not null;" validate:"required,max=100,excludes=/"`
+	Path         string `json:"path" gorm:"-"` // 完整路径, 如 "Clients/Paris", 查询时填充
+	TimeModel
+}
+
+type AddressBookFolderList struct {
+	AddressBookFolders []*AddressBookFolder `json:"list"`
+}
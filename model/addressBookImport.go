@@ -0,0 +1,21 @@
+package model
+
+const (
+	AbImportStrategySkip      = "skip"      // 已存在的设备保持不变
+	AbImportStrategyOverwrite = "overwrite" // 用导入的内容覆盖已存在的设备
+	AbImportStrategyDuplicate = "duplicate" // 已存在时仍然新增一条
+)
+
+// AbImportReport 地址簿导入结果, DryRun 时只校验不写入
+type AbImportReport struct {
+	DryRun      bool                  `json:"dry_run"`
+	Strategy    string                `json:"strategy"`
+	Total       int                   `json:"total"`
+	Created     int                   `json:"created"`
+	Updated     int                   `json:"updated"`
+	Skipped     int                   `json:"skipped"`
+	Failed      int                   `json:"failed"`
+	TagsCreated int                   `json:"tags_created"`
+	TagsUpdated int                   `json:"tags_updated"`
+	Errors      []*PeerImportRowError `json:"errors"`
+}
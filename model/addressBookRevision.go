@@ -0,0 +1,10 @@
+package model
+
+// AddressBookRevision 地址簿(所有者 + 集合)的版本号, 每次条目或标签变更时递增, 用于客户端同步时的乐观并发控制
+type AddressBookRevision struct {
+	IdModel
+	UserId       uint   `json:"user_id" gorm:"default:0;not null;uniqueIndex:idx_ab_revision_book"`
+	CollectionId uint   `json:"collection_id" gorm:"default:0;not null;uniqueIndex:idx_ab_revision_book"`
+	Revision     uint64 `json:"revision" gorm:"default:0;not null"`
+	TimeModel
+}
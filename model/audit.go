@@ -18,6 +18,7 @@ type AuditConn struct {
 	Uuid      string `json:"uuid" gorm:"default:'';not null;"`
 	CloseTime int64  `json:"close_time" gorm:"default:0;not null;"`
 	TimeModel
+	GeoInfo `gorm:"embedded;embeddedPrefix:geo_"`
 }
 
 type AuditConnList struct {
@@ -0,0 +1,35 @@
+package model
+
+const (
+	VersionComplianceOk          = "ok"          // 不低于推荐版本
+	VersionComplianceOutdated    = "outdated"    // 低于推荐版本, 但不低于最低版本
+	VersionComplianceUnsupported = "unsupported" // 低于最低版本
+	VersionComplianceUnknown     = "unknown"     // 无法识别平台/版本, 或该平台未配置策略
+)
+
+// ClientVersionPolicy 每个平台的客户端版本要求
+type ClientVersionPolicy struct {
+	IdModel
+	Platform           string `json:"platform" gorm:"default:'';not null;uniqueIndex"` // windows/macos/linux/android/ios
+	MinVersion         string `json:"min_version" gorm:"default:'';not null;"`
+	RecommendedVersion string `json:"recommended_version" gorm:"default:'';not null;"`
+	Remark             string `json:"remark" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+// ClientVersionCount 某个版本的设备数量
+type ClientVersionCount struct {
+	Version    string `json:"version"`
+	Count      int64  `json:"count"`
+	Compliance string `json:"compliance"`
+}
+
+// ClientVersionPlatformReport 单个平台的版本分布
+type ClientVersionPlatformReport struct {
+	Platform    string                `json:"platform"`
+	Policy      *ClientVersionPolicy  `json:"policy"`
+	Total       int64                 `json:"total"`
+	Outdated    int64                 `json:"outdated"`
+	Unsupported int64                 `json:"unsupported"`
+	Versions    []*ClientVersionCount `json:"versions"`
+}
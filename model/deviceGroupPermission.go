@@ -0,0 +1,21 @@
+package model
+
+const (
+	DeviceGroupPermissionRead    = 1 // 可查看
+	DeviceGroupPermissionConnect = 2 // 可查看和连接
+)
+
+// DeviceGroupPermission 授予用户或群组访问设备组的权限
+type DeviceGroupPermission struct {
+	IdModel
+	DeviceGroupId uint `json:"device_group_id" gorm:"default:0;not null;index"`
+	Type          int  `json:"type" gorm:"default:1;not null;index"` // 1: 个人 2: 群组
+	ToId          uint `json:"to_id" gorm:"default:0;not null;index"`
+	Permission    int  `json:"permission" gorm:"default:1;not null;"` // 1: 查看 2: 连接
+	TimeModel
+}
+
+type DeviceGroupPermissionList struct {
+	DeviceGroupPermissions []*DeviceGroupPermission `json:"list"`
+	Pagination
+}
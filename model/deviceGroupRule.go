@@ -0,0 +1,21 @@
+package model
+
+// DeviceGroupRule 设备组自动分配规则, 按 Sort 升序匹配, 第一条匹配的规则生效; 为空的条件不参与匹配
+type DeviceGroupRule struct {
+	IdModel
+	Name          string     `json:"name" gorm:"default:'';not null;"`
+	Sort          int        `json:"sort" gorm:"default:0;not null;index"`
+	DeviceGroupId uint       `json:"device_group_id" gorm:"default:0;not null;index"` // 分配到的设备组
+	HostnameRegex string     `json:"hostname_regex" gorm:"default:'';not null;"`
+	Os            string     `json:"os" gorm:"default:'';not null;"`           // 包含, 不区分大小写
+	Version       string     `json:"version" gorm:"default:'';not null;"`      // 前缀匹配, 如 1.3
+	IpCidr        string     `json:"ip_cidr" gorm:"default:'';not null;"`      // 匹配 last_online_ip
+	UserGroupId   uint       `json:"user_group_id" gorm:"default:0;not null;"` // 设备所属用户的群组
+	Status        StatusCode `json:"status" gorm:"default:1;not null;"`
+	TimeModel
+}
+
+type DeviceGroupRuleList struct {
+	DeviceGroupRules []*DeviceGroupRule `json:"list"`
+	Pagination
+}
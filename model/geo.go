@@ -0,0 +1,9 @@
+package model
+
+// GeoInfo 由本地 GeoIP 库根据 IP 填充, 以 geo_ 前缀嵌入到记录中
+type GeoInfo struct {
+	Country string `json:"geo_country" gorm:"default:'';not null;index"` // ISO 国家代码
+	City    string `json:"geo_city" gorm:"default:'';not null;"`
+	Asn     uint   `json:"geo_asn" gorm:"default:0;not null;"`
+	AsnOrg  string `json:"geo_asn_org" gorm:"default:'';not null;"`
+}
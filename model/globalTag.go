@@ -0,0 +1,27 @@
+package model
+
+// GlobalTag 管理员定义的全局标签, 在所有集合中可用, 颜色统一, 同名的集合标签以全局标签的颜色为准
+type GlobalTag struct {
+	IdModel
+	Name   string `json:"name" gorm:"default:'';not null;uniqueIndex"` // 创建后不能修改, 设备上保存的是标签名
+	Color  uint   `json:"color" gorm:"default:0;not null;"`            // 同 Tag.Color
+	Remark string `json:"remark" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type GlobalTagList struct {
+	GlobalTags []*GlobalTag `json:"list"`
+	Pagination
+}
+
+// 集合的标签策略
+const (
+	AbTagPolicyAny        = 0 // 可以使用任意标签
+	AbTagPolicyGlobalOnly = 1 // 只能使用全局标签
+)
+
+// GlobalTagMergeReport 合并结果
+type GlobalTagMergeReport struct {
+	TagsMerged   int `json:"tags_merged"`
+	PeersUpdated int `json:"peers_updated"`
+}
@@ -12,6 +12,7 @@ type LoginLog struct {
 	UserTokenId uint   `json:"user_token_id" gorm:"default:0;not null;"`
 	IsDeleted   uint   `json:"is_deleted" gorm:"default:0;not null;"`
 	TimeModel
+	GeoInfo `gorm:"embedded;embeddedPrefix:geo_"`
 }
 
 const (
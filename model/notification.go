@@ -0,0 +1,25 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	NotificationTypeAbRuleExpiring = "ab_rule_expiring" // 地址簿共享规则即将到期
+)
+
+// Notification 站内通知, Data 为前端按 Type 展示所需的参数
+type Notification struct {
+	IdModel
+	UserId  uint                  `json:"user_id" gorm:"default:0;not null;index"`
+	Type    string                `json:"type" gorm:"default:'';not null;"`
+	Title   string                `json:"title" gorm:"default:'';not null;"`
+	Content string                `json:"content" gorm:"type:text"`
+	Data    custom_types.AutoJson `json:"data" gorm:"type:text" swaggertype:"object"`
+	ReadAt  int64                 `json:"read_at" gorm:"default:0;not null;"`
+	TimeModel
+}
+
+type NotificationList struct {
+	Notifications []*Notification `json:"list"`
+	Unread        int64           `json:"unread"`
+	Pagination
+}
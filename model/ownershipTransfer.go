@@ -0,0 +1,22 @@
+package model
+
+// OwnershipTransferReport 用户之间转移所有权的结果, DryRun 时为预览, 不写入
+type OwnershipTransferReport struct {
+	DryRun     bool  `json:"dry_run"`
+	FromUserId uint  `json:"from_user_id"`
+	ToUserId   uint  `json:"to_user_id"`
+	Peers      int64 `json:"peers"`
+	// Collections 原用户的地址簿集合
+	Collections int64 `json:"collections"`
+	// PersonalCollection 原用户的默认地址簿转为目标用户的集合时的名称, 默认地址簿为空时不创建
+	PersonalCollection string `json:"personal_collection"`
+	AddressBooks       int64  `json:"address_books"`
+	Tags               int64  `json:"tags"`
+	ShareRecords       int64  `json:"share_records"`
+	// Rules 原用户集合上的共享规则
+	Rules int64 `json:"rules"`
+	// Grants 其他集合共享给原用户的规则, 转给目标用户
+	Grants int64 `json:"grants"`
+	// PeerShares 原用户共享出去的设备, 转给目标用户
+	PeerShares int64 `json:"peer_shares"`
+}
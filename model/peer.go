@@ -12,11 +12,22 @@ type Peer struct {
 	Version        string `json:"version"  gorm:"default:'';not null;"`
 	UserId         uint   `json:"user_id"  gorm:"default:0;not null;index"`
 	User           *User  `json:"user,omitempty"`
-	LastOnlineTime int64  `json:"last_online_time"  gorm:"default:0;not null;"`
+	LastOnlineTime int64  `json:"last_online_time"  gorm:"default:0;not null;index"`
 	LastOnlineIp   string `json:"last_online_ip"  gorm:"default:'';not null;"`
+	Online         bool   `json:"online"  gorm:"default:0;not null;index"`
+	OnlineSince    int64  `json:"online_since"  gorm:"default:0;not null;"`
+	Stale          bool   `json:"stale"  gorm:"default:0;not null;index"` // 长时间没有心跳, 由清理策略标记
 	GroupId        uint   `json:"group_id"  gorm:"default:0;not null;index"`
 	Alias          string `json:"alias" gorm:"default:'';not null;index"`
 	TimeModel
+	// GeoInfo last_online_ip 的地理信息
+	GeoInfo `gorm:"embedded;embeddedPrefix:geo_"`
+	// Attributes 自定义字段 name => value
+	Attributes map[string]string `json:"attributes,omitempty" gorm:"-"`
+	// VersionCompliance 客户端版本合规状态, 见 VersionCompliance* 常量
+	VersionCompliance string `json:"version_compliance,omitempty" gorm:"-"`
+	// SysinfoTimeline 系统信息变化记录, 只在详情中返回
+	SysinfoTimeline []*PeerSysinfoChange `json:"sysinfo_timeline,omitempty" gorm:"-"`
 }
 
 type PeerList struct {
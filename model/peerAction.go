@@ -0,0 +1,38 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	PeerActionSysinfo    = "sysinfo"    // 重新上传系统信息
+	PeerActionDisconnect = "disconnect" // 断开会话
+	PeerActionUnlink     = "unlink"     // 解除账号绑定
+)
+
+const (
+	PeerActionStatusPending   = 1 // 等待心跳
+	PeerActionStatusDelivered = 2 // 已通过心跳下发
+	PeerActionStatusAcked     = 3 // 已确认
+	PeerActionStatusExpired   = 4 // 已过期
+	PeerActionStatusCanceled  = 5 // 已取消
+	PeerActionStatusFailed    = 6 // 失败
+)
+
+// PeerAction 排队等待心跳下发的设备操作
+type PeerAction struct {
+	IdModel
+	PeerId      string                `json:"peer_id" gorm:"default:'';not null;index"`
+	Action      string                `json:"action" gorm:"default:'';not null;"`
+	Params      custom_types.AutoJson `json:"params" gorm:"not null;" swaggertype:"object"`
+	Status      int                   `json:"status" gorm:"default:1;not null;index"`
+	ExpireAt    int64                 `json:"expire_at" gorm:"default:0;not null;index"`
+	DeliveredAt int64                 `json:"delivered_at" gorm:"default:0;not null;"`
+	AckedAt     int64                 `json:"acked_at" gorm:"default:0;not null;"`
+	Result      string                `json:"result" gorm:"default:'';not null;"`
+	CreatedBy   uint                  `json:"created_by" gorm:"default:0;not null;"`
+	TimeModel
+}
+
+type PeerActionList struct {
+	PeerActions []*PeerAction `json:"list"`
+	Pagination
+}
@@ -0,0 +1,39 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	PeerAttributeTypeString = "string"
+	PeerAttributeTypeNumber = "number"
+	PeerAttributeTypeDate   = "date" // YYYY-MM-DD
+	PeerAttributeTypeEnum   = "enum"
+)
+
+// PeerAttributeDef 管理员定义的设备自定义字段
+type PeerAttributeDef struct {
+	IdModel
+	Name     string                `json:"name" gorm:"default:'';not null;uniqueIndex"` // 字段键, 如 asset_tag
+	Label    string                `json:"label" gorm:"default:'';not null;"`
+	Type     string                `json:"type" gorm:"default:'string';not null;"`
+	Options  custom_types.AutoJson `json:"options" gorm:"not null;" swaggertype:"array,string"` // enum 可选值
+	Required bool                  `json:"required" gorm:"default:0;not null;"`
+	Pattern  string                `json:"pattern" gorm:"default:'';not null;"` // string 类型的正则校验
+	Min      *float64              `json:"min"`                                 // number 类型的最小值
+	Max      *float64              `json:"max"`                                 // number 类型的最大值
+	Sort     int                   `json:"sort" gorm:"default:0;not null;"`
+	TimeModel
+}
+
+type PeerAttributeDefList struct {
+	PeerAttributeDefs []*PeerAttributeDef `json:"list"`
+	Pagination
+}
+
+// PeerAttributeValue 设备自定义字段的值
+type PeerAttributeValue struct {
+	IdModel
+	PeerId string `json:"peer_id" gorm:"default:'';not null;uniqueIndex:idx_peer_attribute_value"`
+	DefId  uint   `json:"def_id" gorm:"default:0;not null;uniqueIndex:idx_peer_attribute_value;index"`
+	Value  string `json:"value" gorm:"default:'';not null;"`
+	TimeModel
+}
@@ -0,0 +1,30 @@
+package model
+
+const (
+	PeerIdentityKindId   = "id"   // 同一 uuid 出现了新的 RustDesk id
+	PeerIdentityKindUuid = "uuid" // 同一 RustDesk id 上报了新的 uuid
+
+	PeerIdentityStatusPending   = 1 // 等待管理员确认合并
+	PeerIdentityStatusMerged    = 2 // 已合并(uuid 变化无需合并, 直接记录为已合并)
+	PeerIdentityStatusDismissed = 3 // 管理员忽略, 两条设备记录都保留
+)
+
+// PeerIdentityChange 设备 id/uuid 变化记录, 也是待合并队列
+type PeerIdentityChange struct {
+	IdModel
+	PeerRowId  uint   `json:"peer_row_id" gorm:"default:0;not null;index"` // 新的设备记录, 合并后保留
+	OldRowId   uint   `json:"old_row_id" gorm:"default:0;not null;index"`  // 旧的设备记录, 合并后删除; uuid 变化时与 PeerRowId 相同
+	Kind       string `json:"kind" gorm:"default:'';not null;"`
+	OldValue   string `json:"old_value" gorm:"default:'';not null;index"`
+	NewValue   string `json:"new_value" gorm:"default:'';not null;index"`
+	Status     int    `json:"status" gorm:"default:1;not null;index"`
+	Auto       bool   `json:"auto" gorm:"default:0;not null;"` // 是否自动合并
+	ResolvedBy uint   `json:"resolved_by" gorm:"default:0;not null;"`
+	ResolvedAt int64  `json:"resolved_at" gorm:"default:0;not null;"`
+	TimeModel
+}
+
+type PeerIdentityChangeList struct {
+	PeerIdentityChanges []*PeerIdentityChange `json:"list"`
+	Pagination
+}
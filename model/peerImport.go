@@ -0,0 +1,19 @@
+package model
+
+// PeerImportRowError 导入时单行的错误, Row 为数据行号(从1开始, 不含表头)
+type PeerImportRowError struct {
+	Row   int    `json:"row"`
+	Id    string `json:"id"`
+	Error string `json:"error"`
+}
+
+// PeerImportReport 导入结果, DryRun 时只校验不写入
+type PeerImportReport struct {
+	DryRun         bool                  `json:"dry_run"`
+	Total          int                   `json:"total"`
+	Created        int                   `json:"created"`
+	Updated        int                   `json:"updated"`
+	Failed         int                   `json:"failed"`
+	IgnoredColumns []string              `json:"ignored_columns"`
+	Errors         []*PeerImportRowError `json:"errors"`
+}
@@ -0,0 +1,16 @@
+package model
+
+// PeerPresenceEvent 设备上线/下线记录
+type PeerPresenceEvent struct {
+	IdModel
+	PeerId string `json:"peer_id" gorm:"default:'';not null;index"`
+	Online bool   `json:"online" gorm:"default:0;not null;"` // true: 上线 false: 下线
+	Ip     string `json:"ip" gorm:"default:'';not null;"`
+	Time   int64  `json:"time" gorm:"default:0;not null;index"`
+	TimeModel
+}
+
+type PeerPresenceEventList struct {
+	PeerPresenceEvents []*PeerPresenceEvent `json:"list"`
+	Pagination
+}
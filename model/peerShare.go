@@ -0,0 +1,25 @@
+package model
+
+// PeerShare 单个设备的共享: 共享地址簿条目(AbRowId) 或直接共享设备(PeerId), 对象为个人或群组.
+// 接收方在 /api/ab 的虚拟地址簿 "与我共享" 中看到这些设备
+type PeerShare struct {
+	IdModel
+	UserId  uint   `json:"user_id" gorm:"default:0;not null;index"`                         // 共享人
+	AbRowId uint   `json:"ab_row_id" gorm:"default:0;not null;index"`                       // 地址簿条目, 0 表示直接共享设备
+	PeerId  string `json:"peer_id" gorm:"default:'';not null;index"`                        // 设备 ID, 共享地址簿条目时为条目的设备 ID
+	Type    int    `json:"type" gorm:"default:1;not null;" validate:"required,gte=1,lte=2"` // 同 AddressBookCollectionRule.Type, 1: 个人 2: 群组
+	ToId    uint   `json:"to_id" gorm:"default:0;not null;index" validate:"required,gt=0"`
+	Level   int    `json:"level" gorm:"default:1;not null;" validate:"required,gte=1,lte=2"` // 见 PeerShareLevelRead / PeerShareLevelConnect
+	EndAt   int64  `json:"end_at" gorm:"default:0;not null;index" validate:"gte=0"`          // 到期时间, 0 为永久有效
+	TimeModel
+}
+
+type PeerShareList struct {
+	PeerShares []*PeerShare `json:"list"`
+	Pagination
+}
+
+const (
+	PeerShareLevelRead    = 1 // 只能看到设备
+	PeerShareLevelConnect = 2 // 同时带上地址簿中保存的密码, 可以直接连接
+)
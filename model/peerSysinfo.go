@@ -0,0 +1,27 @@
+package model
+
+// PeerSysinfo 设备系统信息快照, 只在信息变化时记录
+type PeerSysinfo struct {
+	IdModel
+	PeerId   string `json:"peer_id" gorm:"default:'';not null;index"`
+	Cpu      string `json:"cpu" gorm:"default:'';not null;"`
+	Hostname string `json:"hostname" gorm:"default:'';not null;"`
+	Memory   string `json:"memory" gorm:"default:'';not null;"`
+	Os       string `json:"os" gorm:"default:'';not null;"`
+	Username string `json:"username" gorm:"default:'';not null;"`
+	Version  string `json:"version" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type PeerSysinfoList struct {
+	PeerSysinfos []*PeerSysinfo `json:"list"`
+	Pagination
+}
+
+// PeerSysinfoChange 两个快照之间单个字段的变化
+type PeerSysinfoChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Time  int64  `json:"time"`
+}
@@ -0,0 +1,17 @@
+package model
+
+// SavedSearch 管理员保存的列表筛选表达式
+type SavedSearch struct {
+	IdModel
+	UserId   uint   `json:"user_id" gorm:"default:0;not null;index"`
+	Resource string `json:"resource" gorm:"default:'';not null;"` // peer/user/audit_conn/audit_file
+	Name     string `json:"name" gorm:"default:'';not null;"`
+	Query    string `json:"query" gorm:"type:text;"`
+	Sort     string `json:"sort" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type SavedSearchList struct {
+	SavedSearches []*SavedSearch `json:"list"`
+	Pagination
+}
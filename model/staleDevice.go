@@ -0,0 +1,35 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	StaleRunTriggerCron   = "cron"
+	StaleRunTriggerManual = "manual"
+)
+
+// StaleDevicePolicy 不活跃设备清理策略, 只有一条记录
+type StaleDevicePolicy struct {
+	IdModel
+	Enable          bool                  `json:"enable" gorm:"default:0;not null;"`
+	FlagDays        int                   `json:"flag_days" gorm:"default:90;not null;"`  // 超过N天没有心跳标记为不活跃
+	DeleteDays      int                   `json:"delete_days" gorm:"default:0;not null;"` // 超过M天没有心跳删除, 0 不删除
+	ExcludeGroupIds custom_types.AutoJson `json:"exclude_group_ids" gorm:"not null;" swaggertype:"array,integer"`
+	TimeModel
+}
+
+// StaleDeviceRun 每次清理的执行记录
+type StaleDeviceRun struct {
+	IdModel
+	Trigger    string                `json:"trigger" gorm:"default:'';not null;"` // cron, manual
+	Flagged    int                   `json:"flagged" gorm:"default:0;not null;"`
+	Unflagged  int                   `json:"unflagged" gorm:"default:0;not null;"`
+	Deleted    int                   `json:"deleted" gorm:"default:0;not null;"`
+	DeletedIds custom_types.AutoJson `json:"deleted_ids" gorm:"not null;" swaggertype:"array,string"`
+	Error      string                `json:"error" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type StaleDeviceRunList struct {
+	StaleDeviceRuns []*StaleDeviceRun `json:"list"`
+	Pagination
+}
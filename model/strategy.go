@@ -0,0 +1,55 @@
+package model
+
+import "github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+
+const (
+	StrategyTargetDeviceGroup = 1 // 设备组
+	StrategyTargetUser        = 2 // 用户
+	StrategyTargetPeer        = 3 // 单个设备, ToId 为 peer row_id
+)
+
+// Strategy 通过心跳下发给客户端的配置选项集合
+type Strategy struct {
+	IdModel
+	Name    string                `json:"name" gorm:"default:'';not null;"`
+	Options custom_types.AutoJson `json:"options" gorm:"not null;" swaggertype:"object"` // RustDesk 客户端选项 key => value
+	Version int64                 `json:"version" gorm:"default:0;not null;"`            // 每次修改选项时更新, 作为心跳中的 modified_at
+	Status  StatusCode            `json:"status" gorm:"default:1;not null;"`
+	Remark  string                `json:"remark" gorm:"default:'';not null;"`
+	TimeModel
+}
+
+type StrategyList struct {
+	Strategies []*Strategy `json:"list"`
+	Pagination
+}
+
+// StrategyAssignment 策略分配, 优先级: 单个设备 > 用户 > 设备组
+type StrategyAssignment struct {
+	IdModel
+	StrategyId uint `json:"strategy_id" gorm:"default:0;not null;index"`
+	Type       int  `json:"type" gorm:"default:1;not null;uniqueIndex:idx_strategy_assignment_target"`
+	ToId       uint `json:"to_id" gorm:"default:0;not null;uniqueIndex:idx_strategy_assignment_target"`
+	TimeModel
+}
+
+type StrategyAssignmentList struct {
+	StrategyAssignments []*StrategyAssignment `json:"list"`
+	Pagination
+}
+
+// PeerStrategyState 设备当前应使用的策略和客户端已应用的版本
+type PeerStrategyState struct {
+	IdModel
+	PeerId          string `json:"peer_id" gorm:"default:'';not null;uniqueIndex"`
+	StrategyId      uint   `json:"strategy_id" gorm:"default:0;not null;index"`
+	ExpectedVersion int64  `json:"expected_version" gorm:"default:0;not null;"`
+	AppliedVersion  int64  `json:"applied_version" gorm:"default:0;not null;"` // 客户端心跳上报的 modified_at
+	LastSeen        int64  `json:"last_seen" gorm:"default:0;not null;"`
+	TimeModel
+}
+
+type PeerStrategyStateList struct {
+	PeerStrategyStates []*PeerStrategyState `json:"list"`
+	Pagination
+}
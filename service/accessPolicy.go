@@ -0,0 +1,285 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+	"gorm.io/gorm"
+)
+
+type AccessPolicyService struct {
+}
+
+var ErrIpAccessDenied = errors.New("IpAccessDenied")
+
+// IpCheckResult IP检查结果, Rule为命中的规则(可能为空)
+type IpCheckResult struct {
+	Allowed bool                `json:"allowed"`
+	Reason  string              `json:"reason"`
+	Rule    *model.IpAccessRule `json:"rule,omitempty"`
+}
+
+func (s *AccessPolicyService) IpRuleInfoById(id uint) *model.IpAccessRule {
+	r := &model.IpAccessRule{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *AccessPolicyService) ListIpRules(page, pageSize uint, where func(tx *gorm.DB)) (res *model.IpAccessRuleList) {
+	res = &model.IpAccessRuleList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.IpAccessRule{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.IpAccessRules)
+	return
+}
+
+func (s *AccessPolicyService) CreateIpRule(r *model.IpAccessRule) error {
+	return DB.Create(r).Error
+}
+
+func (s *AccessPolicyService) UpdateIpRule(r *model.IpAccessRule) error {
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+func (s *AccessPolicyService) DeleteIpRule(r *model.IpAccessRule) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteIpRulesByTarget 删除用户或群组的全部IP规则
+func (s *AccessPolicyService) DeleteIpRulesByTarget(tx *gorm.DB, t int, toId uint) error {
+	return tx.Where("type = ? and to_id = ?", t, toId).Delete(&model.IpAccessRule{}).Error
+}
+
+// ValidateCidr 校验CIDR或IP格式
+func (s *AccessPolicyService) ValidateCidr(cidr string) error {
+	_, err := utils.ParseCidr(cidr)
+	return err
+}
+
+// ipRules 取用户及其群组的IP规则
+func (s *AccessPolicyService) ipRules(userId, groupId uint) (userRules, groupRules []*model.IpAccessRule) {
+	DB.Where("type = ? and to_id = ?", model.AccessTargetTypeUser, userId).Find(&userRules)
+	if groupId > 0 {
+		DB.Where("type = ? and to_id = ?", model.AccessTargetTypeGroup, groupId).Find(&groupRules)
+	}
+	return
+}
+
+// CheckIpByIds 检查IP是否允许, 拒绝规则优先; 用户有允许规则时以用户的为准, 否则使用群组的允许规则
+func (s *AccessPolicyService) CheckIpByIds(userId, groupId uint, ip string) *IpCheckResult {
+	userRules, groupRules := s.ipRules(userId, groupId)
+	all := append(append([]*model.IpAccessRule{}, userRules...), groupRules...)
+	for _, r := range all {
+		if r.Action == model.IpAccessActionDeny && utils.IpInCidr(ip, r.Cidr) {
+			return &IpCheckResult{Allowed: false, Reason: fmt.Sprintf("ip %s matches deny rule %s", ip, r.Cidr), Rule: r}
+		}
+	}
+	for _, rules := range [][]*model.IpAccessRule{userRules, groupRules} {
+		hasAllow := false
+		for _, r := range rules {
+			if r.Action != model.IpAccessActionAllow {
+				continue
+			}
+			hasAllow = true
+			if utils.IpInCidr(ip, r.Cidr) {
+				return &IpCheckResult{Allowed: true, Reason: fmt.Sprintf("ip %s matches allow rule %s", ip, r.Cidr), Rule: r}
+			}
+		}
+		if hasAllow {
+			return &IpCheckResult{Allowed: false, Reason: fmt.Sprintf("ip %s is not in any allowed range", ip)}
+		}
+	}
+	return &IpCheckResult{Allowed: true, Reason: "no restriction"}
+}
+
+// CheckIp 检查用户是否可以从该IP访问
+func (s *AccessPolicyService) CheckIp(u *model.User, ip string) *IpCheckResult {
+	return s.CheckIpByIds(u.Id, u.GroupId, ip)
+}
+
+// CheckAccess 登录和token使用时的访问检查, 返回的error可直接用于翻译, reason用于审计
+func (s *AccessPolicyService) CheckAccess(u *model.User, ip string) (error, string) {
+	r := s.CheckIp(u, ip)
+	if !r.Allowed {
+		return ErrIpAccessDenied, r.Reason
+	}
+	sr := s.CheckSchedule(u)
+	if !sr.Allowed {
+		return ErrOutsideAccessWindow, sr.Reason
+	}
+	return nil, ""
+}
+
+var ErrOutsideAccessWindow = errors.New("OutsideAccessWindow")
+
+// ScheduleCheckResult 时间窗口检查结果, Schedule为命中或拒绝的时间策略(可能为空)
+type ScheduleCheckResult struct {
+	Allowed  bool                  `json:"allowed"`
+	Reason   string                `json:"reason"`
+	Schedule *model.AccessSchedule `json:"schedule,omitempty"`
+	// revoke 拒绝时是否需要吊销token
+	revoke bool
+}
+
+func (s *AccessPolicyService) ScheduleInfoById(id uint) *model.AccessSchedule {
+	r := &model.AccessSchedule{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *AccessPolicyService) ListSchedules(page, pageSize uint, where func(tx *gorm.DB)) (res *model.AccessScheduleList) {
+	res = &model.AccessScheduleList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.AccessSchedule{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.AccessSchedules)
+	return
+}
+
+func (s *AccessPolicyService) CreateSchedule(r *model.AccessSchedule) error {
+	return DB.Create(r).Error
+}
+
+func (s *AccessPolicyService) UpdateSchedule(r *model.AccessSchedule) error {
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+func (s *AccessPolicyService) DeleteSchedule(r *model.AccessSchedule) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteSchedulesByTarget 删除用户或群组的全部时间策略
+func (s *AccessPolicyService) DeleteSchedulesByTarget(tx *gorm.DB, t int, toId uint) error {
+	return tx.Where("type = ? and to_id = ?", t, toId).Delete(&model.AccessSchedule{}).Error
+}
+
+// LoadLocation 取时区, 为空时使用配置的默认时区, 再为空使用服务器时区
+func (s *AccessPolicyService) LoadLocation(tz string) (*time.Location, error) {
+	if tz == "" && Config != nil {
+		tz = Config.App.AccessTimezone
+	}
+	if tz == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(tz)
+}
+
+// ValidateSchedule 校验时区, 时间窗口和例外日期
+func (s *AccessPolicyService) ValidateSchedule(tz string, windows []utils.WeeklyWindow, holidays []string) error {
+	if _, err := s.LoadLocation(tz); err != nil {
+		return err
+	}
+	for i := range windows {
+		if err := windows[i].Validate(); err != nil {
+			return err
+		}
+	}
+	for _, h := range holidays {
+		if _, err := time.Parse(utils.HolidayDateLayout, h); err != nil {
+			return fmt.Errorf("invalid holiday: %s", h)
+		}
+	}
+	return nil
+}
+
+// scheduleInWindow 判断t是否在时间策略的窗口内, 配置错误时视为不在窗口内
+func (s *AccessPolicyService) scheduleInWindow(sc *model.AccessSchedule, t time.Time) bool {
+	loc, err := s.LoadLocation(sc.Timezone)
+	if err != nil {
+		Logger.Warn("AccessSchedule ", sc.Id, " invalid timezone: ", err)
+		return false
+	}
+	var windows []utils.WeeklyWindow
+	var holidays []string
+	_ = json.Unmarshal(sc.Windows, &windows)
+	_ = json.Unmarshal(sc.Holidays, &holidays)
+	return utils.InWeeklyWindows(t, loc, windows, holidays)
+}
+
+// CheckScheduleByIds 检查t时刻是否在允许的时间窗口内; 用户有启用的时间策略时以用户的为准, 否则使用群组的
+func (s *AccessPolicyService) CheckScheduleByIds(userId, groupId uint, t time.Time) *ScheduleCheckResult {
+	var userSchedules, groupSchedules []*model.AccessSchedule
+	DB.Where("type = ? and to_id = ? and status = ?", model.AccessTargetTypeUser, userId, model.COMMON_STATUS_ENABLE).Find(&userSchedules)
+	if groupId > 0 {
+		DB.Where("type = ? and to_id = ? and status = ?", model.AccessTargetTypeGroup, groupId, model.COMMON_STATUS_ENABLE).Find(&groupSchedules)
+	}
+	schedules := userSchedules
+	if len(schedules) == 0 {
+		schedules = groupSchedules
+	}
+	if len(schedules) == 0 {
+		return &ScheduleCheckResult{Allowed: true, Reason: "no restriction"}
+	}
+	res := &ScheduleCheckResult{Allowed: false}
+	for _, sc := range schedules {
+		if s.scheduleInWindow(sc, t) {
+			return &ScheduleCheckResult{Allowed: true, Reason: fmt.Sprintf("inside window of schedule %s", sc.Name), Schedule: sc}
+		}
+		if res.Schedule == nil {
+			res.Schedule = sc
+		}
+		if sc.RevokeTokens {
+			res.revoke = true
+		}
+	}
+	res.Reason = fmt.Sprintf("outside access window of schedule %s", res.Schedule.Name)
+	return res
+}
+
+// CheckSchedule 检查用户当前是否在允许的时间窗口内
+func (s *AccessPolicyService) CheckSchedule(u *model.User) *ScheduleCheckResult {
+	return s.CheckScheduleByIds(u.Id, u.GroupId, time.Now())
+}
+
+// RevokeOutsideWindowTokens 吊销已处于时间窗口外且策略要求吊销的用户token, 由后台任务定时调用
+func (s *AccessPolicyService) RevokeOutsideWindowTokens() {
+	var schedules []*model.AccessSchedule
+	DB.Where("revoke_tokens = ? and status = ?", true, model.COMMON_STATUS_ENABLE).Find(&schedules)
+	if len(schedules) == 0 {
+		return
+	}
+	userIds := map[uint]struct{}{}
+	for _, sc := range schedules {
+		if sc.Type == model.AccessTargetTypeUser {
+			userIds[sc.ToId] = struct{}{}
+			continue
+		}
+		for _, id := range AllService.UserService.ListIdsByGroupId(sc.ToId) {
+			userIds[id] = struct{}{}
+		}
+	}
+	now := time.Now()
+	for id := range userIds {
+		u := AllService.UserService.InfoById(id)
+		if u.Id == 0 {
+			continue
+		}
+		r := s.CheckScheduleByIds(u.Id, u.GroupId, now)
+		if r.Allowed || !r.revoke {
+			continue
+		}
+		tx := DB.Where("user_id = ?", u.Id).Delete(&model.UserToken{})
+		if tx.Error != nil {
+			Logger.Error("Revoke tokens of user ", u.Id, " failed: ", tx.Error)
+			continue
+		}
+		if tx.RowsAffected > 0 {
+			Logger.Info("Revoked ", tx.RowsAffected, " tokens of user ", u.Username, ": ", r.Reason)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package service
 import (
 	"encoding/json"
 	"github.com/google/uuid"
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/secret"
 	"github.com/RobertLesgros/rustdesk-interface/v2/model"
 	"gorm.io/gorm"
 	"strings"
+	"time"
 )
 
 type AddressBookService struct {
@@ -47,12 +49,13 @@ func (s *AddressBookService) ListByUserIds(userIds []uint, page, pageSize uint)
 }
 
 // AddAddressBook
-func (s *AddressBookService) AddAddressBook(ab *model.AddressBook) error {
-	return DB.Create(ab).Error
+func (s *AddressBookService) AddAddressBook(ab *model.AddressBook, actor *AbActor) error {
+	return s.Create(ab, actor)
 }
 
 // UpdateAddressBook
 func (s *AddressBookService) UpdateAddressBook(abs []*model.AddressBook, userId uint) error {
+	actor := &AbActor{UserId: userId, Source: model.AbChangeSourceClient}
 	//比较peers和数据库中的数据，如果peers中的数据在数据库中不存在，则添加，如果存在则更新，如果数据库中的数据在peers中不存在，则删除
 	// 开始事务
 	tx := DB.Begin()
@@ -85,9 +88,13 @@ func (s *AddressBookService) UpdateAddressBook(abs []*model.AddressBook, userId
 				}
 			}
 			tx.Create(ab)
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, nil, ab)
 		} else {
 			//更新
 			tx.Model(&model.AddressBook{}).Where("row_id = ?", dbAB.RowId).Updates(ab)
+			after := &model.AddressBook{}
+			tx.Where("row_id = ?", dbAB.RowId).First(after)
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, dbAB, after)
 		}
 	}
 	//2.4 删除
@@ -95,6 +102,8 @@ func (s *AddressBookService) UpdateAddressBook(abs []*model.AddressBook, userId
 		_, ok := aBIds[id]
 		if !ok {
 			tx.Delete(dbAB)
+			AllService.PeerShareService.DeleteByAbRowIds(tx, []uint{dbAB.RowId})
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, dbAB, nil)
 		}
 	}
 	tx.Commit()
@@ -126,28 +135,74 @@ func (s *AddressBookService) FromPeer(peer *model.Peer) (a *model.AddressBook) {
 	return a
 }
 
-// Create 创建
-func (s *AddressBookService) Create(u *model.AddressBook) error {
+// Create 创建, actor 不为空时记录变更历史
+func (s *AddressBookService) Create(u *model.AddressBook, actor *AbActor) error {
 	res := DB.Create(u).Error
+	if res == nil {
+		AllService.AddressBookHistoryService.RecordPeer(nil, actor, nil, u)
+	}
 	return res
 }
-func (s *AddressBookService) Delete(u *model.AddressBook) error {
-	return DB.Delete(u).Error
+func (s *AddressBookService) Delete(u *model.AddressBook, actor *AbActor) error {
+	before := s.InfoByRowId(u.RowId)
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(u).Error; err != nil {
+			return err
+		}
+		if err := AllService.PeerShareService.DeleteByAbRowIds(tx, []uint{u.RowId}); err != nil {
+			return err
+		}
+		if before.RowId != 0 {
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, before, nil)
+		}
+		return nil
+	})
 }
 
 // Update 更新
-func (s *AddressBookService) Update(u *model.AddressBook) error {
-	return DB.Model(u).Updates(u).Error
+func (s *AddressBookService) Update(u *model.AddressBook, actor *AbActor) error {
+	return s.updateWithHistory(u.RowId, actor, func() error {
+		return DB.Model(u).Updates(u).Error
+	})
+}
+
+// updateWithHistory 执行 fn 并记录 row_id 对应条目的前后变化
+func (s *AddressBookService) updateWithHistory(rowId uint, actor *AbActor, fn func() error) error {
+	if actor == nil {
+		return fn()
+	}
+	before := s.InfoByRowId(rowId)
+	if err := fn(); err != nil {
+		return err
+	}
+	if before.RowId != 0 {
+		AllService.AddressBookHistoryService.RecordPeer(nil, actor, before, s.InfoByRowId(rowId))
+	}
+	return nil
 }
 
 // UpdateByMap 更新
-func (s *AddressBookService) UpdateByMap(u *model.AddressBook, data map[string]interface{}) error {
-	return DB.Model(u).Updates(data).Error
+func (s *AddressBookService) UpdateByMap(u *model.AddressBook, data map[string]interface{}, actor *AbActor) error {
+	// map 更新不经过 secret 序列化
+	for _, k := range []string{"password", "hash"} {
+		if v, ok := data[k].(string); ok {
+			enc, err := secret.Encrypt(v)
+			if err != nil {
+				return err
+			}
+			data[k] = enc
+		}
+	}
+	return s.updateWithHistory(u.RowId, actor, func() error {
+		return DB.Model(u).Updates(data).Error
+	})
 }
 
 // UpdateAll 更新
-func (s *AddressBookService) UpdateAll(u *model.AddressBook) error {
-	return DB.Model(u).Select("*").Omit("created_at").Updates(u).Error
+func (s *AddressBookService) UpdateAll(u *model.AddressBook, actor *AbActor) error {
+	return s.updateWithHistory(u.RowId, actor, func() error {
+		return DB.Model(u).Select("*").Omit("created_at").Updates(u).Error
+	})
 }
 
 // ShareByWebClient 分享
@@ -218,27 +273,44 @@ func (s *AddressBookService) CollectionInfoById(id uint) *model.AddressBookColle
 func (s *AddressBookService) CollectionReadRules(user *model.User) (res []*model.AddressBookCollectionRule) {
 	// personalRules
 	var personalRules []*model.AddressBookCollectionRule
-	tx2 := DB.Model(&model.AddressBookCollectionRule{})
+	tx2 := DB.Model(&model.AddressBookCollectionRule{}).Scopes(activeRule(time.Now().Unix()))
 	tx2.Where("type = ? and to_id = ? and rule > 0", model.ShareAddressBookRuleTypePersonal, user.Id).Find(&personalRules)
 	res = append(res, personalRules...)
 
 	//group
 	var groupRules []*model.AddressBookCollectionRule
-	tx3 := DB.Model(&model.AddressBookCollectionRule{})
+	tx3 := DB.Model(&model.AddressBookCollectionRule{}).Scopes(activeRule(time.Now().Unix()))
 	tx3.Where("type = ? and to_id = ? and rule > 0", model.ShareAddressBookRuleTypeGroup, user.GroupId).Find(&groupRules)
 	res = append(res, groupRules...)
 	return
 }
 
 func (s *AddressBookService) UserMaxRule(user *model.User, uid, cid uint) int {
+	// 虚拟地址簿 "与我共享" 只能由本人读取
+	if cid == SharedWithMeCollectionId {
+		if user.Id == uid {
+			return model.ShareAddressBookRuleRuleRead
+		}
+		return 0
+	}
+	max := s.userMaxRule(user, uid, cid)
+	// 动态集合对客户端只读
+	if max > model.ShareAddressBookRuleRuleRead && !s.CheckCollectionWritable(cid) {
+		return model.ShareAddressBookRuleRuleRead
+	}
+	return max
+}
+
+func (s *AddressBookService) userMaxRule(user *model.User, uid, cid uint) int {
 	// ismy?
 	if user.Id == uid {
 		return model.ShareAddressBookRuleRuleFullControl
 	}
 	max := 0
+	now := time.Now().Unix()
 	personalRules := &model.AddressBookCollectionRule{}
-	tx := DB.Model(personalRules)
-	tx.Where("type = ? and collection_id = ? and to_id = ?", model.ShareAddressBookRuleTypePersonal, cid, user.Id).First(&personalRules)
+	tx := DB.Model(personalRules).Scopes(activeRule(now))
+	tx.Where("type = ? and collection_id = ? and folder_id = 0 and to_id = ?", model.ShareAddressBookRuleTypePersonal, cid, user.Id).First(&personalRules)
 	if personalRules.Id != 0 {
 		max = personalRules.Rule
 		if max == model.ShareAddressBookRuleRuleFullControl {
@@ -247,8 +319,8 @@ func (s *AddressBookService) UserMaxRule(user *model.User, uid, cid uint) int {
 	}
 
 	groupRules := &model.AddressBookCollectionRule{}
-	tx2 := DB.Model(groupRules)
-	tx2.Where("type = ? and collection_id = ? and to_id = ?", model.ShareAddressBookRuleTypeGroup, cid, user.GroupId).First(&groupRules)
+	tx2 := DB.Model(groupRules).Scopes(activeRule(now))
+	tx2.Where("type = ? and collection_id = ? and folder_id = 0 and to_id = ?", model.ShareAddressBookRuleTypeGroup, cid, user.GroupId).First(&groupRules)
 	if groupRules.Id != 0 {
 		if groupRules.Rule > max {
 			max = groupRules.Rule
@@ -275,14 +347,27 @@ func (s *AddressBookService) CreateCollection(t *model.AddressBookCollection) er
 }
 
 func (s *AddressBookService) UpdateCollection(t *model.AddressBookCollection) error {
-	return DB.Model(t).Updates(t).Error
+	if err := DB.Model(t).Updates(t).Error; err != nil {
+		return err
+	}
+	// 清空表达式即改回普通集合, 标签策略可以改回 0, 不能只靠 Updates
+	return DB.Model(t).UpdateColumns(map[string]interface{}{"query": t.Query, "tag_policy": t.TagPolicy}).Error
 }
 
-func (s *AddressBookService) DeleteCollection(t *model.AddressBookCollection) error {
+func (s *AddressBookService) DeleteCollection(t *model.AddressBookCollection, actor *AbActor) error {
 	//删除集合下的所有规则、地址簿，再删除集合
 	tx := DB.Begin()
 	tx.Where("collection_id = ?", t.Id).Delete(&model.AddressBookCollectionRule{})
+	if actor != nil {
+		var abs []*model.AddressBook
+		tx.Where("collection_id = ?", t.Id).Find(&abs)
+		for _, ab := range abs {
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, ab, nil)
+		}
+	}
+	AllService.PeerShareService.DeleteByCollectionId(tx, t.Id)
 	tx.Where("collection_id = ?", t.Id).Delete(&model.AddressBook{})
+	AllService.AddressBookFolderService.DeleteByCollectionId(tx, t.Id)
 	tx.Delete(t)
 	return tx.Commit().Error
 }
@@ -296,8 +381,13 @@ func (s *AddressBookService) RulePersonalInfoByToIdAndCid(toid, cid uint) *model
 	return s.RuleInfoByToIdAndCid(model.ShareAddressBookRuleTypePersonal, toid, cid)
 }
 func (s *AddressBookService) RuleInfoByToIdAndCid(t int, toid, cid uint) *model.AddressBookCollectionRule {
+	return s.RuleInfoByToIdAndFolder(t, toid, cid, 0)
+}
+
+// RuleInfoByToIdAndFolder 集合中某个文件夹的规则, folderId 为 0 时为整个集合的规则
+func (s *AddressBookService) RuleInfoByToIdAndFolder(t int, toid, cid, folderId uint) *model.AddressBookCollectionRule {
 	p := &model.AddressBookCollectionRule{}
-	DB.Where("type = ? and to_id = ? and collection_id = ?", t, toid, cid).First(p)
+	DB.Where("type = ? and to_id = ? and collection_id = ? and folder_id = ?", t, toid, cid, folderId).First(p)
 	return p
 }
 func (s *AddressBookService) CreateRule(t *model.AddressBookCollectionRule) error {
@@ -319,7 +409,24 @@ func (s *AddressBookService) ListRules(page uint, size uint, f func(tx *gorm.DB)
 }
 
 func (s *AddressBookService) UpdateRule(t *model.AddressBookCollectionRule) error {
-	return DB.Model(t).Updates(t).Error
+	ex := s.RuleInfoById(t.Id)
+	if err := DB.Model(t).Updates(t).Error; err != nil {
+		return err
+	}
+	// 有效期和文件夹可以清零, 不能只靠 Updates; 修改到期时间后重新提醒
+	cols := map[string]interface{}{"start_at": t.StartAt, "end_at": t.EndAt, "folder_id": t.FolderId}
+	if ex.EndAt != t.EndAt {
+		cols["notified_at"] = 0
+		// 只恢复定时任务到期停用的规则, 手动停用或表单指定的状态不变
+		if ex.ExpiredAt > 0 && t.Status == 0 && (t.EndAt == 0 || t.EndAt > time.Now().Unix()) {
+			cols["status"] = model.COMMON_STATUS_ENABLE
+			cols["expired_at"] = 0
+		}
+	}
+	if t.Status == model.COMMON_STATUS_ENABLE {
+		cols["expired_at"] = 0
+	}
+	return DB.Model(t).UpdateColumns(cols).Error
 }
 
 func (s *AddressBookService) DeleteRule(t *model.AddressBookCollectionRule) error {
@@ -332,11 +439,20 @@ func (s *AddressBookService) CheckCollectionOwner(uid uint, cid uint) bool {
 	return p.UserId == uid
 }
 
-func (s *AddressBookService) BatchUpdateTags(abs []*model.AddressBook, tags []string) error {
+func (s *AddressBookService) BatchUpdateTags(abs []*model.AddressBook, tags []string, actor *AbActor) error {
 	ids := make([]uint, 0)
 	for _, ab := range abs {
 		ids = append(ids, ab.RowId)
 	}
 	tagsv, _ := json.Marshal(tags)
-	return DB.Model(&model.AddressBook{}).Where("row_id in ?", ids).Update("tags", tagsv).Error
+	err := DB.Model(&model.AddressBook{}).Where("row_id in ?", ids).Update("tags", tagsv).Error
+	if err != nil || actor == nil {
+		return err
+	}
+	for _, ab := range abs {
+		after := *ab
+		after.Tags = tagsv
+		AllService.AddressBookHistoryService.RecordPeer(nil, actor, ab, &after)
+	}
+	return nil
 }
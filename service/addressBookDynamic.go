@@ -0,0 +1,95 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// DynamicAbMaxPeers 动态集合最多返回的设备数, 与 /api/ab/peers 的分页上限一致
+const DynamicAbMaxPeers = 1000
+
+var (
+	ErrAbCollectionDynamic  = errors.New("AbCollectionDynamic")
+	ErrAbCollectionNotEmpty = errors.New("AbCollectionNotEmpty")
+)
+
+// ValidateCollectionQuery 保存前编译一次动态集合的表达式
+func (s *AddressBookService) ValidateCollectionQuery(t *model.AddressBookCollection) error {
+	t.Query = strings.TrimSpace(t.Query)
+	if t.Query == "" {
+		return nil
+	}
+	_, err := AllService.SearchService.Compile(SearchResourcePeer, t.Query, "")
+	return err
+}
+
+// CheckCollectionWritable 动态集合的内容由表达式生成, 不能直接添加或修改设备
+func (s *AddressBookService) CheckCollectionWritable(cid uint) bool {
+	if cid == 0 {
+		return true
+	}
+	return !s.CollectionInfoById(cid).IsDynamic()
+}
+
+// CheckCollectionCanBeDynamic 普通集合改为动态集合前, 集合内不能有设备和文件夹
+func (s *AddressBookService) CheckCollectionCanBeDynamic(t *model.AddressBookCollection) error {
+	if !t.IsDynamic() || t.Id == 0 || s.CollectionInfoById(t.Id).IsDynamic() {
+		return nil
+	}
+	var n int64
+	DB.Model(&model.AddressBook{}).Where("collection_id = ?", t.Id).Count(&n)
+	if n > 0 {
+		return ErrAbCollectionNotEmpty
+	}
+	DB.Model(&model.AddressBookFolder{}).Where("collection_id = ?", t.Id).Count(&n)
+	if n > 0 {
+		return ErrAbCollectionNotEmpty
+	}
+	return nil
+}
+
+// DynamicPeers 计算动态集合的内容. 管理员的集合可以匹配全部设备, 其他用户只匹配自己的设备
+func (s *AddressBookService) DynamicPeers(c *model.AddressBookCollection, page, pageSize uint) (*model.AddressBookList, error) {
+	where, err := AllService.SearchService.Compile(SearchResourcePeer, c.Query, "")
+	if err != nil {
+		return nil, err
+	}
+	owner := AllService.UserService.InfoById(c.UserId)
+	if owner.Id == 0 {
+		return &model.AddressBookList{AddressBooks: make([]*model.AddressBook, 0)}, nil
+	}
+	isAdmin := AllService.UserService.IsAdmin(owner)
+	peers := AllService.PeerService.List(page, pageSize, func(tx *gorm.DB) {
+		if !isAdmin {
+			tx.Where("user_id = ?", owner.Id)
+		}
+		if where != nil {
+			where(tx)
+		}
+		tx.Order("row_id asc")
+	})
+	res := &model.AddressBookList{AddressBooks: make([]*model.AddressBook, 0, len(peers.Peers))}
+	res.Pagination = peers.Pagination
+	for _, p := range peers.Peers {
+		res.AddressBooks = append(res.AddressBooks, s.peerToDynamicAb(c, p))
+	}
+	return res, nil
+}
+
+// peerToDynamicAb 动态集合中的条目没有保存在地址簿表中, RowId 为 0, 不带密码
+func (s *AddressBookService) peerToDynamicAb(c *model.AddressBookCollection, p *model.Peer) *model.AddressBook {
+	return &model.AddressBook{
+		Id:           p.Id,
+		Username:     p.Username,
+		Hostname:     p.Hostname,
+		Alias:        p.Alias,
+		Platform:     s.PlatformFromOs(p.Os),
+		Tags:         custom_types.AutoJson("[]"),
+		UserId:       c.UserId,
+		CollectionId: c.Id,
+	}
+}
@@ -0,0 +1,29 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestPeerToDynamicAb(t *testing.T) {
+	s := &AddressBookService{}
+	c := &model.AddressBookCollection{UserId: 3, Query: "os:windows"}
+	c.Id = 7
+	if !c.IsDynamic() {
+		t.Fatal("collection with a query should be dynamic")
+	}
+	ab := s.peerToDynamicAb(c, &model.Peer{Id: "123456789", Hostname: "pc-01", Username: "alice", Alias: "accueil", Os: "Windows 11"})
+	if ab.Id != "123456789" || ab.Hostname != "pc-01" || ab.Username != "alice" || ab.Alias != "accueil" {
+		t.Errorf("unexpected entry %+v", ab)
+	}
+	if ab.Platform != "Windows" {
+		t.Errorf("Platform = %q, want Windows", ab.Platform)
+	}
+	if ab.UserId != 3 || ab.CollectionId != 7 || ab.RowId != 0 {
+		t.Errorf("UserId/CollectionId/RowId = %d/%d/%d", ab.UserId, ab.CollectionId, ab.RowId)
+	}
+	if string(ab.Tags) != "[]" || ab.Password != "" || ab.Hash != "" {
+		t.Errorf("dynamic entries must not carry tags or secrets: %+v", ab)
+	}
+}
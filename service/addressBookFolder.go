@@ -0,0 +1,348 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// AbFolderTagPrefix 客户端没有文件夹, /api/ab 接口中用 "folder:路径" 标签表示设备所在的文件夹
+const AbFolderTagPrefix = "folder:"
+
+var (
+	ErrFolderNotFound = errors.New("ItemNotFound")
+	ErrFolderExists   = errors.New("ItemExists")
+	ErrFolderCycle    = errors.New("FolderCycle")
+)
+
+type AddressBookFolderService struct {
+}
+
+// folderTree 一个集合的全部文件夹
+type folderTree struct {
+	byId     map[uint]*model.AddressBookFolder
+	children map[uint][]uint
+}
+
+func loadFolderTree(cid uint) *folderTree {
+	var folders []*model.AddressBookFolder
+	DB.Where("collection_id = ?", cid).Find(&folders)
+	t := &folderTree{byId: make(map[uint]*model.AddressBookFolder, len(folders)), children: make(map[uint][]uint)}
+	for _, f := range folders {
+		t.byId[f.Id] = f
+		t.children[f.ParentId] = append(t.children[f.ParentId], f.Id)
+	}
+	for _, f := range folders {
+		f.Path = t.path(f.Id)
+	}
+	return t
+}
+
+// path 文件夹完整路径, 父文件夹丢失时从能找到的部分开始
+func (t *folderTree) path(id uint) string {
+	var names []string
+	for _, a := range t.ancestors(id) {
+		names = append(names, t.byId[a].Name)
+	}
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return strings.Join(names, "/")
+}
+
+// ancestors 自身及所有上级文件夹, 从自身开始
+func (t *folderTree) ancestors(id uint) []uint {
+	var res []uint
+	seen := make(map[uint]bool)
+	for id != 0 && !seen[id] {
+		f, ok := t.byId[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		res = append(res, id)
+		id = f.ParentId
+	}
+	return res
+}
+
+// descendants 自身及所有子文件夹
+func (t *folderTree) descendants(id uint) []uint {
+	res := []uint{id}
+	for i := 0; i < len(res); i++ {
+		res = append(res, t.children[res[i]]...)
+	}
+	return res
+}
+
+// byPath 按完整路径查找文件夹
+func (t *folderTree) byPath(path string) *model.AddressBookFolder {
+	for _, f := range t.byId {
+		if f.Path == path {
+			return f
+		}
+	}
+	return nil
+}
+
+func (s *AddressBookFolderService) InfoById(id uint) *model.AddressBookFolder {
+	f := &model.AddressBookFolder{}
+	DB.Where("id = ?", id).First(f)
+	return f
+}
+
+// ListByCollectionId 集合的全部文件夹, 按路径排序
+func (s *AddressBookFolderService) ListByCollectionId(cid uint) *model.AddressBookFolderList {
+	t := loadFolderTree(cid)
+	res := &model.AddressBookFolderList{AddressBookFolders: make([]*model.AddressBookFolder, 0, len(t.byId))}
+	for _, f := range t.byId {
+		res.AddressBookFolders = append(res.AddressBookFolders, f)
+	}
+	sort.Slice(res.AddressBookFolders, func(i, j int) bool {
+		return res.AddressBookFolders[i].Path < res.AddressBookFolders[j].Path
+	})
+	return res
+}
+
+// Check 文件夹是否属于集合, 0 为根目录
+func (s *AddressBookFolderService) Check(cid, folderId uint) bool {
+	if folderId == 0 {
+		return true
+	}
+	return cid > 0 && s.InfoById(folderId).CollectionId == cid
+}
+
+// siblingExists 同一上级下是否已有同名文件夹
+func (s *AddressBookFolderService) siblingExists(f *model.AddressBookFolder, parentId uint, name string) bool {
+	ex := &model.AddressBookFolder{}
+	DB.Where("collection_id = ? and parent_id = ? and name = ? and id <> ?", f.CollectionId, parentId, name, f.Id).First(ex)
+	return ex.Id != 0
+}
+
+func (s *AddressBookFolderService) Create(f *model.AddressBookFolder) error {
+	if !AllService.AddressBookService.CheckCollectionWritable(f.CollectionId) {
+		return ErrAbCollectionDynamic
+	}
+	if f.ParentId > 0 && !s.Check(f.CollectionId, f.ParentId) {
+		return ErrFolderNotFound
+	}
+	if s.siblingExists(f, f.ParentId, f.Name) {
+		return ErrFolderExists
+	}
+	if err := DB.Create(f).Error; err != nil {
+		return err
+	}
+	s.bump(f)
+	return nil
+}
+
+// Rename 重命名, 客户端看到的文件夹标签随之变化
+func (s *AddressBookFolderService) Rename(f *model.AddressBookFolder, name string) error {
+	if s.siblingExists(f, f.ParentId, name) {
+		return ErrFolderExists
+	}
+	if err := DB.Model(f).Update("name", name).Error; err != nil {
+		return err
+	}
+	s.bump(f)
+	return nil
+}
+
+// Move 移动到同一集合的另一个文件夹下, 不能移动到自身或子文件夹下
+func (s *AddressBookFolderService) Move(f *model.AddressBookFolder, parentId uint) error {
+	if parentId > 0 {
+		if !s.Check(f.CollectionId, parentId) {
+			return ErrFolderNotFound
+		}
+		for _, id := range loadFolderTree(f.CollectionId).descendants(f.Id) {
+			if id == parentId {
+				return ErrFolderCycle
+			}
+		}
+	}
+	if s.siblingExists(f, parentId, f.Name) {
+		return ErrFolderExists
+	}
+	if err := DB.Model(f).Update("parent_id", parentId).Error; err != nil {
+		return err
+	}
+	s.bump(f)
+	return nil
+}
+
+// Delete 删除文件夹, 其中的子文件夹和设备移到上级, 该文件夹的共享规则一并删除
+func (s *AddressBookFolderService) Delete(f *model.AddressBookFolder) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AddressBookFolder{}).Where("parent_id = ?", f.Id).Update("parent_id", f.ParentId).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&model.AddressBook{}).Where("folder_id = ?", f.Id).Update("folder_id", f.ParentId).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("folder_id = ?", f.Id).Delete(&model.AddressBookCollectionRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(f).Error
+	})
+	if err == nil {
+		s.bump(f)
+	}
+	return err
+}
+
+// bump 文件夹变化会改变客户端看到的标签, 地址簿版本号加一
+func (s *AddressBookFolderService) bump(f *model.AddressBookFolder) {
+	AllService.AddressBookRevisionService.Bump(nil, f.UserId, f.CollectionId)
+}
+
+// DeleteByCollectionId 删除集合时清理
+func (s *AddressBookFolderService) DeleteByCollectionId(tx *gorm.DB, cid uint) error {
+	return tx.Where("collection_id = ?", cid).Delete(&model.AddressBookFolder{}).Error
+}
+
+// DeleteByUserId 删除用户时清理
+func (s *AddressBookFolderService) DeleteByUserId(tx *gorm.DB, userId uint) error {
+	return tx.Where("user_id = ?", userId).Delete(&model.AddressBookFolder{}).Error
+}
+
+// FolderTag 文件夹路径对应的标签
+func FolderTag(path string) string {
+	return AbFolderTagPrefix + path
+}
+
+// SplitFolderTags 分开普通标签和文件夹标签, 返回文件夹路径
+func SplitFolderTags(tags []string) (plain []string, paths []string) {
+	plain = make([]string, 0, len(tags))
+	for _, t := range tags {
+		if strings.HasPrefix(t, AbFolderTagPrefix) {
+			paths = append(paths, strings.TrimPrefix(t, AbFolderTagPrefix))
+			continue
+		}
+		plain = append(plain, t)
+	}
+	return
+}
+
+// FolderByPath 按路径查找集合中的文件夹, 空路径为根目录
+func (s *AddressBookFolderService) FolderByPath(cid uint, path string) (uint, bool) {
+	if path == "" {
+		return 0, true
+	}
+	f := loadFolderTree(cid).byPath(path)
+	if f == nil {
+		return 0, false
+	}
+	return f.Id, true
+}
+
+// FolderTags 集合中的文件夹标签, all 为 false 时只取 ids 对应的文件夹, 用于 /api/ab/tags
+func (s *AddressBookFolderService) FolderTags(cid uint, all bool, ids []uint) []string {
+	t := loadFolderTree(cid)
+	if all {
+		ids = make([]uint, 0, len(t.byId))
+		for id := range t.byId {
+			ids = append(ids, id)
+		}
+	}
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if f, ok := t.byId[id]; ok {
+			res = append(res, FolderTag(f.Path))
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+// FillFolderTags 把设备所在文件夹的路径作为标签追加到 Tags, 只修改返回给客户端的内容
+func (s *AddressBookFolderService) FillFolderTags(cid uint, abs []*model.AddressBook) {
+	if cid == 0 {
+		return
+	}
+	var t *folderTree
+	for _, ab := range abs {
+		if ab.FolderId == 0 {
+			continue
+		}
+		if t == nil {
+			t = loadFolderTree(cid)
+		}
+		f, ok := t.byId[ab.FolderId]
+		if !ok {
+			continue
+		}
+		var tags []string
+		_ = json.Unmarshal(ab.Tags, &tags)
+		b, _ := json.Marshal(append(tags, FolderTag(f.Path)))
+		ab.Tags = custom_types.AutoJson(b)
+	}
+}
+
+// userFolderRules 用户在集合各文件夹上的共享规则(个人和群组取较高者), 只取当前生效的
+func (s *AddressBookService) userFolderRules(user *model.User, cid uint) map[uint]int {
+	var rules []*model.AddressBookCollectionRule
+	DB.Scopes(activeRule(time.Now().Unix())).
+		Where("collection_id = ? and folder_id > 0 and rule > 0 and ((type = ? and to_id = ?) or (type = ? and to_id = ?))",
+			cid, model.ShareAddressBookRuleTypePersonal, user.Id, model.ShareAddressBookRuleTypeGroup, user.GroupId).
+		Find(&rules)
+	res := make(map[uint]int, len(rules))
+	for _, r := range rules {
+		if r.Rule > res[r.FolderId] {
+			res[r.FolderId] = r.Rule
+		}
+	}
+	return res
+}
+
+// UserFolderRule 用户对集合中某个文件夹内设备的权限: 集合规则与该文件夹及上级文件夹规则中的最高者
+func (s *AddressBookService) UserFolderRule(user *model.User, uid, cid, folderId uint) int {
+	max := s.UserMaxRule(user, uid, cid)
+	if max == model.ShareAddressBookRuleRuleFullControl || folderId == 0 || cid == 0 {
+		return max
+	}
+	rules := s.userFolderRules(user, cid)
+	if len(rules) == 0 {
+		return max
+	}
+	for _, id := range loadFolderTree(cid).ancestors(folderId) {
+		if rules[id] > max {
+			max = rules[id]
+		}
+	}
+	return max
+}
+
+// UserReadableFolders 用户可以读取的范围: all 为整个集合, 否则为可读的文件夹(含子文件夹)
+func (s *AddressBookService) UserReadableFolders(user *model.User, uid, cid uint) (all bool, ids []uint) {
+	if s.CheckUserReadPrivilege(user, uid, cid) {
+		return true, nil
+	}
+	if cid == 0 {
+		return false, nil
+	}
+	rules := s.userFolderRules(user, cid)
+	if len(rules) == 0 {
+		return false, nil
+	}
+	t := loadFolderTree(cid)
+	seen := make(map[uint]bool)
+	for id, r := range rules {
+		if r < model.ShareAddressBookRuleRuleRead {
+			continue
+		}
+		for _, d := range t.descendants(id) {
+			if _, ok := t.byId[d]; ok && !seen[d] {
+				seen[d] = true
+				ids = append(ids, d)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return false, ids
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestSplitFolderTags(t *testing.T) {
+	plain, paths := SplitFolderTags([]string{"prod", "folder:Clients/Paris", "linux"})
+	if !reflect.DeepEqual(plain, []string{"prod", "linux"}) {
+		t.Errorf("plain = %v", plain)
+	}
+	if !reflect.DeepEqual(paths, []string{"Clients/Paris"}) {
+		t.Errorf("paths = %v", paths)
+	}
+	if FolderTag("Clients/Paris") != "folder:Clients/Paris" {
+		t.Errorf("FolderTag = %s", FolderTag("Clients/Paris"))
+	}
+}
+
+func TestFolderTree(t *testing.T) {
+	tree := &folderTree{byId: map[uint]*model.AddressBookFolder{}, children: map[uint][]uint{}}
+	add := func(id, parent uint, name string) {
+		f := &model.AddressBookFolder{ParentId: parent, Name: name}
+		f.Id = id
+		tree.byId[id] = f
+		tree.children[parent] = append(tree.children[parent], id)
+	}
+	add(1, 0, "Clients")
+	add(2, 1, "Paris")
+	add(3, 2, "Nord")
+	add(4, 0, "Interne")
+	for _, f := range tree.byId {
+		f.Path = tree.path(f.Id)
+	}
+
+	if p := tree.path(3); p != "Clients/Paris/Nord" {
+		t.Errorf("path(3) = %s", p)
+	}
+	if a := tree.ancestors(3); !reflect.DeepEqual(a, []uint{3, 2, 1}) {
+		t.Errorf("ancestors(3) = %v", a)
+	}
+	if d := tree.descendants(1); !reflect.DeepEqual(d, []uint{1, 2, 3}) {
+		t.Errorf("descendants(1) = %v", d)
+	}
+	if f := tree.byPath("Clients/Paris"); f == nil || f.Id != 2 {
+		t.Errorf("byPath(Clients/Paris) = %v", f)
+	}
+	if f := tree.byPath("Paris"); f != nil {
+		t.Errorf("byPath(Paris) = %v, want nil", f)
+	}
+}
@@ -0,0 +1,406 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/secret"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// AbActor 地址簿变更的操作人和来源, 为 nil 时不记录
+type AbActor struct {
+	UserId uint
+	Source string
+}
+
+func NewAbActor(u *model.User, source string) *AbActor {
+	return &AbActor{UserId: u.Id, Source: source}
+}
+
+// abPeerSnapshot 地址簿条目快照, 只包含可恢复的字段
+type abPeerSnapshot struct {
+	Id               string                `json:"id"`
+	Username         string                `json:"username"`
+	Password         string                `json:"password"`
+	Hostname         string                `json:"hostname"`
+	Alias            string                `json:"alias"`
+	Platform         string                `json:"platform"`
+	Tags             custom_types.AutoJson `json:"tags"`
+	Hash             string                `json:"hash"`
+	ForceAlwaysRelay bool                  `json:"forceAlwaysRelay"`
+	RdpPort          string                `json:"rdpPort"`
+	RdpUsername      string                `json:"rdpUsername"`
+	LoginName        string                `json:"loginName"`
+	SameServer       bool                  `json:"sameServer"`
+	FolderId         uint                  `json:"folder_id"`
+}
+
+type abTagSnapshot struct {
+	Id    uint   `json:"id"`
+	Name  string `json:"name"`
+	Color uint   `json:"color"`
+}
+
+func newPeerSnapshot(ab *model.AddressBook) *abPeerSnapshot {
+	tags := ab.Tags
+	if len(tags) == 0 || string(tags) == "null" {
+		tags = custom_types.AutoJson("[]")
+	}
+	return &abPeerSnapshot{
+		Id:               ab.Id,
+		Username:         ab.Username,
+		Password:         ab.Password,
+		Hostname:         ab.Hostname,
+		Alias:            ab.Alias,
+		Platform:         ab.Platform,
+		Tags:             tags,
+		Hash:             ab.Hash,
+		ForceAlwaysRelay: ab.ForceAlwaysRelay,
+		RdpPort:          ab.RdpPort,
+		RdpUsername:      ab.RdpUsername,
+		LoginName:        ab.LoginName,
+		SameServer:       ab.SameServer,
+		FolderId:         ab.FolderId,
+	}
+}
+
+func (p *abPeerSnapshot) apply(ab *model.AddressBook) {
+	ab.Id = p.Id
+	ab.Username = p.Username
+	ab.Password = p.Password
+	ab.Hostname = p.Hostname
+	ab.Alias = p.Alias
+	ab.Platform = p.Platform
+	ab.Tags = p.Tags
+	ab.Hash = p.Hash
+	ab.ForceAlwaysRelay = p.ForceAlwaysRelay
+	ab.RdpPort = p.RdpPort
+	ab.RdpUsername = p.RdpUsername
+	ab.LoginName = p.LoginName
+	ab.SameServer = p.SameServer
+	ab.FolderId = p.FolderId
+}
+
+// encode 密码和 hash 加密后保存
+func (p *abPeerSnapshot) encode() (custom_types.AutoJson, error) {
+	c := *p
+	var err error
+	if c.Password, err = secret.Encrypt(c.Password); err != nil {
+		return nil, err
+	}
+	if c.Hash, err = secret.Encrypt(c.Hash); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(&c)
+	return custom_types.AutoJson(b), err
+}
+
+func decodePeerSnapshot(j custom_types.AutoJson) (*abPeerSnapshot, error) {
+	if isNullJson(j) {
+		return nil, nil
+	}
+	p := &abPeerSnapshot{}
+	if err := json.Unmarshal(j, p); err != nil {
+		return nil, err
+	}
+	var err error
+	if p.Password, err = secret.Decrypt(p.Password); err != nil {
+		return nil, err
+	}
+	if p.Hash, err = secret.Decrypt(p.Hash); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func decodeTagSnapshot(j custom_types.AutoJson) (*abTagSnapshot, error) {
+	if isNullJson(j) {
+		return nil, nil
+	}
+	t := &abTagSnapshot{}
+	return t, json.Unmarshal(j, t)
+}
+
+func isNullJson(j custom_types.AutoJson) bool {
+	s := string(j)
+	return s == "" || s == "null" || s == "[]"
+}
+
+type AddressBookHistoryService struct {
+}
+
+func (s *AddressBookHistoryService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.AddressBookChangeList) {
+	res = &model.AddressBookChangeList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.AddressBookChange{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.AddressBookChanges)
+	for _, c := range res.AddressBookChanges {
+		c.Before = maskSnapshotSecrets(c.Before)
+		c.After = maskSnapshotSecrets(c.After)
+	}
+	return
+}
+
+// maskSnapshotSecrets 历史接口不返回密码和 hash, 只标记是否有值
+func maskSnapshotSecrets(j custom_types.AutoJson) custom_types.AutoJson {
+	if isNullJson(j) {
+		return j
+	}
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		return j
+	}
+	for _, k := range []string{"password", "hash"} {
+		if v, ok := m[k].(string); ok && v != "" {
+			m[k] = "******"
+		}
+	}
+	b, _ := json.Marshal(m)
+	return custom_types.AutoJson(b)
+}
+
+// RecordPeer 记录地址簿条目变更, before 为 nil 表示新增, after 为 nil 表示删除; 内容没有变化时不记录.
+// tx 为 nil 时使用 DB
+func (s *AddressBookHistoryService) RecordPeer(tx *gorm.DB, actor *AbActor, before, after *model.AddressBook) {
+	if actor == nil || (before == nil && after == nil) {
+		return
+	}
+	// 移动到其他地址簿时记录为两个地址簿各自的删除和新增
+	if before != nil && after != nil && (before.UserId != after.UserId || before.CollectionId != after.CollectionId) {
+		s.RecordPeer(tx, actor, before, nil)
+		s.RecordPeer(tx, actor, nil, after)
+		return
+	}
+	r := &model.AddressBookChange{Entity: model.AbChangeEntityPeer}
+	var b, a *abPeerSnapshot
+	if before != nil {
+		b = newPeerSnapshot(before)
+		r.UserId, r.CollectionId, r.EntityKey = before.UserId, before.CollectionId, before.Id
+	}
+	if after != nil {
+		a = newPeerSnapshot(after)
+		r.UserId, r.CollectionId, r.EntityKey = after.UserId, after.CollectionId, after.Id
+	}
+	if b != nil && a != nil && b.equal(a) {
+		return
+	}
+	var err error
+	if b != nil {
+		if r.Before, err = b.encode(); err != nil {
+			Logger.Error("Record address book change failed: ", err)
+			return
+		}
+	}
+	if a != nil {
+		if r.After, err = a.encode(); err != nil {
+			Logger.Error("Record address book change failed: ", err)
+			return
+		}
+	}
+	s.create(tx, actor, r)
+}
+
+// RecordTag 记录标签变更, 规则同 RecordPeer
+func (s *AddressBookHistoryService) RecordTag(tx *gorm.DB, actor *AbActor, before, after *model.Tag) {
+	if actor == nil || (before == nil && after == nil) {
+		return
+	}
+	if before != nil && after != nil && (before.UserId != after.UserId || before.CollectionId != after.CollectionId) {
+		s.RecordTag(tx, actor, before, nil)
+		s.RecordTag(tx, actor, nil, after)
+		return
+	}
+	r := &model.AddressBookChange{Entity: model.AbChangeEntityTag}
+	var bj, aj []byte
+	if before != nil {
+		bj, _ = json.Marshal(&abTagSnapshot{Id: before.Id, Name: before.Name, Color: before.Color})
+		r.UserId, r.CollectionId, r.EntityKey = before.UserId, before.CollectionId, strconv.Itoa(int(before.Id))
+		r.Before = custom_types.AutoJson(bj)
+	}
+	if after != nil {
+		aj, _ = json.Marshal(&abTagSnapshot{Id: after.Id, Name: after.Name, Color: after.Color})
+		r.UserId, r.CollectionId, r.EntityKey = after.UserId, after.CollectionId, strconv.Itoa(int(after.Id))
+		r.After = custom_types.AutoJson(aj)
+	}
+	if bj != nil && aj != nil && string(bj) == string(aj) {
+		return
+	}
+	s.create(tx, actor, r)
+}
+
+func (s *AddressBookHistoryService) create(tx *gorm.DB, actor *AbActor, r *model.AddressBookChange) {
+	if tx == nil {
+		tx = DB
+	}
+	switch {
+	case isNullJson(r.Before):
+		r.Action = model.AbChangeActionCreate
+	case isNullJson(r.After):
+		r.Action = model.AbChangeActionDelete
+	default:
+		r.Action = model.AbChangeActionUpdate
+	}
+	r.ActorId = actor.UserId
+	r.Source = actor.Source
+	r.ChangedAt = time.Now().Unix()
+	if err := tx.Create(r).Error; err != nil {
+		Logger.Error("Record address book change failed: ", err)
+	}
+	AllService.AddressBookRevisionService.Bump(tx, r.UserId, r.CollectionId)
+}
+
+// Restore 将地址簿(所有者 + 集合)中的条目和标签恢复到 ts 时的状态: 取 ts 之后每个条目的第一条变更, 其 Before 即 ts 时的状态.
+// 恢复本身也会记录为变更, 可以再次恢复
+func (s *AddressBookHistoryService) Restore(userId, cid uint, ts int64, actor *AbActor) (*model.AddressBookRestoreResult, error) {
+	res := &model.AddressBookRestoreResult{}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var changes []*model.AddressBookChange
+		tx.Where("user_id = ? and collection_id = ? and changed_at > ?", userId, cid, ts).Order("id asc").Find(&changes)
+		seen := make(map[string]bool)
+		for _, c := range changes {
+			k := c.Entity + ":" + c.EntityKey
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			var changed bool
+			var err error
+			if c.Entity == model.AbChangeEntityTag {
+				changed, err = s.restoreTag(tx, userId, cid, c, actor)
+				if changed {
+					res.Tags++
+				}
+			} else {
+				changed, err = s.restorePeer(tx, userId, cid, c, actor)
+				if changed {
+					res.Peers++
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return res, err
+}
+
+func (s *AddressBookHistoryService) restorePeer(tx *gorm.DB, userId, cid uint, c *model.AddressBookChange, actor *AbActor) (bool, error) {
+	target, err := decodePeerSnapshot(c.Before)
+	if err != nil {
+		return false, err
+	}
+	cur := &model.AddressBook{}
+	tx.Where("user_id = ? and collection_id = ? and id = ?", userId, cid, c.EntityKey).First(cur)
+	if target == nil {
+		if cur.RowId == 0 {
+			return false, nil
+		}
+		if err := tx.Delete(cur).Error; err != nil {
+			return false, err
+		}
+		if err := AllService.PeerShareService.DeleteByAbRowIds(tx, []uint{cur.RowId}); err != nil {
+			return false, err
+		}
+		s.RecordPeer(tx, actor, cur, nil)
+		return true, nil
+	}
+	// 快照中的文件夹已被删除时恢复到根目录
+	if target.FolderId > 0 && !AllService.AddressBookFolderService.Check(cid, target.FolderId) {
+		target.FolderId = 0
+	}
+	if cur.RowId == 0 {
+		ab := &model.AddressBook{UserId: userId, CollectionId: cid}
+		target.apply(ab)
+		if err := tx.Create(ab).Error; err != nil {
+			return false, err
+		}
+		s.RecordPeer(tx, actor, nil, ab)
+		return true, nil
+	}
+	before := *cur
+	target.apply(cur)
+	if newPeerSnapshot(&before).equal(newPeerSnapshot(cur)) {
+		return false, nil
+	}
+	if err := tx.Model(cur).Select("*").Omit("created_at").Updates(cur).Error; err != nil {
+		return false, err
+	}
+	s.RecordPeer(tx, actor, &before, cur)
+	return true, nil
+}
+
+func (p *abPeerSnapshot) equal(o *abPeerSnapshot) bool {
+	a, _ := json.Marshal(p)
+	b, _ := json.Marshal(o)
+	return string(a) == string(b)
+}
+
+func (s *AddressBookHistoryService) restoreTag(tx *gorm.DB, userId, cid uint, c *model.AddressBookChange, actor *AbActor) (bool, error) {
+	target, err := decodeTagSnapshot(c.Before)
+	if err != nil {
+		return false, err
+	}
+	id, _ := strconv.Atoi(c.EntityKey)
+	cur := &model.Tag{}
+	tx.Where("id = ? and user_id = ? and collection_id = ?", id, userId, cid).First(cur)
+	if target == nil {
+		if cur.Id == 0 {
+			return false, nil
+		}
+		if err := tx.Delete(cur).Error; err != nil {
+			return false, err
+		}
+		s.RecordTag(tx, actor, cur, nil)
+		return true, nil
+	}
+	if cur.Id == 0 {
+		t := &model.Tag{Name: target.Name, Color: target.Color, UserId: userId, CollectionId: cid}
+		// 原 id 没有被占用时沿用, 保持历史记录可以对应
+		var n int64
+		tx.Model(&model.Tag{}).Where("id = ?", target.Id).Count(&n)
+		if n == 0 {
+			t.Id = target.Id
+		}
+		if err := tx.Create(t).Error; err != nil {
+			return false, err
+		}
+		s.RecordTag(tx, actor, nil, t)
+		return true, nil
+	}
+	if cur.Name == target.Name && cur.Color == target.Color {
+		return false, nil
+	}
+	before := *cur
+	cur.Name = target.Name
+	cur.Color = target.Color
+	if err := tx.Model(cur).Select("name", "color").Updates(cur).Error; err != nil {
+		return false, err
+	}
+	s.RecordTag(tx, actor, &before, cur)
+	return true, nil
+}
+
+// Prune 删除超过 ab-history-days 天的记录, 为 0 时不删除
+func (s *AddressBookHistoryService) Prune() {
+	days := Config.App.AbHistoryDays
+	if days <= 0 {
+		return
+	}
+	DB.Where("changed_at < ?", time.Now().AddDate(0, 0, -days).Unix()).Delete(&model.AddressBookChange{})
+}
+
+// DeleteByUserId 删除用户时删除其地址簿的变更记录
+func (s *AddressBookHistoryService) DeleteByUserId(tx *gorm.DB, userId uint) error {
+	return tx.Where("user_id = ?", userId).Delete(&model.AddressBookChange{}).Error
+}
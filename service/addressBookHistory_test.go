@@ -0,0 +1,46 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+)
+
+func TestMaskSnapshotSecrets(t *testing.T) {
+	j := maskSnapshotSecrets(custom_types.AutoJson(`{"id":"1","password":"pw","hash":""}`))
+	m := map[string]interface{}{}
+	if err := json.Unmarshal(j, &m); err != nil {
+		t.Fatal(err)
+	}
+	if m["password"] != "******" || m["hash"] != "" || m["id"] != "1" {
+		t.Fatalf("masked = %s", j)
+	}
+	if s := string(maskSnapshotSecrets(custom_types.AutoJson("null"))); s != "null" {
+		t.Fatalf("null snapshot = %s", s)
+	}
+}
+
+func TestPeerSnapshotRoundTrip(t *testing.T) {
+	ab := &model.AddressBook{Id: "123", Alias: "srv", Password: "pw", Tags: custom_types.AutoJson(`["a"]`), FolderId: 3}
+	j, err := newPeerSnapshot(ab).encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := decodePeerSnapshot(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &model.AddressBook{}
+	p.apply(got)
+	if got.Id != "123" || got.Alias != "srv" || got.Password != "pw" || string(got.Tags) != `["a"]` || got.FolderId != 3 {
+		t.Fatalf("round trip = %+v", got)
+	}
+	if !newPeerSnapshot(ab).equal(newPeerSnapshot(got)) {
+		t.Fatal("snapshots differ")
+	}
+	if p, _ := decodePeerSnapshot(custom_types.AutoJson("null")); p != nil {
+		t.Fatal("null snapshot decoded")
+	}
+}
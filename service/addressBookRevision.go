@@ -0,0 +1,159 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+// ErrAbRevisionConflict 客户端提交时的版本号不是最新
+var ErrAbRevisionConflict = errors.New("AbRevisionConflict")
+
+type AddressBookRevisionService struct {
+}
+
+// Get 地址簿当前版本号, 没有任何变更时为 0
+func (s *AddressBookRevisionService) Get(userId, cid uint) uint64 {
+	r := &model.AddressBookRevision{}
+	DB.Where("user_id = ? and collection_id = ?", userId, cid).First(r)
+	return r.Revision
+}
+
+// Bump 版本号加一, 在记录地址簿变更时调用, tx 为 nil 时使用 DB
+func (s *AddressBookRevisionService) Bump(tx *gorm.DB, userId, cid uint) {
+	if tx == nil {
+		tx = DB
+	}
+	res := tx.Model(&model.AddressBookRevision{}).Where("user_id = ? and collection_id = ?", userId, cid).
+		UpdateColumn("revision", gorm.Expr("revision + 1"))
+	if res.Error == nil && res.RowsAffected > 0 {
+		return
+	}
+	if err := tx.Create(&model.AddressBookRevision{UserId: userId, CollectionId: cid, Revision: 1}).Error; err != nil {
+		Logger.Error("Bump address book revision failed: ", err)
+	}
+}
+
+// BumpAll 所有地址簿版本号加一, 用于全局标签这类影响每个地址簿的变更.
+// 还没有版本记录的地址簿(每个用户的默认地址簿和每个集合)同时创建记录, 否则客户端持有的 ETag "0" 仍然有效
+func (s *AddressBookRevisionService) BumpAll() {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.AddressBookRevision{}).Where("1 = 1").
+			UpdateColumn("revision", gorm.Expr("revision + 1")).Error; err != nil {
+			return err
+		}
+		var revs []*model.AddressBookRevision
+		if err := tx.Select("user_id, collection_id").Find(&revs).Error; err != nil {
+			return err
+		}
+		exists := make(map[[2]uint]bool, len(revs))
+		for _, r := range revs {
+			exists[[2]uint{r.UserId, r.CollectionId}] = true
+		}
+		var userIds []uint
+		if err := tx.Model(&model.User{}).Pluck("id", &userIds).Error; err != nil {
+			return err
+		}
+		var cols []*model.AddressBookCollection
+		if err := tx.Select("id, user_id").Find(&cols).Error; err != nil {
+			return err
+		}
+		books := make([][2]uint, 0, len(userIds)+len(cols))
+		for _, uid := range userIds {
+			books = append(books, [2]uint{uid, 0})
+		}
+		for _, c := range cols {
+			books = append(books, [2]uint{c.UserId, c.Id})
+		}
+		missing := make([]*model.AddressBookRevision, 0)
+		for _, b := range books {
+			if !exists[b] {
+				missing = append(missing, &model.AddressBookRevision{UserId: b[0], CollectionId: b[1], Revision: 1})
+			}
+		}
+		if len(missing) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(missing, 500).Error
+	})
+	if err != nil {
+		Logger.Error("Bump address book revisions failed: ", err)
+	}
+}
+
+// ETag 版本号转为 ETag 头的值
+func (s *AddressBookRevisionService) ETag(rev uint64) string {
+	return fmt.Sprintf(`"%d"`, rev)
+}
+
+// MembersETag 动态集合的条目在读取时计算, 成员变化不经过变更记录, ETag 中加上成员的摘要
+func (s *AddressBookRevisionService) MembersETag(rev uint64, abs []*model.AddressBook) string {
+	h := fnv.New32a()
+	for _, ab := range abs {
+		h.Write([]byte(ab.Id))
+		h.Write([]byte{0})
+	}
+	return fmt.Sprintf(`"%d-%08x"`, rev, h.Sum32())
+}
+
+// ParseRevision 解析 If-Match 的值, 接受 "3"、W/"3" 和 3; 为空或 * 时返回 false, 表示不检查
+func (s *AddressBookRevisionService) ParseRevision(v string) (uint64, bool, error) {
+	v = strings.TrimSpace(v)
+	if v == "" || v == "*" {
+		return 0, false, nil
+	}
+	v = strings.Trim(strings.TrimPrefix(v, "W/"), `"`)
+	rev, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return rev, true, nil
+}
+
+// Guard 检查版本号后执行 fn; ifMatch 为空时不检查, 兼容不支持的客户端. 返回执行后的版本号.
+// 检查时用条件更新 revision = ifMatch 原子地占用下一个版本号, 多个实例同时提交同一版本时只有一个成功
+func (s *AddressBookRevisionService) Guard(userId, cid uint, ifMatch string, fn func() error) (uint64, error) {
+	expected, check, err := s.ParseRevision(ifMatch)
+	if err != nil {
+		return 0, err
+	}
+	if check {
+		if err := s.claim(userId, cid, expected); err != nil {
+			return 0, err
+		}
+	}
+	if err := fn(); err != nil {
+		return 0, err
+	}
+	return s.Get(userId, cid), nil
+}
+
+// claim 版本号为 expected 时加一, 否则返回 ErrAbRevisionConflict
+func (s *AddressBookRevisionService) claim(userId, cid uint, expected uint64) error {
+	res := DB.Model(&model.AddressBookRevision{}).Where("user_id = ? and collection_id = ? and revision = ?", userId, cid, expected).
+		UpdateColumn("revision", gorm.Expr("revision + 1"))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	if expected != 0 {
+		return ErrAbRevisionConflict
+	}
+	// 还没有版本记录, 唯一索引保证同时创建时只有一个成功
+	if err := DB.Create(&model.AddressBookRevision{UserId: userId, CollectionId: cid, Revision: 1}).Error; err != nil {
+		return ErrAbRevisionConflict
+	}
+	return nil
+}
+
+// DeleteByUserId 删除用户时清理
+func (s *AddressBookRevisionService) DeleteByUserId(tx *gorm.DB, userId uint) error {
+	return tx.Where("user_id = ?", userId).Delete(&model.AddressBookRevision{}).Error
+}
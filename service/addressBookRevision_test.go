@@ -0,0 +1,97 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestParseRevision(t *testing.T) {
+	s := &AddressBookRevisionService{}
+	cases := []struct {
+		in    string
+		rev   uint64
+		check bool
+		err   bool
+	}{
+		{"", 0, false, false},
+		{"*", 0, false, false},
+		{`"3"`, 3, true, false},
+		{`W/"4"`, 4, true, false},
+		{"5", 5, true, false},
+		{`"x"`, 0, false, true},
+	}
+	for _, c := range cases {
+		rev, check, err := s.ParseRevision(c.in)
+		if rev != c.rev || check != c.check || (err != nil) != c.err {
+			t.Errorf("ParseRevision(%q) = %d, %v, %v", c.in, rev, check, err)
+		}
+	}
+	if e := s.ETag(7); e != `"7"` {
+		t.Errorf("ETag = %s", e)
+	}
+}
+
+func TestGuardConflict(t *testing.T) {
+	newTestDB(t, &model.AddressBookRevision{})
+	s := &AddressBookRevisionService{}
+	calls := 0
+	fn := func() error {
+		calls++
+		return nil
+	}
+	// 还没有版本记录时版本号为 0
+	rev, err := s.Guard(1, 0, `"0"`, fn)
+	if err != nil || rev != 1 {
+		t.Fatalf("first write = %d, %v", rev, err)
+	}
+	// 另一个客户端仍然持有版本 0
+	if _, err := s.Guard(1, 0, `"0"`, fn); !errors.Is(err, ErrAbRevisionConflict) {
+		t.Fatalf("stale write err = %v, want conflict", err)
+	}
+	if calls != 1 {
+		t.Fatalf("fn called %d times, want 1", calls)
+	}
+	rev, err = s.Guard(1, 0, `"1"`, fn)
+	if err != nil || rev != 2 {
+		t.Fatalf("write with current revision = %d, %v", rev, err)
+	}
+	// 不带 If-Match 的客户端不检查
+	if _, err := s.Guard(1, 0, "", fn); err != nil || calls != 3 {
+		t.Fatalf("unchecked write = %v, %d calls", err, calls)
+	}
+}
+
+func TestMembersETag(t *testing.T) {
+	s := &AddressBookRevisionService{}
+	a := s.MembersETag(3, []*model.AddressBook{{Id: "1"}, {Id: "2"}})
+	b := s.MembersETag(3, []*model.AddressBook{{Id: "1"}})
+	if a == b || !strings.HasPrefix(a, `"3-`) {
+		t.Errorf("etags %s and %s", a, b)
+	}
+}
+
+func TestBumpAllCreatesMissing(t *testing.T) {
+	newTestDB(t, &model.AddressBookRevision{}, &model.User{}, &model.AddressBookCollection{})
+	u1 := &model.User{Username: "alice"}
+	u2 := &model.User{Username: "bob"}
+	DB.Create(u1)
+	DB.Create(u2)
+	col := &model.AddressBookCollection{UserId: u2.Id, Name: "c"}
+	DB.Create(col)
+	DB.Create(&model.AddressBookRevision{UserId: u1.Id, Revision: 4})
+	s := AllService.AddressBookRevisionService
+	s.BumpAll()
+	// 已有记录加一, 没有记录的地址簿从 0 变为 1
+	if r := s.Get(u1.Id, 0); r != 5 {
+		t.Errorf("existing revision = %d, want 5", r)
+	}
+	if r := s.Get(u2.Id, 0); r != 1 {
+		t.Errorf("personal book revision = %d, want 1", r)
+	}
+	if r := s.Get(u2.Id, col.Id); r != 1 {
+		t.Errorf("collection revision = %d, want 1", r)
+	}
+}
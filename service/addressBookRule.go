@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	AbRuleExpireActionDisable = "disable"
+	AbRuleExpireActionDelete  = "delete"
+)
+
+// activeRule 只取当前生效的共享规则: 未停用且在 start_at ~ end_at 之间
+func activeRule(now int64) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("status = ? and start_at <= ? and (end_at = 0 or end_at > ?)", model.COMMON_STATUS_ENABLE, now, now)
+	}
+}
+
+// RuleWindowValid 检查规则有效期: 到期时间必须晚于生效时间和当前时间. 修改规则时只在有效期变化时检查
+func (s *AddressBookService) RuleWindowValid(t *model.AddressBookCollectionRule) bool {
+	if t.EndAt == 0 {
+		return true
+	}
+	if t.Id > 0 {
+		ex := s.RuleInfoById(t.Id)
+		if ex.Id > 0 && ex.StartAt == t.StartAt && ex.EndAt == t.EndAt {
+			return true
+		}
+	}
+	return t.EndAt > t.StartAt && t.EndAt > time.Now().Unix()
+}
+
+// ExpireRules 处理已到期的共享规则, 按 ab-rule-expire-action 停用或删除, 每条规则记录审计
+func (s *AddressBookService) ExpireRules() {
+	var rules []*model.AddressBookCollectionRule
+	DB.Where("status = ? and end_at > 0 and end_at <= ?", model.COMMON_STATUS_ENABLE, time.Now().Unix()).Find(&rules)
+	action := AbRuleExpireActionDisable
+	if Config.App.AbRuleExpireAction == AbRuleExpireActionDelete {
+		action = AbRuleExpireActionDelete
+	}
+	n := 0
+	for _, r := range rules {
+		var err error
+		if action == AbRuleExpireActionDelete {
+			err = DB.Delete(r).Error
+		} else {
+			err = DB.Model(r).UpdateColumns(map[string]interface{}{"status": model.COMMON_STATUS_DISABLED, "expired_at": time.Now().Unix()}).Error
+		}
+		if err != nil {
+			Logger.Error("Expire address book rule ", r.Id, " failed: ", err)
+			continue
+		}
+		audit.LogShareRuleExpired(r.Id, r.UserId, r.CollectionId, r.Type, r.ToId, action+"d")
+		n++
+	}
+	if n > 0 {
+		Logger.Info("Expired ", n, " address book sharing rules")
+	}
+}
+
+// NotifyExpiringRules 在到期前 ab-rule-notify-before 通知集合所有者, 每条规则只通知一次
+func (s *AddressBookService) NotifyExpiringRules() {
+	before := Config.App.AbRuleNotifyBefore
+	if before <= 0 {
+		return
+	}
+	now := time.Now().Unix()
+	var rules []*model.AddressBookCollectionRule
+	DB.Where("status = ? and notified_at = 0 and end_at > ? and end_at <= ?", model.COMMON_STATUS_ENABLE, now, now+int64(before.Seconds())).
+		Find(&rules)
+	for _, r := range rules {
+		col := s.CollectionInfoById(r.CollectionId)
+		target := ""
+		if r.Type == model.ShareAddressBookRuleTypeGroup {
+			target = "group " + AllService.GroupService.InfoById(r.ToId).Name
+		} else {
+			target = "user " + AllService.UserService.InfoById(r.ToId).Username
+		}
+		content := fmt.Sprintf("Sharing of address book %s with %s expires at %s.", col.Name, target,
+			time.Unix(r.EndAt, 0).Format(time.RFC3339))
+		err := AllService.NotificationService.Notify(r.UserId, model.NotificationTypeAbRuleExpiring, "Address book sharing expires soon", content, map[string]interface{}{
+			"rule_id":       r.Id,
+			"collection_id": r.CollectionId,
+			"type":          r.Type,
+			"to_id":         r.ToId,
+			"end_at":        r.EndAt,
+		})
+		if err != nil {
+			Logger.Error("Notify address book rule expiry failed: ", err)
+			continue
+		}
+		DB.Model(r).UpdateColumn("notified_at", now)
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestRuleWindowValid(t *testing.T) {
+	s := &AddressBookService{}
+	now := time.Now().Unix()
+	cases := []struct {
+		start, end int64
+		want       bool
+	}{
+		{0, 0, true},
+		{now + 60, 0, true},
+		{0, now + 60, true},
+		{now + 60, now + 120, true},
+		{now + 120, now + 60, false},
+		{0, now - 60, false},
+	}
+	for _, c := range cases {
+		r := &model.AddressBookCollectionRule{StartAt: c.start, EndAt: c.end}
+		if got := s.RuleWindowValid(r); got != c.want {
+			t.Errorf("RuleWindowValid(%d, %d) = %v, want %v", c.start, c.end, got, c.want)
+		}
+	}
+}
+
+func TestUpdateRuleExpiredStatus(t *testing.T) {
+	newTestDB(t, &model.AddressBookCollectionRule{})
+	s := AllService.AddressBookService
+	now := time.Now().Unix()
+	expired := &model.AddressBookCollectionRule{UserId: 1, CollectionId: 1, Rule: 1, Type: 1, ToId: 2, EndAt: now - 60}
+	manual := &model.AddressBookCollectionRule{UserId: 1, CollectionId: 1, Rule: 1, Type: 1, ToId: 3, EndAt: now - 60}
+	DB.Create(expired)
+	DB.Create(manual)
+	// 一条由到期任务停用, 一条手动停用
+	DB.Model(expired).UpdateColumns(map[string]interface{}{"status": model.COMMON_STATUS_DISABLED, "expired_at": now})
+	DB.Model(manual).Update("status", model.COMMON_STATUS_DISABLED)
+	// 修改其他字段时不检查已过去的到期时间
+	if !s.RuleWindowValid(&model.AddressBookCollectionRule{IdModel: model.IdModel{Id: manual.Id}, EndAt: now - 60}) {
+		t.Error("unchanged window rejected")
+	}
+	for _, r := range []*model.AddressBookCollectionRule{expired, manual} {
+		if err := s.UpdateRule(&model.AddressBookCollectionRule{IdModel: model.IdModel{Id: r.Id}, EndAt: now + 3600}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r := s.RuleInfoById(expired.Id); r.Status != model.COMMON_STATUS_ENABLE || r.ExpiredAt != 0 {
+		t.Errorf("expired rule status %d, expired_at %d", r.Status, r.ExpiredAt)
+	}
+	if r := s.RuleInfoById(manual.Id); r.Status != model.COMMON_STATUS_DISABLED {
+		t.Errorf("manually disabled rule re-enabled")
+	}
+}
@@ -0,0 +1,434 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+const (
+	// AbCsvTagSeparator CSV 中 tags 列的分隔符
+	AbCsvTagSeparator = ";"
+
+	abCsvTypePeer = "peer"
+	abCsvTypeTag  = "tag"
+)
+
+// AbCsvColumns 地址簿 CSV 列; type 为 peer 或 tag, tag 行只使用 tags(标签名)和 tag_color 列.
+// 没有 type 列时所有行都是设备
+var AbCsvColumns = []string{"type", "id", "alias", "hostname", "username", "platform", "tags", "tag_color",
+	"forceAlwaysRelay", "rdpPort", "rdpUsername", "loginName", "sameServer", "hash", "password"}
+
+var errAbImportPreview = errors.New("address book import preview")
+
+// abImportColumns 导入覆盖时可更新的字段, key 为 JSON 字段名(与 CSV 列名相同)
+var abImportColumns = map[string]string{
+	"username":         "username",
+	"password":         "password",
+	"hostname":         "hostname",
+	"alias":            "alias",
+	"platform":         "platform",
+	"tags":             "tags",
+	"hash":             "hash",
+	"forceAlwaysRelay": "force_always_relay",
+	"rdpPort":          "rdp_port",
+	"rdpUsername":      "rdp_username",
+	"loginName":        "login_name",
+	"sameServer":       "same_server",
+}
+
+// AbTransferData 与 /api/ab 的 AbList 结构相同, tag_colors 为 JSON 字符串
+type AbTransferData struct {
+	Peers     []*model.AddressBook `json:"peers"`
+	Tags      []string             `json:"tags"`
+	TagColors string               `json:"tag_colors"`
+	// fields 每个设备在文件中出现的字段, 为 nil 时视为全部出现
+	fields []map[string]bool
+}
+
+// peerFields 第 i 个设备在文件中出现的字段
+func (d *AbTransferData) peerFields(i int) map[string]bool {
+	if i < len(d.fields) && d.fields[i] != nil {
+		return d.fields[i]
+	}
+	all := make(map[string]bool, len(abImportColumns))
+	for k := range abImportColumns {
+		all[k] = true
+	}
+	return all
+}
+
+// tagColors 解析 tag_colors, 并把 tags 中没有颜色的标签补为 0; given 为文件中给出颜色的标签
+func (d *AbTransferData) tagColors() (colors map[string]uint, given map[string]bool, err error) {
+	colors = map[string]uint{}
+	if d.TagColors != "" {
+		if err := json.Unmarshal([]byte(d.TagColors), &colors); err != nil {
+			return nil, nil, err
+		}
+	}
+	given = make(map[string]bool, len(colors))
+	for t := range colors {
+		given[t] = true
+	}
+	for _, t := range d.Tags {
+		if _, ok := colors[t]; !ok {
+			colors[t] = 0
+		}
+	}
+	return colors, given, nil
+}
+
+// Export 导出地址簿(所有者 + 集合), includeSecrets 为 false 时不导出密码和 hash
+func (s *AddressBookService) Export(userId, cid uint, includeSecrets bool) *AbTransferData {
+	var abs []*model.AddressBook
+	if c := s.CollectionInfoById(cid); cid > 0 && c.IsDynamic() {
+		// 动态集合导出当前匹配的设备
+		if al, err := s.DynamicPeers(c, 1, DynamicAbMaxPeers); err == nil {
+			abs = al.AddressBooks
+		}
+	} else {
+		DB.Where("user_id = ? and collection_id = ?", userId, cid).Order("row_id asc").Find(&abs)
+	}
+	tags := AllService.TagService.ListByUserIdAndCollectionId(userId, cid)
+	res := &AbTransferData{Peers: make([]*model.AddressBook, 0, len(abs)), Tags: make([]string, 0, len(tags.Tags))}
+	colors := map[string]uint{}
+	for _, t := range tags.Tags {
+		res.Tags = append(res.Tags, t.Name)
+		colors[t.Name] = t.Color
+	}
+	tc, _ := json.Marshal(colors)
+	res.TagColors = string(tc)
+	for _, ab := range abs {
+		// 只保留可迁移的内容
+		p := &model.AddressBook{}
+		newPeerSnapshot(ab).apply(p)
+		// 文件夹只在本集合内有效
+		p.FolderId = 0
+		if !includeSecrets {
+			p.Password = ""
+			p.Hash = ""
+		}
+		res.Peers = append(res.Peers, p)
+	}
+	return res
+}
+
+// WriteAbCSV 写出 CSV, 先写标签行再写设备行
+func WriteAbCSV(w io.Writer, d *AbTransferData) error {
+	colors, _, err := d.tagColors()
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write(AbCsvColumns)
+	names := make([]string, 0, len(colors))
+	for n := range colors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		rec := make([]string, len(AbCsvColumns))
+		rec[0], rec[6], rec[7] = abCsvTypeTag, n, strconv.FormatUint(uint64(colors[n]), 10)
+		_ = cw.Write(rec)
+	}
+	for _, p := range d.Peers {
+		var tags []string
+		_ = json.Unmarshal(p.Tags, &tags)
+		_ = cw.Write([]string{abCsvTypePeer, p.Id, p.Alias, p.Hostname, p.Username, p.Platform,
+			strings.Join(tags, AbCsvTagSeparator), "", strconv.FormatBool(p.ForceAlwaysRelay), p.RdpPort, p.RdpUsername,
+			p.LoginName, strconv.FormatBool(p.SameServer), p.Hash, p.Password})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ReadAbCSV 读取 WriteAbCSV 格式的 CSV, 列按表头匹配, 可以缺少列
+func ReadAbCSV(r io.Reader) (*AbTransferData, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	idx := make(map[string]int)
+	for i, col := range header {
+		idx[strings.TrimSpace(col)] = i
+	}
+	d := &AbTransferData{}
+	colors := map[string]uint{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		get := func(col string) string {
+			if i, ok := idx[col]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		if get("type") == abCsvTypeTag {
+			if name := get("tags"); name != "" {
+				// 没有颜色的标签行只定义标签, 不覆盖已有颜色
+				if c := get("tag_color"); c != "" {
+					color, _ := strconv.ParseUint(c, 10, 32)
+					colors[name] = uint(color)
+				}
+				d.Tags = append(d.Tags, name)
+			}
+			continue
+		}
+		tags := make([]string, 0)
+		for _, t := range strings.Split(get("tags"), AbCsvTagSeparator) {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+		fields := map[string]bool{}
+		for col := range abImportColumns {
+			if i, ok := idx[col]; ok && i < len(record) {
+				fields[col] = true
+			}
+		}
+		d.fields = append(d.fields, fields)
+		tj, _ := json.Marshal(tags)
+		relay, _ := strconv.ParseBool(get("forceAlwaysRelay"))
+		same, _ := strconv.ParseBool(get("sameServer"))
+		d.Peers = append(d.Peers, &model.AddressBook{
+			Id:               get("id"),
+			Alias:            get("alias"),
+			Hostname:         get("hostname"),
+			Username:         get("username"),
+			Platform:         get("platform"),
+			Tags:             custom_types.AutoJson(tj),
+			ForceAlwaysRelay: relay,
+			RdpPort:          get("rdpPort"),
+			RdpUsername:      get("rdpUsername"),
+			LoginName:        get("loginName"),
+			SameServer:       same,
+			Hash:             get("hash"),
+			Password:         get("password"),
+		})
+	}
+	tc, _ := json.Marshal(colors)
+	d.TagColors = string(tc)
+	return d, nil
+}
+
+// ReadAbJSON 读取 AbList 结构, 也接受 GET /api/ab 的返回 {"data": "<AbList JSON>"}
+func ReadAbJSON(r io.Reader) (*AbTransferData, error) {
+	raw, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := struct {
+		Data *string `json:"data"`
+	}{}
+	if err := json.Unmarshal(raw, &wrapped); err == nil && wrapped.Data != nil {
+		raw = []byte(*wrapped.Data)
+	}
+	d := &AbTransferData{}
+	if err := json.Unmarshal(raw, d); err != nil {
+		return nil, err
+	}
+	present := struct {
+		Peers []map[string]json.RawMessage `json:"peers"`
+	}{}
+	if err := json.Unmarshal(raw, &present); err != nil {
+		return nil, err
+	}
+	for _, p := range present.Peers {
+		fields := map[string]bool{}
+		for k := range p {
+			if _, ok := abImportColumns[k]; ok {
+				fields[k] = true
+			}
+		}
+		d.fields = append(d.fields, fields)
+	}
+	return d, nil
+}
+
+// Import 导入到地址簿(所有者 + 集合), 在一个事务中完成; dryRun 时执行后回滚, 结果与实际导入一致
+func (s *AddressBookService) Import(userId, cid uint, d *AbTransferData, strategy string, dryRun bool, actor *AbActor) (*model.AbImportReport, error) {
+	if !s.CheckCollectionWritable(cid) {
+		return nil, ErrAbCollectionDynamic
+	}
+	colors, given, err := d.tagColors()
+	if err != nil {
+		return nil, err
+	}
+	report := &model.AbImportReport{DryRun: dryRun, Strategy: strategy, Total: len(d.Peers), Errors: make([]*model.PeerImportRowError, 0)}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		// 设备上使用但没有定义的标签也要创建
+		for _, p := range d.Peers {
+			var tags []string
+			_ = json.Unmarshal(p.Tags, &tags)
+			for _, t := range tags {
+				if _, ok := colors[t]; !ok {
+					colors[t] = 0
+				}
+			}
+		}
+		// 只允许全局标签的集合不创建集合标签
+		globalOnly := AllService.GlobalTagService.globalOnly(cid)
+		if !globalOnly {
+			if err := importAbTags(tx, userId, cid, colors, given, strategy, actor, report); err != nil {
+				return err
+			}
+		}
+		checkTags := AllService.GlobalTagService.TagChecker(cid)
+		for i, p := range d.Peers {
+			row := &model.PeerImportRowError{Row: i + 1, Id: strings.TrimSpace(p.Id)}
+			if row.Id == "" {
+				row.Error = "id is required"
+				report.Errors = append(report.Errors, row)
+				report.Failed++
+				continue
+			}
+			in := &model.AddressBook{}
+			newPeerSnapshot(p).apply(in)
+			in.FolderId = 0
+			in.Id = row.Id
+			in.UserId = userId
+			in.CollectionId = cid
+			var tags []string
+			_ = json.Unmarshal(in.Tags, &tags)
+			if !checkTags(tags) {
+				row.Error = "tags must be global tags in this collection"
+				report.Errors = append(report.Errors, row)
+				report.Failed++
+				continue
+			}
+			ex := &model.AddressBook{}
+			tx.Where("user_id = ? and collection_id = ? and id = ?", userId, cid, in.Id).First(ex)
+			switch {
+			case ex.RowId == 0 || strategy == model.AbImportStrategyDuplicate:
+				if err := tx.Create(in).Error; err != nil {
+					return err
+				}
+				AllService.AddressBookHistoryService.RecordPeer(tx, actor, nil, in)
+				report.Created++
+			case strategy == model.AbImportStrategyOverwrite:
+				up, cols := overwriteAbPeer(ex, in, d.peerFields(i))
+				if len(cols) > 0 {
+					if err := tx.Model(up).Select(cols).Updates(up).Error; err != nil {
+						return err
+					}
+				}
+				AllService.AddressBookHistoryService.RecordPeer(tx, actor, ex, up)
+				report.Updated++
+			default:
+				report.Skipped++
+			}
+		}
+		if dryRun {
+			return errAbImportPreview
+		}
+		return nil
+	})
+	if errors.Is(err, errAbImportPreview) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// overwriteAbPeer 用导入的内容覆盖已有条目: 只更新文件中出现的字段, 文件中密码和 hash 为空时保留原值,
+// 所属文件夹等不在文件中的信息不变
+func overwriteAbPeer(ex, in *model.AddressBook, fields map[string]bool) (*model.AddressBook, []string) {
+	up := *ex
+	cols := make([]string, 0, len(fields))
+	overwriteAbField(fields, &cols, "username", &up.Username, in.Username)
+	overwriteAbField(fields, &cols, "hostname", &up.Hostname, in.Hostname)
+	overwriteAbField(fields, &cols, "alias", &up.Alias, in.Alias)
+	overwriteAbField(fields, &cols, "platform", &up.Platform, in.Platform)
+	overwriteAbField(fields, &cols, "tags", &up.Tags, in.Tags)
+	overwriteAbField(fields, &cols, "forceAlwaysRelay", &up.ForceAlwaysRelay, in.ForceAlwaysRelay)
+	overwriteAbField(fields, &cols, "rdpPort", &up.RdpPort, in.RdpPort)
+	overwriteAbField(fields, &cols, "rdpUsername", &up.RdpUsername, in.RdpUsername)
+	overwriteAbField(fields, &cols, "loginName", &up.LoginName, in.LoginName)
+	overwriteAbField(fields, &cols, "sameServer", &up.SameServer, in.SameServer)
+	if in.Password != "" {
+		overwriteAbField(fields, &cols, "password", &up.Password, in.Password)
+	}
+	if in.Hash != "" {
+		overwriteAbField(fields, &cols, "hash", &up.Hash, in.Hash)
+	}
+	return &up, cols
+}
+
+func overwriteAbField[T any](fields map[string]bool, cols *[]string, key string, dst *T, v T) {
+	if !fields[key] {
+		return
+	}
+	*dst = v
+	*cols = append(*cols, abImportColumns[key])
+}
+
+func importAbTags(tx *gorm.DB, userId, cid uint, colors map[string]uint, given map[string]bool, strategy string, actor *AbActor, report *model.AbImportReport) error {
+	names := make([]string, 0, len(colors))
+	for n := range colors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		ex := &model.Tag{}
+		tx.Where("user_id = ? and collection_id = ? and name = ?", userId, cid, name).First(ex)
+		if ex.Id == 0 {
+			t := &model.Tag{Name: name, Color: colors[name], UserId: userId, CollectionId: cid}
+			if err := tx.Create(t).Error; err != nil {
+				return err
+			}
+			AllService.AddressBookHistoryService.RecordTag(tx, actor, nil, t)
+			report.TagsCreated++
+			continue
+		}
+		// 标签不重复创建, 只有覆盖且文件中给出颜色时才更新颜色
+		if strategy == model.AbImportStrategyOverwrite && given[name] && ex.Color != colors[name] {
+			before := *ex
+			ex.Color = colors[name]
+			if err := tx.Model(ex).Update("color", ex.Color).Error; err != nil {
+				return err
+			}
+			AllService.AddressBookHistoryService.RecordTag(tx, actor, &before, ex)
+			report.TagsUpdated++
+		}
+	}
+	return nil
+}
+
+// ReadAbTransfer 按格式读取导入文件
+func ReadAbTransfer(format string, r io.Reader) (*AbTransferData, error) {
+	switch format {
+	case PeerTransferFormatCsv:
+		return ReadAbCSV(r)
+	case PeerTransferFormatJson:
+		return ReadAbJSON(r)
+	}
+	return nil, errors.New("unknown format")
+}
+
+// WriteAbTransfer 按格式写出导出内容
+func WriteAbTransfer(format string, w io.Writer, d *AbTransferData) error {
+	if format == PeerTransferFormatCsv {
+		return WriteAbCSV(w, d)
+	}
+	return json.NewEncoder(w).Encode(d)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+)
+
+func TestAbCSVRoundTrip(t *testing.T) {
+	in := &AbTransferData{
+		Peers: []*model.AddressBook{
+			{Id: "123", Alias: "srv, prod", Tags: custom_types.AutoJson(`["a","b"]`), ForceAlwaysRelay: true},
+		},
+		Tags:      []string{"a", "b"},
+		TagColors: `{"a":4278190335}`,
+	}
+	buf := &bytes.Buffer{}
+	if err := WriteAbCSV(buf, in); err != nil {
+		t.Fatal(err)
+	}
+	out, err := ReadAbCSV(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Peers) != 1 || len(out.Tags) != 2 {
+		t.Fatalf("read %d peers, %d tags", len(out.Peers), len(out.Tags))
+	}
+	p := out.Peers[0]
+	if p.Id != "123" || p.Alias != "srv, prod" || string(p.Tags) != `["a","b"]` || !p.ForceAlwaysRelay {
+		t.Fatalf("peer = %+v", p)
+	}
+	colors, _, err := out.tagColors()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if colors["a"] != 4278190335 || colors["b"] != 0 {
+		t.Fatalf("colors = %v", colors)
+	}
+}
+
+func TestReadAbCSVWithoutType(t *testing.T) {
+	d, err := ReadAbCSV(strings.NewReader("\ufeffid,alias,tags\n1,one,x;y\n2,two,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Peers) != 2 || d.Peers[0].Alias != "one" || string(d.Peers[0].Tags) != `["x","y"]` || string(d.Peers[1].Tags) != `[]` {
+		t.Fatalf("peers = %+v %+v", d.Peers[0], d.Peers[1])
+	}
+}
+
+func TestReadAbJSON(t *testing.T) {
+	raw := `{"peers":[{"id":"1","alias":"a"}],"tags":["t"],"tag_colors":"{\"t\":1}"}`
+	wrapped, _ := json.Marshal(map[string]string{"data": raw})
+	for _, s := range []string{raw, string(wrapped)} {
+		d, err := ReadAbJSON(strings.NewReader(s))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Peers) != 1 || d.Peers[0].Id != "1" || len(d.Tags) != 1 || d.TagColors != `{"t":1}` {
+			t.Fatalf("data = %+v", d)
+		}
+	}
+}
+
+func TestImportOverwriteKeepsMissingFields(t *testing.T) {
+	db := newTestDB(t, &model.AddressBook{}, &model.Tag{}, &model.AddressBookChange{}, &model.AddressBookCollection{})
+	ex := &model.AddressBook{Id: "1", UserId: 1, Alias: "old", Hostname: "host", Password: "pw", Hash: "h",
+		Tags: custom_types.AutoJson(`["a"]`), FolderId: 7}
+	if err := db.Create(ex).Error; err != nil {
+		t.Fatal(err)
+	}
+	s := &AddressBookService{}
+	// 没有 hostname 列, 密码为空(导出时未包含密码)
+	d, err := ReadAbCSV(strings.NewReader("id,alias,tags,password,hash\n1,new,b,,\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.Import(1, 0, d, model.AbImportStrategyOverwrite, false, &AbActor{UserId: 1})
+	if err != nil || report.Updated != 1 {
+		t.Fatalf("report = %+v %v", report, err)
+	}
+	got := &model.AddressBook{}
+	db.Where("row_id = ?", ex.RowId).First(got)
+	if got.Alias != "new" || string(got.Tags) != `["b"]` || got.Hostname != "host" ||
+		got.Password != "pw" || got.Hash != "h" || got.FolderId != 7 {
+		t.Fatalf("overwritten = %+v", got)
+	}
+
+	d, err = ReadAbJSON(strings.NewReader(`{"peers":[{"id":"1","hostname":"","password":"pw2"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Import(1, 0, d, model.AbImportStrategyOverwrite, false, &AbActor{UserId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	got = &model.AddressBook{}
+	db.Where("row_id = ?", ex.RowId).First(got)
+	if got.Alias != "new" || got.Hostname != "" || got.Password != "pw2" || got.Hash != "h" || got.FolderId != 7 {
+		t.Fatalf("overwritten from json = %+v", got)
+	}
+}
+
+func TestImportOverwriteKeepsTagColors(t *testing.T) {
+	db := newTestDB(t, &model.AddressBook{}, &model.Tag{}, &model.AddressBookChange{}, &model.AddressBookCollection{})
+	db.Create(&model.Tag{Name: "a", Color: 5, UserId: 1})
+	db.Create(&model.Tag{Name: "b", Color: 6, UserId: 1})
+	s := &AddressBookService{}
+	// CSV 没有标签行, 标签 a 只出现在设备上; JSON 只给出 b 的颜色
+	d, err := ReadAbCSV(strings.NewReader("id,tags\n1,a\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Import(1, 0, d, model.AbImportStrategyOverwrite, false, &AbActor{UserId: 1}); err != nil {
+		t.Fatal(err)
+	}
+	d, err = ReadAbJSON(strings.NewReader(`{"peers":[{"id":"2","tags":["a","b"]}],"tags":["a","b"],"tag_colors":"{\"b\":9}"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := s.Import(1, 0, d, model.AbImportStrategyOverwrite, false, &AbActor{UserId: 1})
+	if err != nil || report.TagsUpdated != 1 {
+		t.Fatalf("report = %+v %v", report, err)
+	}
+	colors := map[string]uint{}
+	var tags []*model.Tag
+	db.Find(&tags)
+	for _, tag := range tags {
+		colors[tag.Name] = tag.Color
+	}
+	if colors["a"] != 5 || colors["b"] != 9 {
+		t.Fatalf("colors = %v, want a kept and b overwritten", colors)
+	}
+}
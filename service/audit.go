@@ -24,6 +24,7 @@ func (as *AuditService) AuditConnList(page, pageSize uint, where func(tx *gorm.D
 
 // Create 创建
 func (as *AuditService) CreateAuditConn(u *model.AuditConn) error {
+	AllService.GeoipService.Fill(&u.GeoInfo, u.Ip)
 	res := DB.Create(u).Error
 	return res
 }
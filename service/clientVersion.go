@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+	"gorm.io/gorm"
+)
+
+type ClientVersionService struct {
+}
+
+func (s *ClientVersionService) PolicyInfoById(id uint) *model.ClientVersionPolicy {
+	r := &model.ClientVersionPolicy{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+// Policies 所有平台的版本策略
+func (s *ClientVersionService) Policies() (res []*model.ClientVersionPolicy) {
+	DB.Order("platform asc").Find(&res)
+	return
+}
+
+// PolicyMap platform => policy
+func (s *ClientVersionService) PolicyMap() map[string]*model.ClientVersionPolicy {
+	m := make(map[string]*model.ClientVersionPolicy)
+	for _, p := range s.Policies() {
+		m[p.Platform] = p
+	}
+	return m
+}
+
+// ValidatePolicy 校验平台, 至少配置一个版本, 且推荐版本不低于最低版本
+func (s *ClientVersionService) ValidatePolicy(p *model.ClientVersionPolicy) error {
+	known := false
+	for _, pl := range utils.Platforms {
+		if pl == p.Platform {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return errors.New("unknown platform: " + p.Platform)
+	}
+	if p.MinVersion == "" && p.RecommendedVersion == "" {
+		return errors.New("min_version or recommended_version is required")
+	}
+	if p.MinVersion != "" && p.RecommendedVersion != "" && utils.CompareVersion(p.RecommendedVersion, p.MinVersion) < 0 {
+		return errors.New("recommended_version is lower than min_version")
+	}
+	return nil
+}
+
+// SavePolicy 按平台新增或更新
+func (s *ClientVersionService) SavePolicy(p *model.ClientVersionPolicy) error {
+	ex := &model.ClientVersionPolicy{}
+	DB.Where("platform = ?", p.Platform).First(ex)
+	if ex.Id == 0 {
+		return DB.Create(p).Error
+	}
+	p.Id = ex.Id
+	return DB.Model(p).Select("*").Omit("created_at").Updates(p).Error
+}
+
+func (s *ClientVersionService) DeletePolicy(p *model.ClientVersionPolicy) error {
+	return DB.Delete(p).Error
+}
+
+// EvaluateCompliance 根据策略判断某个 os/version 的合规状态
+func EvaluateCompliance(policies map[string]*model.ClientVersionPolicy, os, version string) string {
+	platform := utils.NormalizePlatform(os)
+	p, ok := policies[platform]
+	if platform == "" || version == "" || !ok {
+		return model.VersionComplianceUnknown
+	}
+	if p.MinVersion != "" && utils.CompareVersion(version, p.MinVersion) < 0 {
+		return model.VersionComplianceUnsupported
+	}
+	if p.RecommendedVersion != "" && utils.CompareVersion(version, p.RecommendedVersion) < 0 {
+		return model.VersionComplianceOutdated
+	}
+	return model.VersionComplianceOk
+}
+
+// Evaluate 使用当前策略判断合规状态
+func (s *ClientVersionService) Evaluate(os, version string) string {
+	return EvaluateCompliance(s.PolicyMap(), os, version)
+}
+
+type osVersionCount struct {
+	Os      string
+	Version string
+	Count   int64
+}
+
+func (s *ClientVersionService) osVersionCounts() (rows []*osVersionCount) {
+	DB.Model(&model.Peer{}).Select("os, version, count(*) as count").Group("os, version").Scan(&rows)
+	return
+}
+
+// Report 按平台和版本统计设备数量, 并标记过期版本
+func (s *ClientVersionService) Report() []*model.ClientVersionPlatformReport {
+	policies := s.PolicyMap()
+	reports := make(map[string]*model.ClientVersionPlatformReport)
+	versions := make(map[string]map[string]*model.ClientVersionCount)
+	for _, row := range s.osVersionCounts() {
+		platform := utils.NormalizePlatform(row.Os)
+		r, ok := reports[platform]
+		if !ok {
+			r = &model.ClientVersionPlatformReport{Platform: platform, Policy: policies[platform]}
+			reports[platform] = r
+			versions[platform] = make(map[string]*model.ClientVersionCount)
+		}
+		compliance := EvaluateCompliance(policies, row.Os, row.Version)
+		r.Total += row.Count
+		switch compliance {
+		case model.VersionComplianceOutdated:
+			r.Outdated += row.Count
+		case model.VersionComplianceUnsupported:
+			r.Unsupported += row.Count
+		}
+		// 同一平台的不同 os 字符串合并到同一版本
+		vc, ok := versions[platform][row.Version]
+		if !ok {
+			vc = &model.ClientVersionCount{Version: row.Version, Compliance: compliance}
+			versions[platform][row.Version] = vc
+			r.Versions = append(r.Versions, vc)
+		}
+		vc.Count += row.Count
+	}
+	res := make([]*model.ClientVersionPlatformReport, 0, len(reports))
+	for _, r := range reports {
+		sort.Slice(r.Versions, func(i, j int) bool {
+			return utils.CompareVersion(r.Versions[i].Version, r.Versions[j].Version) > 0
+		})
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Platform < res[j].Platform
+	})
+	return res
+}
+
+// FilterWhere 按合规状态筛选设备, 版本比较无法在 SQL 中完成, 先计算出符合条件的 os/version 组合
+func (s *ClientVersionService) FilterWhere(tx *gorm.DB, compliance string) {
+	if compliance == "" {
+		return
+	}
+	policies := s.PolicyMap()
+	cond := DB.Where("1 = 0")
+	for _, row := range s.osVersionCounts() {
+		if EvaluateCompliance(policies, row.Os, row.Version) == compliance {
+			cond = cond.Or("os = ? AND version = ?", row.Os, row.Version)
+		}
+	}
+	tx.Where(cond)
+}
+
+// FillPeers 填充设备的合规状态
+func (s *ClientVersionService) FillPeers(peers []*model.Peer) {
+	if len(peers) == 0 {
+		return
+	}
+	policies := s.PolicyMap()
+	for _, p := range peers {
+		p.VersionCompliance = EvaluateCompliance(policies, p.Os, p.Version)
+	}
+}
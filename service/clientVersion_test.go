@@ -0,0 +1,31 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestEvaluateCompliance(t *testing.T) {
+	policies := map[string]*model.ClientVersionPolicy{
+		"windows": {Platform: "windows", MinVersion: "1.2.0", RecommendedVersion: "1.3.2"},
+		"linux":   {Platform: "linux", RecommendedVersion: "1.3.0"},
+	}
+	cases := []struct {
+		os, version, want string
+	}{
+		{"windows / Windows 11 Pro", "1.3.2", model.VersionComplianceOk},
+		{"windows / Windows 11 Pro", "1.4.0", model.VersionComplianceOk},
+		{"windows / Windows 10", "1.2.7", model.VersionComplianceOutdated},
+		{"windows / Windows 10", "1.1.9", model.VersionComplianceUnsupported},
+		{"linux / Ubuntu 22.04", "1.0.0", model.VersionComplianceOutdated},
+		{"Mac OS / 14.2", "1.0.0", model.VersionComplianceUnknown},
+		{"windows / Windows 10", "", model.VersionComplianceUnknown},
+		{"", "1.3.2", model.VersionComplianceUnknown},
+	}
+	for _, c := range cases {
+		if got := EvaluateCompliance(policies, c.os, c.version); got != c.want {
+			t.Errorf("%q %q: got %s, want %s", c.os, c.version, got, c.want)
+		}
+	}
+}
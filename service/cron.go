@@ -0,0 +1,42 @@
+package service
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// StartCronJobs 启动后台定时任务, 在服务启动时调用一次
+func StartCronJobs() {
+	startCronJob("access_schedule_revoke", time.Minute, AllService.AccessPolicyService.RevokeOutsideWindowTokens)
+	startCronJob("peer_presence_sweep", 15*time.Second, AllService.PresenceService.SweepOffline)
+	startCronJob("peer_sysinfo_prune", time.Hour, AllService.PeerSysinfoService.Prune)
+	startCronJob("stale_device_cleanup", 6*time.Hour, AllService.StaleDeviceService.RunScheduled)
+	startCronJob("peer_action_expire", time.Minute, AllService.PeerActionService.ExpireStale)
+	startCronJob("ab_history_prune", 6*time.Hour, AllService.AddressBookHistoryService.Prune)
+	startCronJob("ab_rule_expire", time.Minute, AllService.AddressBookService.ExpireRules)
+	startCronJob("ab_rule_expiry_notify", 5*time.Minute, AllService.AddressBookService.NotifyExpiringRules)
+	startCronJob("peer_share_expire", time.Minute, AllService.PeerShareService.DeleteExpired)
+	if Config.Geoip.Enable {
+		startCronJob("geoip_reload", AllService.GeoipService.ReloadInterval(), AllService.GeoipService.Reload)
+	}
+}
+
+// startCronJob 每隔interval执行一次fn, fn中的panic会被记录而不会终止任务
+func startCronJob(name string, interval time.Duration, fn func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			runCronJob(name, fn)
+		}
+	}()
+}
+
+func runCronJob(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			Logger.Error("Cron job ", name, " panic: ", r, "\n", string(debug.Stack()))
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newTestDB 用内存 sqlite 替换 DB 和 AllService, 测试结束后恢复
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	oldDB, oldAll, oldLogger := DB, AllService, Logger
+	t.Cleanup(func() {
+		DB, AllService, Logger = oldDB, oldAll, oldLogger
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	DB = db
+	Logger = log.New()
+	Logger.SetLevel(log.PanicLevel)
+	AllService = new(Service)
+	return db
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type DeviceGroupPermissionService struct {
+}
+
+func (s *DeviceGroupPermissionService) InfoById(id uint) *model.DeviceGroupPermission {
+	r := &model.DeviceGroupPermission{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *DeviceGroupPermissionService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.DeviceGroupPermissionList) {
+	res = &model.DeviceGroupPermissionList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.DeviceGroupPermission{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.DeviceGroupPermissions)
+	return
+}
+
+// Exists 同一设备组和对象只能有一条权限
+func (s *DeviceGroupPermissionService) Exists(deviceGroupId uint, t int, toId uint, exceptId uint) bool {
+	var count int64
+	DB.Model(&model.DeviceGroupPermission{}).
+		Where("device_group_id = ? and type = ? and to_id = ? and id != ?", deviceGroupId, t, toId, exceptId).
+		Count(&count)
+	return count > 0
+}
+
+func (s *DeviceGroupPermissionService) Create(r *model.DeviceGroupPermission) error {
+	return DB.Create(r).Error
+}
+
+func (s *DeviceGroupPermissionService) Update(r *model.DeviceGroupPermission) error {
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+func (s *DeviceGroupPermissionService) Delete(r *model.DeviceGroupPermission) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteByDeviceGroupId 删除设备组的全部权限
+func (s *DeviceGroupPermissionService) DeleteByDeviceGroupId(tx *gorm.DB, deviceGroupId uint) error {
+	return tx.Where("device_group_id = ?", deviceGroupId).Delete(&model.DeviceGroupPermission{}).Error
+}
+
+// DeleteByTarget 删除授予用户或群组的全部权限
+func (s *DeviceGroupPermissionService) DeleteByTarget(tx *gorm.DB, t int, toId uint) error {
+	return tx.Where("type = ? and to_id = ?", t, toId).Delete(&model.DeviceGroupPermission{}).Error
+}
+
+// AccessibleGroups 用户可访问的设备组 deviceGroupId => 最高权限, 管理员可以访问全部设备组
+func (s *DeviceGroupPermissionService) AccessibleGroups(u *model.User) map[uint]int {
+	res := make(map[uint]int)
+	if AllService.UserService.IsAdmin(u) {
+		var ids []uint
+		DB.Model(&model.DeviceGroup{}).Pluck("id", &ids)
+		for _, id := range ids {
+			res[id] = model.DeviceGroupPermissionConnect
+		}
+		return res
+	}
+	var perms []*model.DeviceGroupPermission
+	DB.Where("(type = ? and to_id = ?) or (type = ? and to_id = ?)",
+		model.AccessTargetTypeUser, u.Id, model.AccessTargetTypeGroup, u.GroupId).Find(&perms)
+	for _, p := range perms {
+		if p.Permission > res[p.DeviceGroupId] {
+			res[p.DeviceGroupId] = p.Permission
+		}
+	}
+	return res
+}
+
+// AccessibleGroupIds 用户可访问的设备组id
+func (s *DeviceGroupPermissionService) AccessibleGroupIds(u *model.User) []uint {
+	groups := s.AccessibleGroups(u)
+	ids := make([]uint, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	return ids
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+	"gorm.io/gorm"
+)
+
+type DeviceGroupRuleService struct {
+}
+
+// DeviceGroupAssignment 规则对单个设备的分配结果
+type DeviceGroupAssignment struct {
+	PeerRowId   uint   `json:"peer_row_id"`
+	PeerId      string `json:"peer_id"`
+	Hostname    string `json:"hostname"`
+	FromGroupId uint   `json:"from_group_id"`
+	ToGroupId   uint   `json:"to_group_id"`
+	RuleId      uint   `json:"rule_id"`
+	RuleName    string `json:"rule_name"`
+}
+
+func (s *DeviceGroupRuleService) InfoById(id uint) *model.DeviceGroupRule {
+	r := &model.DeviceGroupRule{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *DeviceGroupRuleService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.DeviceGroupRuleList) {
+	res = &model.DeviceGroupRuleList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.DeviceGroupRule{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("sort asc, id asc").Find(&res.DeviceGroupRules)
+	return
+}
+
+func (s *DeviceGroupRuleService) Create(r *model.DeviceGroupRule) error {
+	return DB.Create(r).Error
+}
+
+func (s *DeviceGroupRuleService) Update(r *model.DeviceGroupRule) error {
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+func (s *DeviceGroupRuleService) Delete(r *model.DeviceGroupRule) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteByDeviceGroupId 删除分配到该设备组的规则
+func (s *DeviceGroupRuleService) DeleteByDeviceGroupId(tx *gorm.DB, deviceGroupId uint) error {
+	return tx.Where("device_group_id = ?", deviceGroupId).Delete(&model.DeviceGroupRule{}).Error
+}
+
+// Validate 校验规则的正则和CIDR, 至少需要一个条件
+func (s *DeviceGroupRuleService) Validate(r *model.DeviceGroupRule) error {
+	if r.HostnameRegex == "" && r.Os == "" && r.Version == "" && r.IpCidr == "" && r.UserGroupId == 0 {
+		return errors.New("at least one condition is required")
+	}
+	if r.HostnameRegex != "" {
+		if _, err := regexp.Compile(r.HostnameRegex); err != nil {
+			return err
+		}
+	}
+	if r.IpCidr != "" {
+		if _, err := utils.ParseCidr(r.IpCidr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MatchDeviceGroupRule 判断设备是否满足规则的全部条件, ip 为设备的访问ip, userGroupId 为设备所属用户的群组
+func MatchDeviceGroupRule(r *model.DeviceGroupRule, p *model.Peer, ip string, userGroupId uint) bool {
+	if r.HostnameRegex != "" {
+		re, err := regexp.Compile(r.HostnameRegex)
+		if err != nil || !re.MatchString(p.Hostname) {
+			return false
+		}
+	}
+	if r.Os != "" && !strings.Contains(strings.ToLower(p.Os), strings.ToLower(r.Os)) {
+		return false
+	}
+	if r.Version != "" && !strings.HasPrefix(p.Version, r.Version) {
+		return false
+	}
+	if r.IpCidr != "" && !utils.IpInCidr(ip, r.IpCidr) {
+		return false
+	}
+	if r.UserGroupId > 0 && r.UserGroupId != userGroupId {
+		return false
+	}
+	return true
+}
+
+func (s *DeviceGroupRuleService) enabledRules() []*model.DeviceGroupRule {
+	var rules []*model.DeviceGroupRule
+	DB.Where("status = ?", model.COMMON_STATUS_ENABLE).Order("sort asc, id asc").Find(&rules)
+	return rules
+}
+
+// evaluate 返回第一条匹配的规则, 没有匹配返回nil
+func (s *DeviceGroupRuleService) evaluate(rules []*model.DeviceGroupRule, p *model.Peer, ip string, userGroups map[uint]uint) *model.DeviceGroupRule {
+	userGroupId := uint(0)
+	if p.UserId > 0 {
+		ug, ok := userGroups[p.UserId]
+		if !ok {
+			ug = AllService.UserService.InfoById(p.UserId).GroupId
+			userGroups[p.UserId] = ug
+		}
+		userGroupId = ug
+	}
+	for _, r := range rules {
+		if MatchDeviceGroupRule(r, p, ip, userGroupId) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Apply 对单个设备执行规则, 匹配且设备组不同时更新; 没有匹配的规则时保持原设备组.
+// ip 为本次请求的客户端ip, 首次注册时设备还没有 LastOnlineIp, 为空时使用 LastOnlineIp
+func (s *DeviceGroupRuleService) Apply(p *model.Peer, ip string) *DeviceGroupAssignment {
+	if p == nil || p.RowId == 0 {
+		return nil
+	}
+	if ip == "" {
+		ip = p.LastOnlineIp
+	}
+	r := s.evaluate(s.enabledRules(), p, ip, map[uint]uint{})
+	if r == nil || r.DeviceGroupId == p.GroupId {
+		return nil
+	}
+	a := &DeviceGroupAssignment{PeerRowId: p.RowId, PeerId: p.Id, Hostname: p.Hostname, FromGroupId: p.GroupId, ToGroupId: r.DeviceGroupId, RuleId: r.Id, RuleName: r.Name}
+	if err := DB.Model(&model.Peer{}).Where("row_id = ?", p.RowId).Update("group_id", r.DeviceGroupId).Error; err != nil {
+		Logger.Error("Assign peer ", p.Id, " to device group failed: ", err)
+		return nil
+	}
+	p.GroupId = r.DeviceGroupId
+	return a
+}
+
+// ApplyAll 对全部设备执行规则, dryRun 为 true 时只返回将要发生的变化
+func (s *DeviceGroupRuleService) ApplyAll(dryRun bool) ([]*DeviceGroupAssignment, error) {
+	rules := s.enabledRules()
+	res := make([]*DeviceGroupAssignment, 0)
+	if len(rules) == 0 {
+		return res, nil
+	}
+	userGroups := map[uint]uint{}
+	var peers []*model.Peer
+	err := DB.FindInBatches(&peers, 500, func(tx *gorm.DB, batch int) error {
+		for _, p := range peers {
+			r := s.evaluate(rules, p, p.LastOnlineIp, userGroups)
+			if r == nil || r.DeviceGroupId == p.GroupId {
+				continue
+			}
+			res = append(res, &DeviceGroupAssignment{PeerRowId: p.RowId, PeerId: p.Id, Hostname: p.Hostname, FromGroupId: p.GroupId, ToGroupId: r.DeviceGroupId, RuleId: r.Id, RuleName: r.Name})
+		}
+		return nil
+	}).Error
+	if err != nil || dryRun {
+		return res, err
+	}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		for _, a := range res {
+			if err := tx.Model(&model.Peer{}).Where("row_id = ?", a.PeerRowId).Update("group_id", a.ToGroupId).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return res, err
+}
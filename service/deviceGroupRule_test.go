@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestMatchDeviceGroupRule(t *testing.T) {
+	p := &model.Peer{Hostname: "FIN-PC-042", Os: "Windows 11 Pro", Version: "1.3.2", LastOnlineIp: "10.1.2.3"}
+	cases := []struct {
+		name string
+		rule *model.DeviceGroupRule
+		ug   uint
+		want bool
+	}{
+		{"hostname", &model.DeviceGroupRule{HostnameRegex: `^FIN-`}, 0, true},
+		{"hostname miss", &model.DeviceGroupRule{HostnameRegex: `^HR-`}, 0, false},
+		{"os case insensitive", &model.DeviceGroupRule{Os: "windows"}, 0, true},
+		{"version prefix", &model.DeviceGroupRule{Version: "1.3"}, 0, true},
+		{"version miss", &model.DeviceGroupRule{Version: "1.2"}, 0, false},
+		{"cidr", &model.DeviceGroupRule{IpCidr: "10.1.0.0/16"}, 0, true},
+		{"cidr miss", &model.DeviceGroupRule{IpCidr: "192.168.0.0/16"}, 0, false},
+		{"user group", &model.DeviceGroupRule{UserGroupId: 3}, 3, true},
+		{"user group miss", &model.DeviceGroupRule{UserGroupId: 3}, 2, false},
+		{"all conditions", &model.DeviceGroupRule{HostnameRegex: `PC`, Os: "Windows", IpCidr: "10.0.0.0/8"}, 0, true},
+		{"one condition fails", &model.DeviceGroupRule{HostnameRegex: `PC`, Os: "Linux"}, 0, false},
+	}
+	for _, c := range cases {
+		if got := MatchDeviceGroupRule(c.rule, p, p.LastOnlineIp, c.ug); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	// 首次注册的设备还没有 LastOnlineIp, 使用请求的ip
+	fresh := &model.Peer{Hostname: "FIN-PC-043"}
+	if !MatchDeviceGroupRule(&model.DeviceGroupRule{IpCidr: "10.1.0.0/16"}, fresh, "10.1.9.9", 0) {
+		t.Error("cidr rule should match the request ip of a new peer")
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/geoip"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+const geoipBackfillBatch = 500
+
+var (
+	geoLocator     *geoip.Locator
+	geoLocatorOnce sync.Once
+)
+
+var ErrGeoipDisabled = errors.New("geoip is disabled")
+
+type GeoipService struct {
+}
+
+// locator 第一次使用时按配置加载数据库, 未启用时返回 nil
+func (s *GeoipService) locator() *geoip.Locator {
+	if !Config.Geoip.Enable {
+		return nil
+	}
+	geoLocatorOnce.Do(func() {
+		geoLocator = geoip.New(Config.Geoip.CityDb, Config.Geoip.AsnDb)
+		if _, err := geoLocator.Reload(); err != nil {
+			Logger.Warn("Load geoip database failed: ", err)
+		}
+	})
+	return geoLocator
+}
+
+// ReloadInterval 文件检查间隔, 默认1分钟
+func (s *GeoipService) ReloadInterval() time.Duration {
+	if Config.Geoip.ReloadInterval < 10*time.Second {
+		return time.Minute
+	}
+	return Config.Geoip.ReloadInterval
+}
+
+// Reload 数据库文件有变化时重新加载, 由后台任务定时调用
+func (s *GeoipService) Reload() {
+	l := s.locator()
+	if l == nil {
+		return
+	}
+	changed, err := l.Reload()
+	if err != nil {
+		Logger.Warn("Reload geoip database failed: ", err)
+	}
+	if changed {
+		Logger.Info("Geoip database reloaded")
+	}
+}
+
+func (s *GeoipService) Status() []*geoip.FileStatus {
+	l := s.locator()
+	if l == nil {
+		return nil
+	}
+	return l.Status()
+}
+
+// Lookup 查询 IP, 未启用或未命中时返回空
+func (s *GeoipService) Lookup(ip string) model.GeoInfo {
+	l := s.locator()
+	if l == nil || ip == "" {
+		return model.GeoInfo{}
+	}
+	r := l.Lookup(ip)
+	if r == nil {
+		return model.GeoInfo{}
+	}
+	return model.GeoInfo{Country: r.Country, City: r.City, Asn: r.Asn, AsnOrg: r.AsnOrg}
+}
+
+// Fill 填充地理信息
+func (s *GeoipService) Fill(g *model.GeoInfo, ip string) {
+	*g = s.Lookup(ip)
+}
+
+func geoColumns(g model.GeoInfo) map[string]interface{} {
+	return map[string]interface{}{"geo_country": g.Country, "geo_city": g.City, "geo_asn": g.Asn, "geo_asn_org": g.AsnOrg}
+}
+
+// RefreshPeer 心跳时更新设备的地理信息, 没有变化时不写库; 新 IP 查不到时清空旧值
+func (s *GeoipService) RefreshPeer(peer *model.Peer, ip string) {
+	if s.locator() == nil {
+		return
+	}
+	g := s.Lookup(ip)
+	if g == peer.GeoInfo {
+		return
+	}
+	if err := DB.Model(&model.Peer{}).Where("row_id = ?", peer.RowId).Updates(geoColumns(g)).Error; err != nil {
+		Logger.Error("Update peer ", peer.Id, " geoip failed: ", err)
+		return
+	}
+	peer.GeoInfo = g
+}
+
+// GeoipBackfillResult 补全结果
+type GeoipBackfillResult struct {
+	Peers      int `json:"peers"`
+	LoginLogs  int `json:"login_logs"`
+	AuditConns int `json:"audit_conns"`
+}
+
+// Backfill 为还没有地理信息的记录补全, 按 IP 缓存查询结果
+func (s *GeoipService) Backfill() (*GeoipBackfillResult, error) {
+	l := s.locator()
+	if l == nil || !l.Ready() {
+		return nil, ErrGeoipDisabled
+	}
+	res := &GeoipBackfillResult{}
+	cache := make(map[string]model.GeoInfo)
+	lookup := func(ip string) model.GeoInfo {
+		g, ok := cache[ip]
+		if !ok {
+			g = s.Lookup(ip)
+			cache[ip] = g
+		}
+		return g
+	}
+	update := func(m interface{}, pk string, id uint, g model.GeoInfo) error {
+		return DB.Model(m).Where(pk+" = ?", id).Updates(geoColumns(g)).Error
+	}
+
+	var peers []*model.Peer
+	err := DB.Where("geo_country = '' and last_online_ip <> ''").FindInBatches(&peers, geoipBackfillBatch, func(_ *gorm.DB, _ int) error {
+		for _, p := range peers {
+			if g := lookup(p.LastOnlineIp); g.Country != "" {
+				if err := update(&model.Peer{}, "row_id", p.RowId, g); err != nil {
+					return err
+				}
+				res.Peers++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return res, err
+	}
+	var logs []*model.LoginLog
+	err = DB.Where("geo_country = '' and ip <> ''").FindInBatches(&logs, geoipBackfillBatch, func(_ *gorm.DB, _ int) error {
+		for _, r := range logs {
+			if g := lookup(r.Ip); g.Country != "" {
+				if err := update(&model.LoginLog{}, "id", r.Id, g); err != nil {
+					return err
+				}
+				res.LoginLogs++
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return res, err
+	}
+	var conns []*model.AuditConn
+	err = DB.Where("geo_country = '' and ip <> ''").FindInBatches(&conns, geoipBackfillBatch, func(_ *gorm.DB, _ int) error {
+		for _, r := range conns {
+			if g := lookup(r.Ip); g.Country != "" {
+				if err := update(&model.AuditConn{}, "id", r.Id, g); err != nil {
+					return err
+				}
+				res.AuditConns++
+			}
+		}
+		return nil
+	}).Error
+	return res, err
+}
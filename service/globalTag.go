@@ -0,0 +1,214 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/utils"
+	"gorm.io/gorm"
+)
+
+var ErrTagNotGlobal = errors.New("TagNotGlobal")
+
+type GlobalTagService struct {
+}
+
+func (s *GlobalTagService) InfoById(id uint) *model.GlobalTag {
+	t := &model.GlobalTag{}
+	DB.Where("id = ?", id).First(t)
+	return t
+}
+
+func (s *GlobalTagService) InfoByName(name string) *model.GlobalTag {
+	t := &model.GlobalTag{}
+	DB.Where("name = ?", name).First(t)
+	return t
+}
+
+func (s *GlobalTagService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.GlobalTagList) {
+	res = &model.GlobalTagList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.GlobalTag{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.GlobalTags)
+	return
+}
+
+// All 全部全局标签, 按名称排序
+func (s *GlobalTagService) All() []*model.GlobalTag {
+	var res []*model.GlobalTag
+	DB.Order("name asc").Find(&res)
+	return res
+}
+
+// Create 全局标签对所有地址簿生效, 变更后所有地址簿版本号加一
+func (s *GlobalTagService) Create(t *model.GlobalTag) error {
+	if err := DB.Create(t).Error; err != nil {
+		return err
+	}
+	AllService.AddressBookRevisionService.BumpAll()
+	return nil
+}
+
+// Update 只修改颜色和备注, 名称已经保存在设备的标签中, 不能修改
+func (s *GlobalTagService) Update(t *model.GlobalTag) error {
+	if err := DB.Model(t).Select("color", "remark").Updates(t).Error; err != nil {
+		return err
+	}
+	AllService.AddressBookRevisionService.BumpAll()
+	return nil
+}
+
+// Delete 删除全局标签, 设备上的标签名保留
+func (s *GlobalTagService) Delete(t *model.GlobalTag) error {
+	if err := DB.Delete(t).Error; err != nil {
+		return err
+	}
+	AllService.AddressBookRevisionService.BumpAll()
+	return nil
+}
+
+// WithGlobalTags 返回给客户端的标签: 集合标签加上全局标签, 同名时使用全局标签的颜色.
+// 只允许全局标签的集合不返回其他标签
+func (s *GlobalTagService) WithGlobalTags(uid, cid uint, tags []*model.Tag) []*model.Tag {
+	globals := s.All()
+	globalOnly := s.globalOnly(cid)
+	if len(globals) == 0 && !globalOnly {
+		return tags
+	}
+	byName := make(map[string]*model.GlobalTag, len(globals))
+	for _, g := range globals {
+		byName[g.Name] = g
+	}
+	res := make([]*model.Tag, 0, len(tags)+len(globals))
+	for _, t := range tags {
+		if g, ok := byName[t.Name]; ok {
+			t.Color = g.Color
+			delete(byName, t.Name)
+		} else if globalOnly {
+			continue
+		}
+		res = append(res, t)
+	}
+	for _, g := range globals {
+		if _, ok := byName[g.Name]; ok {
+			res = append(res, &model.Tag{Name: g.Name, Color: g.Color, UserId: uid, CollectionId: cid})
+		}
+	}
+	return res
+}
+
+func (s *GlobalTagService) globalOnly(cid uint) bool {
+	return cid > 0 && AllService.AddressBookService.CollectionInfoById(cid).TagPolicy == model.AbTagPolicyGlobalOnly
+}
+
+// TagChecker 按集合的标签策略返回检查函数, 文件夹标签不受限制; 批量检查时只查询一次
+func (s *GlobalTagService) TagChecker(cid uint) func(tags []string) bool {
+	if !s.globalOnly(cid) {
+		return func([]string) bool { return true }
+	}
+	names := make(map[string]bool)
+	for _, g := range s.All() {
+		names[g.Name] = true
+	}
+	return func(tags []string) bool {
+		for _, t := range tags {
+			if !names[t] && !strings.HasPrefix(t, AbFolderTagPrefix) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// CheckTags 按集合的标签策略检查设备标签
+func (s *GlobalTagService) CheckTags(cid uint, tags []string) bool {
+	return s.TagChecker(cid)(tags)
+}
+
+// CheckTagsJson 同 CheckTags, 标签为地址簿中保存的 json
+func (s *GlobalTagService) CheckTagsJson(cid uint, tags []byte) bool {
+	var names []string
+	_ = json.Unmarshal(tags, &names)
+	return s.CheckTags(cid, names)
+}
+
+// Merge 把集合中的重复标签合并到全局标签: 设备上的原标签名换成全局标签名, 再删除原标签.
+// 合并 tagIds 指定的标签, 以及所有集合中名称在 names 中的标签
+func (s *GlobalTagService) Merge(g *model.GlobalTag, tagIds []uint, names []string, actor *AbActor) (*model.GlobalTagMergeReport, error) {
+	report := &model.GlobalTagMergeReport{}
+	var tags []*model.Tag
+	if len(tagIds) == 0 && len(names) == 0 {
+		return report, nil
+	}
+	tx := DB.Where("1 = 0")
+	if len(tagIds) > 0 {
+		tx = tx.Or("id in ?", tagIds)
+	}
+	if len(names) > 0 {
+		tx = tx.Or("name in ?", names)
+	}
+	tx.Find(&tags)
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		for _, t := range tags {
+			if t.Name != g.Name {
+				var abs []*model.AddressBook
+				tx.Where("user_id = ? and collection_id = ?", t.UserId, t.CollectionId).Find(&abs)
+				for _, ab := range abs {
+					var names []string
+					_ = json.Unmarshal(ab.Tags, &names)
+					replaced, ok := replaceTag(names, t.Name, g.Name)
+					if !ok {
+						continue
+					}
+					after := *ab
+					after.Tags, _ = json.Marshal(replaced)
+					if err := tx.Model(&model.AddressBook{}).Where("row_id = ?", ab.RowId).UpdateColumn("tags", after.Tags).Error; err != nil {
+						return err
+					}
+					AllService.AddressBookHistoryService.RecordPeer(tx, actor, ab, &after)
+					report.PeersUpdated++
+				}
+			}
+			if err := tx.Delete(t).Error; err != nil {
+				return err
+			}
+			AllService.AddressBookHistoryService.RecordTag(tx, actor, t, nil)
+			report.TagsMerged++
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// replaceTag 把 from 换成 to, 已有 to 时只删除 from
+func replaceTag(names []string, from, to string) ([]string, bool) {
+	if !utils.InArray(from, names) {
+		return names, false
+	}
+	res := make([]string, 0, len(names))
+	seen := false
+	for _, n := range names {
+		if n == from {
+			n = to
+		}
+		if n == to {
+			if seen {
+				continue
+			}
+			seen = true
+		}
+		res = append(res, n)
+	}
+	return res, true
+}
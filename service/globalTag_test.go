@@ -0,0 +1,26 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplaceTag(t *testing.T) {
+	cases := []struct {
+		in       []string
+		from, to string
+		want     []string
+		changed  bool
+	}{
+		{[]string{"a", "prod", "b"}, "prod", "Production", []string{"a", "Production", "b"}, true},
+		{[]string{"Production", "prod"}, "prod", "Production", []string{"Production"}, true},
+		{[]string{"prod", "Production"}, "prod", "Production", []string{"Production"}, true},
+		{[]string{"a", "b"}, "prod", "Production", []string{"a", "b"}, false},
+	}
+	for _, c := range cases {
+		got, changed := replaceTag(c.in, c.from, c.to)
+		if changed != c.changed || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("replaceTag(%v, %s, %s) = %v, %v; want %v, %v", c.in, c.from, c.to, got, changed, c.want, c.changed)
+		}
+	}
+}
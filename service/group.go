@@ -35,7 +35,24 @@ func (us *GroupService) Create(u *model.Group) error {
 	return res
 }
 func (us *GroupService) Delete(u *model.Group) error {
-	return DB.Delete(u).Error
+	tx := DB.Begin()
+	if err := tx.Delete(u).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := AllService.AccessPolicyService.DeleteIpRulesByTarget(tx, model.AccessTargetTypeGroup, u.Id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := AllService.AccessPolicyService.DeleteSchedulesByTarget(tx, model.AccessTargetTypeGroup, u.Id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := AllService.DeviceGroupPermissionService.DeleteByTarget(tx, model.AccessTargetTypeGroup, u.Id); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
 }
 
 // Update 更新
@@ -69,7 +86,18 @@ func (us *GroupService) DeviceGroupCreate(u *model.DeviceGroup) error {
 	return res
 }
 func (us *GroupService) DeviceGroupDelete(u *model.DeviceGroup) error {
-	return DB.Delete(u).Error
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(u).Error; err != nil {
+			return err
+		}
+		if err := AllService.DeviceGroupRuleService.DeleteByDeviceGroupId(tx, u.Id); err != nil {
+			return err
+		}
+		if err := AllService.DeviceGroupPermissionService.DeleteByDeviceGroupId(tx, u.Id); err != nil {
+			return err
+		}
+		return AllService.StrategyService.DeleteAssignmentsByTarget(tx, model.StrategyTargetDeviceGroup, u.Id)
+	})
 }
 
 func (us *GroupService) DeviceGroupUpdate(u *model.DeviceGroup) error {
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+type NotificationService struct {
+}
+
+// Notify 给用户发送站内通知, data 序列化为 JSON
+func (s *NotificationService) Notify(userId uint, typ, title, content string, data interface{}) error {
+	n := &model.Notification{UserId: userId, Type: typ, Title: title, Content: content}
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		n.Data = custom_types.AutoJson(b)
+	}
+	return DB.Create(n).Error
+}
+
+func (s *NotificationService) List(userId, page, pageSize uint, where func(tx *gorm.DB)) (res *model.NotificationList) {
+	res = &model.NotificationList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	DB.Model(&model.Notification{}).Where("user_id = ? and read_at = 0", userId).Count(&res.Unread)
+	tx := DB.Model(&model.Notification{}).Where("user_id = ?", userId)
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.Notifications)
+	return
+}
+
+// MarkRead 标记已读, ids 为空时标记该用户全部通知
+func (s *NotificationService) MarkRead(userId uint, ids []uint) error {
+	tx := DB.Model(&model.Notification{}).Where("user_id = ? and read_at = 0", userId)
+	if len(ids) > 0 {
+		tx.Where("id in ?", ids)
+	}
+	return tx.Update("read_at", time.Now().Unix()).Error
+}
+
+// DeleteByUserId 删除用户时清理
+func (s *NotificationService) DeleteByUserId(tx *gorm.DB, userId uint) error {
+	return tx.Where("user_id = ?", userId).Delete(&model.Notification{}).Error
+}
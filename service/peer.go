@@ -35,10 +35,9 @@ func (ps *PeerService) FindByUserIdAndUuid(uuid string, userId uint) *model.Peer
 // UuidBindUserId 绑定用户id
 func (ps *PeerService) UuidBindUserId(deviceId string, uuid string, userId uint) {
 	peer := ps.FindByUuid(uuid)
-	// 如果存在则更新
+	// 如果存在则更新, 只写 user_id, 避免用旧的在线状态覆盖心跳的更新
 	if peer.RowId > 0 {
-		peer.UserId = userId
-		ps.Update(peer)
+		DB.Model(peer).Update("user_id", userId)
 	} else {
 		// 不存在则创建
 		/*if deviceId != "" {
@@ -77,6 +76,23 @@ func (ps *PeerService) ListByUserIds(userIds []uint, page, pageSize uint) (res *
 	return
 }
 
+// ListByUserIdsOrGroupIds 取属于userIds中用户或在deviceGroupIds设备组中的设备
+func (ps *PeerService) ListByUserIdsOrGroupIds(userIds, deviceGroupIds []uint, page, pageSize uint) (res *model.PeerList) {
+	res = &model.PeerList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Peer{})
+	if len(deviceGroupIds) > 0 {
+		tx.Where("user_id in (?) or group_id in (?)", userIds, deviceGroupIds)
+	} else {
+		tx.Where("user_id in (?)", userIds)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.Peers)
+	return
+}
+
 func (ps *PeerService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerList) {
 	res = &model.PeerList{}
 	res.Page = int64(page)
@@ -112,10 +128,22 @@ func (ps *PeerService) Create(u *model.Peer) error {
 // Delete 删除, 同时也应该删除token
 func (ps *PeerService) Delete(u *model.Peer) error {
 	uuid := u.Uuid
-	err := DB.Delete(u).Error
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(u).Error; err != nil {
+			return err
+		}
+		return AllService.PeerShareService.DeleteByPeerIds(tx, []string{u.Id})
+	})
 	if err != nil {
 		return err
 	}
+	AllService.PeerSysinfoService.DeleteByPeerId(u.Id)
+	AllService.PeerAttributeService.DeleteByPeerId(u.Id)
+	AllService.PresenceService.DeleteEventsByPeerId(u.Id)
+	AllService.PeerActionService.DeleteByPeerId(u.Id)
+	AllService.StrategyService.DeleteStateByPeerId(u.Id)
+	DB.Where("type = ? and to_id = ?", model.StrategyTargetPeer, u.RowId).Delete(&model.StrategyAssignment{})
+	AllService.PeerIdentityService.DismissByRowIds([]uint{u.RowId})
 	// 删除token
 	return AllService.UserService.FlushTokenByUuid(uuid)
 }
@@ -139,10 +167,26 @@ func (ps *PeerService) GetUuidListByIDs(ids []uint) ([]string, error) {
 // BatchDelete 批量删除, 同时也应该删除token
 func (ps *PeerService) BatchDelete(ids []uint) error {
 	uuids, err := ps.GetUuidListByIDs(ids)
-	err = DB.Where("row_id in (?)", ids).Delete(&model.Peer{}).Error
+	var peerIds []string
+	DB.Model(&model.Peer{}).Where("row_id in (?)", ids).Pluck("id", &peerIds)
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("row_id in (?)", ids).Delete(&model.Peer{}).Error; err != nil {
+			return err
+		}
+		return AllService.PeerShareService.DeleteByPeerIds(tx, peerIds)
+	})
 	if err != nil {
 		return err
 	}
+	DB.Where("type = ? and to_id in (?)", model.StrategyTargetPeer, ids).Delete(&model.StrategyAssignment{})
+	AllService.PeerIdentityService.DismissByRowIds(ids)
+	if len(peerIds) > 0 {
+		DB.Where("peer_id in (?)", peerIds).Delete(&model.PeerSysinfo{})
+		DB.Where("peer_id in (?)", peerIds).Delete(&model.PeerAttributeValue{})
+		DB.Where("peer_id in (?)", peerIds).Delete(&model.PeerStrategyState{})
+		DB.Where("peer_id in (?)", peerIds).Delete(&model.PeerPresenceEvent{})
+		DB.Where("peer_id in (?)", peerIds).Delete(&model.PeerAction{})
+	}
 	// 删除token
 	return AllService.UserService.FlushTokenByUuids(uuids)
 }
@@ -0,0 +1,257 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type PeerActionService struct {
+}
+
+const defaultPeerActionTtl = 24 * time.Hour
+
+// PeerActionParams 操作参数, Conns 为要断开的会话, 为空时断开全部
+type PeerActionParams struct {
+	Conns []int `json:"conns,omitempty"`
+}
+
+// PeerActionPayload 心跳响应中下发的操作
+type PeerActionPayload struct {
+	Id     uint             `json:"id"`
+	Action string           `json:"action"`
+	Params PeerActionParams `json:"params"`
+}
+
+func (s *PeerActionService) InfoById(id uint) *model.PeerAction {
+	r := &model.PeerAction{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *PeerActionService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerActionList) {
+	res = &model.PeerActionList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerAction{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.PeerActions)
+	return
+}
+
+// Enqueue 为设备添加操作, ttl 为0时使用默认有效期
+func (s *PeerActionService) Enqueue(peerId, action string, params *PeerActionParams, ttl time.Duration, createdBy uint) (*model.PeerAction, error) {
+	switch action {
+	case model.PeerActionSysinfo, model.PeerActionDisconnect, model.PeerActionUnlink:
+	default:
+		return nil, fmt.Errorf("unknown action %s", action)
+	}
+	if ttl <= 0 {
+		ttl = defaultPeerActionTtl
+	}
+	if params == nil {
+		params = &PeerActionParams{}
+	}
+	p, _ := json.Marshal(params)
+	a := &model.PeerAction{
+		PeerId:    peerId,
+		Action:    action,
+		Params:    p,
+		Status:    model.PeerActionStatusPending,
+		ExpireAt:  time.Now().Add(ttl).Unix(),
+		CreatedBy: createdBy,
+	}
+	return a, DB.Create(a).Error
+}
+
+// Cancel 取消还未下发的操作
+func (s *PeerActionService) Cancel(a *model.PeerAction) error {
+	if a.Status != model.PeerActionStatusPending {
+		return errors.New("action already delivered")
+	}
+	return s.finish(a, model.PeerActionStatusCanceled, "")
+}
+
+// peerActionStates 审计日志中的状态名
+var peerActionStates = map[int]string{
+	model.PeerActionStatusDelivered: "delivered",
+	model.PeerActionStatusAcked:     "acked",
+	model.PeerActionStatusExpired:   "expired",
+	model.PeerActionStatusCanceled:  "canceled",
+	model.PeerActionStatusFailed:    "failed",
+}
+
+func (s *PeerActionService) finish(a *model.PeerAction, status int, result string) error {
+	a.Status = status
+	a.Result = result
+	a.AckedAt = time.Now().Unix()
+	if err := DB.Model(a).Updates(map[string]interface{}{"status": status, "result": result, "acked_at": a.AckedAt}).Error; err != nil {
+		return err
+	}
+	// 取消由管理员操作, 在控制器中记录
+	if status != model.PeerActionStatusCanceled {
+		audit.LogRemoteAction(nil, a.CreatedBy, a.PeerId, a.Action, peerActionStates[status])
+	}
+	return nil
+}
+
+func (s *PeerActionService) deliver(a *model.PeerAction) {
+	a.Status = model.PeerActionStatusDelivered
+	a.DeliveredAt = time.Now().Unix()
+	if err := DB.Model(a).Updates(map[string]interface{}{"status": a.Status, "delivered_at": a.DeliveredAt}).Error; err != nil {
+		Logger.Error("Deliver peer action ", a.Id, " failed: ", err)
+		return
+	}
+	audit.LogRemoteAction(nil, a.CreatedBy, a.PeerId, a.Action, peerActionStates[a.Status])
+}
+
+// HeartbeatPayload 处理设备的操作队列, conns 为客户端心跳上报的当前会话
+// 已下发的断开操作在会话消失后确认, 待处理的操作在此下发; 解除绑定直接在服务端执行
+func (s *PeerActionService) HeartbeatPayload(peer *model.Peer, conns []int) map[string]interface{} {
+	now := time.Now().Unix()
+	var actions []*model.PeerAction
+	DB.Where("peer_id = ? and status in ? and expire_at > ?", peer.Id,
+		[]int{model.PeerActionStatusPending, model.PeerActionStatusDelivered}, now).Order("id asc").Find(&actions)
+	if len(actions) == 0 {
+		return nil
+	}
+	current := make(map[int]bool, len(conns))
+	for _, c := range conns {
+		current[c] = true
+	}
+	res := map[string]interface{}{}
+	payloads := make([]*PeerActionPayload, 0)
+	disconnect := make([]int, 0)
+	for _, a := range actions {
+		params := PeerActionParams{}
+		_ = json.Unmarshal(a.Params, &params)
+		targets := params.Conns
+		if len(targets) == 0 {
+			targets = conns
+		}
+		if a.Status == model.PeerActionStatusDelivered {
+			if a.Action == model.PeerActionDisconnect && !anyIn(params.Conns, current) {
+				s.finish(a, model.PeerActionStatusAcked, "sessions closed")
+			}
+			continue
+		}
+		switch a.Action {
+		case model.PeerActionSysinfo:
+			res["sysinfo"] = true
+			s.deliver(a)
+		case model.PeerActionDisconnect:
+			if len(targets) == 0 {
+				s.finish(a, model.PeerActionStatusAcked, "no active session")
+				continue
+			}
+			disconnect = append(disconnect, targets...)
+			// 记录要断开的会话, 下次心跳时确认
+			a.Params, _ = json.Marshal(&PeerActionParams{Conns: targets})
+			DB.Model(a).Update("params", a.Params)
+			s.deliver(a)
+		case model.PeerActionUnlink:
+			if err := s.unlink(peer); err != nil {
+				s.finish(a, model.PeerActionStatusFailed, err.Error())
+			} else {
+				s.finish(a, model.PeerActionStatusAcked, "account unlinked")
+			}
+			continue
+		}
+		payloads = append(payloads, &PeerActionPayload{Id: a.Id, Action: a.Action, Params: PeerActionParams{Conns: targets}})
+	}
+	if len(disconnect) > 0 {
+		res["disconnect"] = disconnect
+	}
+	if len(payloads) > 0 {
+		res["actions"] = payloads
+	}
+	return res
+}
+
+func anyIn(ids []int, set map[int]bool) bool {
+	for _, id := range ids {
+		if set[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// unlink 解除设备与账号的绑定, 并吊销该设备的token
+func (s *PeerActionService) unlink(peer *model.Peer) error {
+	if err := DB.Model(&model.Peer{}).Where("row_id = ?", peer.RowId).Update("user_id", 0).Error; err != nil {
+		return err
+	}
+	peer.UserId = 0
+	if peer.Uuid == "" {
+		return nil
+	}
+	return AllService.UserService.FlushTokenByUuid(peer.Uuid)
+}
+
+// AckSysinfo 收到系统信息上传时确认已下发的 sysinfo 操作
+func (s *PeerActionService) AckSysinfo(peerId string) {
+	var actions []*model.PeerAction
+	DB.Where("peer_id = ? and action = ? and status = ?", peerId, model.PeerActionSysinfo, model.PeerActionStatusDelivered).Find(&actions)
+	for _, a := range actions {
+		if err := s.finish(a, model.PeerActionStatusAcked, "sysinfo uploaded"); err != nil {
+			Logger.Error("Ack sysinfo action ", a.Id, " failed: ", err)
+		}
+	}
+}
+
+// Ack 客户端主动确认操作结果
+func (s *PeerActionService) Ack(peerId string, id uint, success bool, result string) error {
+	a := s.InfoById(id)
+	if a.Id == 0 || a.PeerId != peerId {
+		return errors.New("ItemNotFound")
+	}
+	if a.Status != model.PeerActionStatusDelivered {
+		return errors.New("action not delivered")
+	}
+	status := model.PeerActionStatusAcked
+	if !success {
+		status = model.PeerActionStatusFailed
+	}
+	return s.finish(a, status, result)
+}
+
+// DeleteByPeerId 删除设备的全部远程操作
+func (s *PeerActionService) DeleteByPeerId(peerId string) error {
+	return DB.Where("peer_id = ?", peerId).Delete(&model.PeerAction{}).Error
+}
+
+// ExpireStale 将过期未完成的操作标记为过期, 由后台任务定时调用
+func (s *PeerActionService) ExpireStale() {
+	var actions []*model.PeerAction
+	DB.Where("status in ? and expire_at <= ?", []int{model.PeerActionStatusPending, model.PeerActionStatusDelivered}, time.Now().Unix()).
+		Find(&actions)
+	if len(actions) == 0 {
+		return
+	}
+	ids := make([]uint, 0, len(actions))
+	for _, a := range actions {
+		ids = append(ids, a.Id)
+	}
+	// 只更新仍未完成的, 避免覆盖期间刚确认的操作
+	tx := DB.Model(&model.PeerAction{}).
+		Where("id in ? and status in ?", ids, []int{model.PeerActionStatusPending, model.PeerActionStatusDelivered}).
+		Update("status", model.PeerActionStatusExpired)
+	if tx.Error != nil {
+		Logger.Error("Expire peer actions failed: ", tx.Error)
+		return
+	}
+	for _, a := range actions {
+		audit.LogRemoteAction(nil, a.CreatedBy, a.PeerId, a.Action, peerActionStates[model.PeerActionStatusExpired])
+	}
+	Logger.Info("Expired ", tx.RowsAffected, " peer actions")
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestPeerActionLifecycle(t *testing.T) {
+	newTestDB(t, &model.Peer{}, &model.PeerAction{})
+	s := &PeerActionService{}
+	peer := &model.Peer{Id: "100", Uuid: "u-100"}
+	DB.Create(peer)
+
+	sysinfo, _ := s.Enqueue(peer.Id, model.PeerActionSysinfo, nil, time.Hour, 1)
+	disconnect, _ := s.Enqueue(peer.Id, model.PeerActionDisconnect, nil, time.Hour, 1)
+	stale, _ := s.Enqueue(peer.Id, model.PeerActionSysinfo, nil, time.Hour, 1)
+	DB.Model(stale).Update("expire_at", time.Now().Add(-time.Minute).Unix())
+
+	res := s.HeartbeatPayload(peer, []int{7})
+	if res["sysinfo"] != true {
+		t.Fatalf("sysinfo not requested: %v", res)
+	}
+	if conns, _ := res["disconnect"].([]int); len(conns) != 1 || conns[0] != 7 {
+		t.Fatalf("disconnect = %v, want [7]", res["disconnect"])
+	}
+	// 会话仍在时不确认断开
+	s.HeartbeatPayload(peer, []int{7})
+	if st := s.InfoById(disconnect.Id).Status; st != model.PeerActionStatusDelivered {
+		t.Errorf("disconnect status = %d, want delivered", st)
+	}
+	s.HeartbeatPayload(peer, nil)
+	if st := s.InfoById(disconnect.Id).Status; st != model.PeerActionStatusAcked {
+		t.Errorf("disconnect status = %d, want acked", st)
+	}
+
+	s.AckSysinfo(peer.Id)
+	if a := s.InfoById(sysinfo.Id); a.Status != model.PeerActionStatusAcked || a.Result != "sysinfo uploaded" {
+		t.Errorf("sysinfo action = %+v, want acked", a)
+	}
+
+	s.ExpireStale()
+	if st := s.InfoById(stale.Id).Status; st != model.PeerActionStatusExpired {
+		t.Errorf("stale action status = %d, want expired", st)
+	}
+	if st := s.InfoById(sysinfo.Id).Status; st != model.PeerActionStatusAcked {
+		t.Errorf("expiry must not touch finished actions, got status %d", st)
+	}
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type PeerAttributeService struct {
+}
+
+var ErrInvalidAttributeValue = errors.New("InvalidAttributeValue")
+
+var attributeNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+
+func (s *PeerAttributeService) DefInfoById(id uint) *model.PeerAttributeDef {
+	r := &model.PeerAttributeDef{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *PeerAttributeService) DefInfoByName(name string) *model.PeerAttributeDef {
+	r := &model.PeerAttributeDef{}
+	DB.Where("name = ?", name).First(r)
+	return r
+}
+
+func (s *PeerAttributeService) ListDefs(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerAttributeDefList) {
+	res = &model.PeerAttributeDefList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerAttributeDef{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("sort asc, id asc").Find(&res.PeerAttributeDefs)
+	return
+}
+
+// AllDefs 全部字段定义, name => def
+func (s *PeerAttributeService) AllDefs() map[string]*model.PeerAttributeDef {
+	var defs []*model.PeerAttributeDef
+	DB.Find(&defs)
+	res := make(map[string]*model.PeerAttributeDef, len(defs))
+	for _, d := range defs {
+		res[d.Name] = d
+	}
+	return res
+}
+
+func (s *PeerAttributeService) CreateDef(d *model.PeerAttributeDef) error {
+	return DB.Create(d).Error
+}
+
+// UpdateDef 更新字段定义, 已有的值不满足新的选项、格式或范围时拒绝修改
+func (s *PeerAttributeService) UpdateDef(d *model.PeerAttributeDef) error {
+	if err := s.CheckExistingValues(d); err != nil {
+		return err
+	}
+	return DB.Model(d).Select("*").Omit("created_at").Updates(d).Error
+}
+
+// CheckExistingValues 按新的字段定义校验设备上已有的值, 返回第一个无效的值
+func (s *PeerAttributeService) CheckExistingValues(d *model.PeerAttributeDef) error {
+	var values []*model.PeerAttributeValue
+	if err := DB.Where("def_id = ?", d.Id).Find(&values).Error; err != nil {
+		return err
+	}
+	for _, v := range values {
+		if err := ValidateValue(d, v.Value); err != nil {
+			return fmt.Errorf("%v (peer %s: %q)", err, v.PeerId, v.Value)
+		}
+	}
+	return nil
+}
+
+// DeleteDef 删除字段定义及所有设备上的值
+func (s *PeerAttributeService) DeleteDef(d *model.PeerAttributeDef) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("def_id = ?", d.Id).Delete(&model.PeerAttributeValue{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(d).Error
+	})
+}
+
+// ValidateDef 校验字段定义本身
+func (s *PeerAttributeService) ValidateDef(d *model.PeerAttributeDef) error {
+	if !attributeNameRegexp.MatchString(d.Name) {
+		return fmt.Errorf("invalid name %q, expected lowercase letters, digits and _", d.Name)
+	}
+	switch d.Type {
+	case model.PeerAttributeTypeString:
+		if d.Pattern != "" {
+			if _, err := regexp.Compile(d.Pattern); err != nil {
+				return fmt.Errorf("invalid pattern: %v", err)
+			}
+		}
+	case model.PeerAttributeTypeNumber:
+		if d.Min != nil && d.Max != nil && *d.Min > *d.Max {
+			return errors.New("min greater than max")
+		}
+	case model.PeerAttributeTypeDate:
+	case model.PeerAttributeTypeEnum:
+		if len(attributeOptions(d)) == 0 {
+			return errors.New("enum requires options")
+		}
+	default:
+		return fmt.Errorf("invalid type %q", d.Type)
+	}
+	return nil
+}
+
+func attributeOptions(d *model.PeerAttributeDef) []string {
+	var opts []string
+	_ = json.Unmarshal(d.Options, &opts)
+	return opts
+}
+
+// ValidateValue 按字段定义校验值, 空值只校验是否必填
+func ValidateValue(d *model.PeerAttributeDef, v string) error {
+	if v == "" {
+		if d.Required {
+			return fmt.Errorf("%s is required", d.Name)
+		}
+		return nil
+	}
+	switch d.Type {
+	case model.PeerAttributeTypeString:
+		if d.Pattern != "" {
+			re, err := regexp.Compile(d.Pattern)
+			if err != nil || !re.MatchString(v) {
+				return fmt.Errorf("%s does not match %s", d.Name, d.Pattern)
+			}
+		}
+	case model.PeerAttributeTypeNumber:
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("%s must be a number", d.Name)
+		}
+		if d.Min != nil && n < *d.Min {
+			return fmt.Errorf("%s must be >= %v", d.Name, *d.Min)
+		}
+		if d.Max != nil && n > *d.Max {
+			return fmt.Errorf("%s must be <= %v", d.Name, *d.Max)
+		}
+	case model.PeerAttributeTypeDate:
+		if _, err := time.Parse("2006-01-02", v); err != nil {
+			return fmt.Errorf("%s must be a date (YYYY-MM-DD)", d.Name)
+		}
+	case model.PeerAttributeTypeEnum:
+		for _, o := range attributeOptions(d) {
+			if o == v {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s must be one of %v", d.Name, attributeOptions(d))
+	}
+	return nil
+}
+
+// ValidateValues 校验一组值, 未定义的字段报错; creating 为 true 时检查必填字段
+func (s *PeerAttributeService) ValidateValues(values map[string]string, creating bool) error {
+	defs := s.AllDefs()
+	for name, v := range values {
+		d, ok := defs[name]
+		if !ok {
+			return fmt.Errorf("unknown attribute %s", name)
+		}
+		if err := ValidateValue(d, v); err != nil {
+			return err
+		}
+	}
+	if creating {
+		for name, d := range defs {
+			if _, ok := values[name]; !ok && d.Required {
+				return fmt.Errorf("%s is required", name)
+			}
+		}
+	}
+	return nil
+}
+
+// SetValues 保存设备的自定义字段, 空值删除该字段, 未出现的字段不变
+func (s *PeerAttributeService) SetValues(peerId string, values map[string]string) error {
+	defs := s.AllDefs()
+	return DB.Transaction(func(tx *gorm.DB) error {
+		return setAttributeValues(tx, defs, peerId, values)
+	})
+}
+
+// setAttributeValues 在 tx 中写入自定义字段, 空值删除该字段, 未定义的字段忽略
+func setAttributeValues(tx *gorm.DB, defs map[string]*model.PeerAttributeDef, peerId string, values map[string]string) error {
+	for name, v := range values {
+		d, ok := defs[name]
+		if !ok {
+			continue
+		}
+		if err := tx.Where("peer_id = ? and def_id = ?", peerId, d.Id).Delete(&model.PeerAttributeValue{}).Error; err != nil {
+			return err
+		}
+		if v == "" {
+			continue
+		}
+		if err := tx.Create(&model.PeerAttributeValue{PeerId: peerId, DefId: d.Id, Value: v}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValuesByPeerIds 取设备的自定义字段 peerId => name => value
+func (s *PeerAttributeService) ValuesByPeerIds(peerIds []string) map[string]map[string]string {
+	res := make(map[string]map[string]string)
+	if len(peerIds) == 0 {
+		return res
+	}
+	names := make(map[uint]string)
+	for name, d := range s.AllDefs() {
+		names[d.Id] = name
+	}
+	var values []*model.PeerAttributeValue
+	DB.Where("peer_id in ?", peerIds).Find(&values)
+	for _, v := range values {
+		name, ok := names[v.DefId]
+		if !ok {
+			continue
+		}
+		if res[v.PeerId] == nil {
+			res[v.PeerId] = make(map[string]string)
+		}
+		res[v.PeerId][name] = v.Value
+	}
+	return res
+}
+
+// FillPeers 填充设备的 Attributes
+func (s *PeerAttributeService) FillPeers(peers []*model.Peer) {
+	ids := make([]string, 0, len(peers))
+	for _, p := range peers {
+		ids = append(ids, p.Id)
+	}
+	values := s.ValuesByPeerIds(ids)
+	for _, p := range peers {
+		p.Attributes = values[p.Id]
+	}
+}
+
+// attrCond 自定义字段的过滤条件, op 为 = > >= < <= 或 .. (闭区间 a..b)
+type attrCond struct {
+	op   string
+	a, b string
+}
+
+// parseAttrCond 解析过滤值, number 和 date 类型可带比较运算符或区间, 如 >=10、<2026-01-01、10..20
+func parseAttrCond(d *model.PeerAttributeDef, v string) (*attrCond, error) {
+	c := &attrCond{op: "=", a: v}
+	if d.Type == model.PeerAttributeTypeNumber || d.Type == model.PeerAttributeTypeDate {
+		for _, op := range []string{">=", "<=", ">", "<"} {
+			if strings.HasPrefix(v, op) {
+				c.op, c.a = op, strings.TrimSpace(v[len(op):])
+				break
+			}
+		}
+		if c.op == "=" {
+			if a, b, ok := strings.Cut(v, ".."); ok {
+				c.op, c.a, c.b = "..", strings.TrimSpace(a), strings.TrimSpace(b)
+			}
+		}
+	}
+	if c.a == "" || (c.op == ".." && c.b == "") {
+		return nil, fmt.Errorf("%s: empty value", d.Name)
+	}
+	for _, x := range []string{c.a, c.b} {
+		if x == "" {
+			continue
+		}
+		if _, err := attrCompare(d, x, x); err != nil {
+			return nil, fmt.Errorf("%s: %v", d.Name, err)
+		}
+	}
+	return c, nil
+}
+
+// attrCompare 按字段类型比较两个值, number 按数值, date(YYYY-MM-DD) 按日期, 其它按字符串
+func attrCompare(d *model.PeerAttributeDef, x, y string) (int, error) {
+	switch d.Type {
+	case model.PeerAttributeTypeNumber:
+		fx, err := strconv.ParseFloat(x, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", x)
+		}
+		fy, err := strconv.ParseFloat(y, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number %q", y)
+		}
+		switch {
+		case fx < fy:
+			return -1, nil
+		case fx > fy:
+			return 1, nil
+		}
+		return 0, nil
+	case model.PeerAttributeTypeDate:
+		for _, v := range []string{x, y} {
+			if _, err := time.Parse("2006-01-02", v); err != nil {
+				return 0, fmt.Errorf("invalid date %q", v)
+			}
+		}
+	}
+	return strings.Compare(x, y), nil
+}
+
+// where 条件对应的 SQL. 值以文本保存: number 转为数值比较, date 为 YYYY-MM-DD, 按文本比较即按日期比较
+func (c *attrCond) where(d *model.PeerAttributeDef) (string, []interface{}) {
+	col := "value"
+	arg := func(x string) interface{} { return x }
+	if d.Type == model.PeerAttributeTypeNumber {
+		col = "cast(value as decimal(30,10))"
+		arg = func(x string) interface{} {
+			f, _ := strconv.ParseFloat(x, 64)
+			return f
+		}
+	}
+	if c.op == ".." {
+		return col + " between ? and ?", []interface{}{arg(c.a), arg(c.b)}
+	}
+	return col + " " + c.op + " ?", []interface{}{arg(c.a)}
+}
+
+// FilterWhere 按自定义字段过滤设备: string 类型模糊匹配, enum 精确匹配,
+// number 和 date 类型支持比较运算符和区间(见 parseAttrCond). 条件无效时返回错误
+func (s *PeerAttributeService) FilterWhere(filters map[string]string) (func(tx *gorm.DB), error) {
+	if len(filters) == 0 {
+		return nil, nil
+	}
+	defs := s.AllDefs()
+	conds := make([]func(tx *gorm.DB), 0, len(filters))
+	for name, v := range filters {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		d, ok := defs[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %q", name)
+		}
+		sub := DB.Model(&model.PeerAttributeValue{}).Select("peer_id").Where("def_id = ?", d.Id)
+		switch d.Type {
+		case model.PeerAttributeTypeString:
+			sub = sub.Where("value like ?", "%"+v+"%")
+		case model.PeerAttributeTypeEnum:
+			sub = sub.Where("value = ?", v)
+		default:
+			c, err := parseAttrCond(d, v)
+			if err != nil {
+				return nil, err
+			}
+			query, args := c.where(d)
+			sub = sub.Where(query, args...)
+		}
+		conds = append(conds, func(tx *gorm.DB) {
+			tx.Where("id in (?)", sub)
+		})
+	}
+	return func(tx *gorm.DB) {
+		for _, c := range conds {
+			c(tx)
+		}
+	}, nil
+}
+
+// DeleteByPeerId 删除设备的全部自定义字段
+func (s *PeerAttributeService) DeleteByPeerId(peerId string) error {
+	return DB.Where("peer_id = ?", peerId).Delete(&model.PeerAttributeValue{}).Error
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestValidateValue(t *testing.T) {
+	min, max := 0.0, 100.0
+	defs := map[string]*model.PeerAttributeDef{
+		"asset_tag":   {Name: "asset_tag", Type: model.PeerAttributeTypeString, Pattern: `^AT-\d+$`, Required: true},
+		"cost":        {Name: "cost", Type: model.PeerAttributeTypeNumber, Min: &min, Max: &max},
+		"bought":      {Name: "bought", Type: model.PeerAttributeTypeDate},
+		"environment": {Name: "environment", Type: model.PeerAttributeTypeEnum, Options: []byte(`["prod","dev"]`)},
+	}
+	cases := []struct {
+		def   string
+		value string
+		ok    bool
+	}{
+		{"asset_tag", "AT-123", true},
+		{"asset_tag", "123", false},
+		{"asset_tag", "", false},
+		{"cost", "42.5", true},
+		{"cost", "101", false},
+		{"cost", "abc", false},
+		{"cost", "", true},
+		{"bought", "2026-01-31", true},
+		{"bought", "31/01/2026", false},
+		{"environment", "prod", true},
+		{"environment", "staging", false},
+	}
+	for _, c := range cases {
+		err := ValidateValue(defs[c.def], c.value)
+		if (err == nil) != c.ok {
+			t.Errorf("%s=%q: expected ok=%v, got %v", c.def, c.value, c.ok, err)
+		}
+	}
+}
+
+func TestAttrCond(t *testing.T) {
+	newTestDB(t, &model.PeerAttributeValue{})
+	number := &model.PeerAttributeDef{Name: "cost", Type: model.PeerAttributeTypeNumber}
+	date := &model.PeerAttributeDef{Name: "bought", Type: model.PeerAttributeTypeDate}
+	cases := []struct {
+		def    *model.PeerAttributeDef
+		filter string
+		value  string
+		match  bool
+	}{
+		{number, "10", "10.0", true},
+		{number, "10", "100", false},
+		{number, ">=10", "10", true},
+		{number, ">10", "10", false},
+		{number, "<10", "9.5", true},
+		{number, "<= 10", "11", false},
+		{number, "10..20", "20", true},
+		{number, "10..20", "9", false},
+		{number, ">-5", "-1", true},
+		{date, "2026-01-01", "2026-01-01", true},
+		{date, "<2026-01-01", "2025-12-31", true},
+		{date, ">=2026-01-01", "2025-12-31", false},
+		{date, "2026-01-01..2026-01-31", "2026-01-15", true},
+		{date, "2026-01-01..2026-01-31", "2026-02-01", false},
+	}
+	for i, c := range cases {
+		cond, err := parseAttrCond(c.def, c.filter)
+		if err != nil {
+			t.Errorf("%s %q: %v", c.def.Name, c.filter, err)
+			continue
+		}
+		peerId := strconv.Itoa(i)
+		DB.Create(&model.PeerAttributeValue{PeerId: peerId, Value: c.value})
+		query, args := cond.where(c.def)
+		var n int64
+		DB.Model(&model.PeerAttributeValue{}).Where("peer_id = ?", peerId).Where(query, args...).Count(&n)
+		if got := n == 1; got != c.match {
+			t.Errorf("%s %q on %q = %v, want %v", c.def.Name, c.filter, c.value, got, c.match)
+		}
+	}
+
+	for _, c := range []struct {
+		def    *model.PeerAttributeDef
+		filter string
+	}{
+		{number, ">=abc"},
+		{number, ">="},
+		{number, "10.."},
+		{date, "<31/01/2026"},
+		{date, "2026-01-01..2026-13-01"},
+	} {
+		if _, err := parseAttrCond(c.def, c.filter); err == nil {
+			t.Errorf("%s %q: expected an error", c.def.Name, c.filter)
+		}
+	}
+}
+
+func TestAttributeFilterWhere(t *testing.T) {
+	newTestDB(t, &model.Peer{}, &model.PeerAttributeDef{}, &model.PeerAttributeValue{})
+	cost := &model.PeerAttributeDef{Name: "cost", Type: model.PeerAttributeTypeNumber, Options: []byte("[]")}
+	tag := &model.PeerAttributeDef{Name: "asset_tag", Type: model.PeerAttributeTypeString, Options: []byte("[]")}
+	DB.Create(cost)
+	DB.Create(tag)
+	for i, v := range []string{"5", "50", "500"} {
+		id := string(rune('a' + i))
+		DB.Create(&model.Peer{Id: id})
+		DB.Create(&model.PeerAttributeValue{PeerId: id, DefId: cost.Id, Value: v})
+		DB.Create(&model.PeerAttributeValue{PeerId: id, DefId: tag.Id, Value: "AT-" + v})
+	}
+	s := &PeerAttributeService{}
+	find := func(filters map[string]string) []string {
+		where, err := s.FilterWhere(filters)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		tx := DB.Model(&model.Peer{})
+		if where != nil {
+			where(tx)
+		}
+		tx.Order("id asc").Pluck("id", &ids)
+		return ids
+	}
+	if got := find(map[string]string{"cost": ">=50"}); len(got) != 2 || got[0] != "b" || got[1] != "c" {
+		t.Errorf("cost >=50 = %v", got)
+	}
+	if got := find(map[string]string{"cost": "1..100", "asset_tag": "AT-5"}); len(got) != 2 {
+		t.Errorf("cost 1..100 and asset_tag AT-5 = %v", got)
+	}
+	if got := find(map[string]string{"cost": ">1000"}); len(got) != 0 {
+		t.Errorf("cost >1000 = %v", got)
+	}
+	if _, err := s.FilterWhere(map[string]string{"missing": "x"}); err == nil {
+		t.Error("unknown attribute should be rejected")
+	}
+}
+
+func TestUpdateDefChecksExistingValues(t *testing.T) {
+	newTestDB(t, &model.PeerAttributeDef{}, &model.PeerAttributeValue{})
+	d := &model.PeerAttributeDef{Name: "environment", Type: model.PeerAttributeTypeEnum, Options: []byte(`["prod","dev"]`)}
+	DB.Create(d)
+	DB.Create(&model.PeerAttributeValue{PeerId: "a", DefId: d.Id, Value: "dev"})
+	s := &PeerAttributeService{}
+	d.Options = []byte(`["prod","staging"]`)
+	if err := s.UpdateDef(d); err == nil {
+		t.Fatal("removing an option in use should be rejected")
+	}
+	if got := s.DefInfoById(d.Id); string(got.Options) != `["prod","dev"]` {
+		t.Errorf("options = %s, want unchanged", got.Options)
+	}
+	d.Options = []byte(`["prod","dev","staging"]`)
+	if err := s.UpdateDef(d); err != nil {
+		t.Errorf("adding an option: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/audit"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	PeerMergeModeManual = "manual"
+	PeerMergeModeAuto   = "auto"
+)
+
+var ErrPeerIdentityResolved = errors.New("identity change already resolved")
+
+type PeerIdentityService struct {
+}
+
+func (s *PeerIdentityService) InfoById(id uint) *model.PeerIdentityChange {
+	r := &model.PeerIdentityChange{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *PeerIdentityService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerIdentityChangeList) {
+	res = &model.PeerIdentityChangeList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerIdentityChange{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.PeerIdentityChanges)
+	return
+}
+
+// History 某个 RustDesk id 的 id 变化记录, 包括它变成其他 id 和从其他 id 变来的记录
+func (s *PeerIdentityService) History(peerId string) (res []*model.PeerIdentityChange) {
+	DB.Where("kind = ? and (old_value = ? or new_value = ?)", model.PeerIdentityKindId, peerId, peerId).
+		Order("id desc").Find(&res)
+	return
+}
+
+// AutoMerge 配置 peer-merge-mode 为 auto 时自动合并
+func (s *PeerIdentityService) AutoMerge() bool {
+	return Config.App.PeerMergeMode == PeerMergeModeAuto
+}
+
+// authenticated 新设备是否已在该 uuid 下登录: 有绑定该 uuid 和新 id 的未过期 token,
+// 旧设备有所有者时 token 必须属于同一用户. /sysinfo 不需要认证, 只凭 uuid 不能自动合并
+func (s *PeerIdentityService) authenticated(old, peer *model.Peer) bool {
+	tx := DB.Model(&model.UserToken{}).Where("device_uuid = ? and device_id = ? and expired_at > ?", peer.Uuid, peer.Id, time.Now().Unix())
+	if old.UserId != 0 {
+		tx = tx.Where("user_id = ?", old.UserId)
+	}
+	var n int64
+	tx.Count(&n)
+	return n > 0
+}
+
+// DetectIdChange 新设备创建后调用, 同一 uuid 下已有其他 id 的设备时记录为 id 变化
+func (s *PeerIdentityService) DetectIdChange(peer *model.Peer) {
+	if peer.Uuid == "" {
+		return
+	}
+	old := &model.Peer{}
+	DB.Where("uuid = ? and id <> ?", peer.Uuid, peer.Id).Order("last_online_time desc").First(old)
+	if old.RowId == 0 {
+		return
+	}
+	var n int64
+	DB.Model(&model.PeerIdentityChange{}).Where("kind = ? and old_row_id = ? and peer_row_id = ?",
+		model.PeerIdentityKindId, old.RowId, peer.RowId).Count(&n)
+	if n > 0 {
+		return
+	}
+	r := &model.PeerIdentityChange{
+		PeerRowId: peer.RowId,
+		OldRowId:  old.RowId,
+		Kind:      model.PeerIdentityKindId,
+		OldValue:  old.Id,
+		NewValue:  peer.Id,
+		Status:    model.PeerIdentityStatusPending,
+	}
+	if err := DB.Create(r).Error; err != nil {
+		Logger.Error("Record id change of peer ", old.Id, " failed: ", err)
+		return
+	}
+	Logger.Info("Peer id changed from ", old.Id, " to ", peer.Id, " (uuid ", peer.Uuid, ")")
+	if s.AutoMerge() {
+		s.autoMerge(r, old, peer)
+	}
+}
+
+// MergePendingOnLogin 设备登录后重试自动合并: 客户端通常先上传系统信息再登录, 检测时还没有登录凭据
+func (s *PeerIdentityService) MergePendingOnLogin(peerId, uuid string) {
+	if !s.AutoMerge() || peerId == "" || uuid == "" {
+		return
+	}
+	peer := AllService.PeerService.FindById(peerId)
+	if peer.RowId == 0 || peer.Uuid != uuid {
+		return
+	}
+	var rs []*model.PeerIdentityChange
+	DB.Where("kind = ? and peer_row_id = ? and status = ?", model.PeerIdentityKindId, peer.RowId, model.PeerIdentityStatusPending).
+		Order("id asc").Find(&rs)
+	for _, r := range rs {
+		old := &model.Peer{}
+		DB.Where("row_id = ?", r.OldRowId).First(old)
+		if old.RowId == 0 || old.Uuid != peer.Uuid {
+			continue
+		}
+		s.autoMerge(r, old, peer)
+	}
+}
+
+// autoMerge 新设备有登录凭据时合并, 否则留给管理员处理
+func (s *PeerIdentityService) autoMerge(r *model.PeerIdentityChange, old, peer *model.Peer) {
+	if !s.authenticated(old, peer) {
+		Logger.Warn("Peer ", peer.Id, " has no login for uuid ", peer.Uuid, ", id change from ", old.Id, " left pending")
+		return
+	}
+	r.Auto = true
+	if err := s.Merge(r, 0); err != nil {
+		Logger.Error("Auto merge peer ", old.Id, " into ", peer.Id, " failed: ", err)
+		return
+	}
+	audit.LogPeerMerged(nil, 0, old.Id, peer.Id)
+}
+
+// RecordUuidChange 同一 id 上报了新的 uuid, 设备记录直接更新, 这里只记录历史
+func (s *PeerIdentityService) RecordUuidChange(peer *model.Peer, newUuid string) {
+	if peer.Uuid == "" || newUuid == "" || peer.Uuid == newUuid {
+		return
+	}
+	r := &model.PeerIdentityChange{
+		PeerRowId:  peer.RowId,
+		OldRowId:   peer.RowId,
+		Kind:       model.PeerIdentityKindUuid,
+		OldValue:   peer.Uuid,
+		NewValue:   newUuid,
+		Status:     model.PeerIdentityStatusMerged,
+		Auto:       true,
+		ResolvedAt: time.Now().Unix(),
+	}
+	if err := DB.Create(r).Error; err != nil {
+		Logger.Error("Record uuid change of peer ", peer.Id, " failed: ", err)
+	}
+}
+
+// Merge 将旧设备合并到新设备: 保留新设备, 带过去所有者、分组、别名、自定义字段和地址簿引用, 然后删除旧设备.
+// 旧设备与新设备 uuid 相同, 所以不清除登录 token
+func (s *PeerIdentityService) Merge(r *model.PeerIdentityChange, adminId uint) error {
+	if r.Status != model.PeerIdentityStatusPending || r.Kind != model.PeerIdentityKindId {
+		return ErrPeerIdentityResolved
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		old := &model.Peer{}
+		cur := &model.Peer{}
+		tx.Where("row_id = ?", r.OldRowId).First(old)
+		tx.Where("row_id = ?", r.PeerRowId).First(cur)
+		if cur.RowId == 0 {
+			return errors.New("peer " + r.NewValue + " not found")
+		}
+		if old.RowId != 0 {
+			actor := &AbActor{UserId: adminId, Source: model.AbChangeSourceAdmin}
+			if adminId == 0 {
+				actor.Source = model.AbChangeSourceSystem
+			}
+			if err := mergePeerInto(tx, old, cur, actor); err != nil {
+				return err
+			}
+		}
+		r.Status = model.PeerIdentityStatusMerged
+		r.ResolvedBy = adminId
+		r.ResolvedAt = time.Now().Unix()
+		if err := tx.Model(r).Select("status", "auto", "resolved_by", "resolved_at").Updates(r).Error; err != nil {
+			return err
+		}
+		// 同一旧设备的其他待处理记录已经没有意义
+		return tx.Model(&model.PeerIdentityChange{}).
+			Where("old_row_id = ? and status = ? and id <> ?", r.OldRowId, model.PeerIdentityStatusPending, r.Id).
+			Updates(map[string]interface{}{"status": model.PeerIdentityStatusDismissed, "resolved_by": adminId, "resolved_at": r.ResolvedAt}).Error
+	})
+}
+
+// Dismiss 忽略, 两条设备记录都保留
+func (s *PeerIdentityService) Dismiss(r *model.PeerIdentityChange, adminId uint) error {
+	if r.Status != model.PeerIdentityStatusPending {
+		return ErrPeerIdentityResolved
+	}
+	r.Status = model.PeerIdentityStatusDismissed
+	r.ResolvedBy = adminId
+	r.ResolvedAt = time.Now().Unix()
+	return DB.Model(r).Select("status", "resolved_by", "resolved_at").Updates(r).Error
+}
+
+// DismissByRowIds 设备删除后, 涉及它的待处理记录无法再合并
+func (s *PeerIdentityService) DismissByRowIds(rowIds []uint) {
+	DB.Model(&model.PeerIdentityChange{}).
+		Where("status = ? and (peer_row_id in (?) or old_row_id in (?))", model.PeerIdentityStatusPending, rowIds, rowIds).
+		Updates(map[string]interface{}{"status": model.PeerIdentityStatusDismissed, "resolved_at": time.Now().Unix()})
+}
+
+// mergePeerInto 地址簿条目的修改以 actor 记录变更历史
+func mergePeerInto(tx *gorm.DB, old, cur *model.Peer, actor *AbActor) error {
+	// 新设备上没有的信息从旧设备带过来
+	up := map[string]interface{}{}
+	if cur.UserId == 0 && old.UserId != 0 {
+		up["user_id"] = old.UserId
+	}
+	if cur.GroupId == 0 && old.GroupId != 0 {
+		up["group_id"] = old.GroupId
+	}
+	if cur.Alias == "" && old.Alias != "" {
+		up["alias"] = old.Alias
+	}
+	if len(up) > 0 {
+		if err := tx.Model(&model.Peer{}).Where("row_id = ?", cur.RowId).Updates(up).Error; err != nil {
+			return err
+		}
+	}
+	// 地址簿: 同一用户同一地址簿中已有新 id 时删除旧条目, 否则改为新 id
+	var abs []*model.AddressBook
+	tx.Where("id = ?", old.Id).Find(&abs)
+	for _, ab := range abs {
+		keep := &model.AddressBook{}
+		tx.Where("user_id = ? and collection_id = ? and id = ?", ab.UserId, ab.CollectionId, cur.Id).First(keep)
+		before := *ab
+		if keep.RowId > 0 {
+			// 条目上的共享改为共享保留的条目
+			if err := movePeerShares(tx, "ab_row_id", ab.RowId, keep.RowId); err != nil {
+				return err
+			}
+			if err := tx.Delete(ab).Error; err != nil {
+				return err
+			}
+			AllService.AddressBookHistoryService.RecordPeer(tx, actor, &before, nil)
+			continue
+		}
+		if err := tx.Model(ab).Update("id", cur.Id).Error; err != nil {
+			return err
+		}
+		AllService.AddressBookHistoryService.RecordPeer(tx, actor, &before, ab)
+	}
+	if err := tx.Model(&model.ShareRecord{}).Where("peer_id = ?", old.Id).Update("peer_id", cur.Id).Error; err != nil {
+		return err
+	}
+	// 直接共享设备改为新 id, 共享地址簿条目的随条目改为新 id
+	if err := movePeerShares(tx, "peer_id", old.Id, cur.Id); err != nil {
+		return err
+	}
+	// 自定义字段: 新设备没有的字段带过来
+	sub := tx.Session(&gorm.Session{NewDB: true}).Model(&model.PeerAttributeValue{}).Select("def_id").Where("peer_id = ?", cur.Id)
+	if err := tx.Model(&model.PeerAttributeValue{}).Where("peer_id = ? and def_id not in (?)", old.Id, sub).
+		Update("peer_id", cur.Id).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("peer_id = ?", old.Id).Delete(&model.PeerAttributeValue{}).Error; err != nil {
+		return err
+	}
+	// 历史记录归到新 id
+	for _, m := range []interface{}{&model.PeerSysinfo{}, &model.PeerPresenceEvent{}} {
+		if err := tx.Model(m).Where("peer_id = ?", old.Id).Update("peer_id", cur.Id).Error; err != nil {
+			return err
+		}
+	}
+	// 单设备策略分配指向新设备, 新设备已有分配时以新设备为准
+	var n int64
+	tx.Model(&model.StrategyAssignment{}).Where("type = ? and to_id = ?", model.StrategyTargetPeer, cur.RowId).Count(&n)
+	if n == 0 {
+		if err := tx.Model(&model.StrategyAssignment{}).Where("type = ? and to_id = ?", model.StrategyTargetPeer, old.RowId).
+			Update("to_id", cur.RowId).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Where("type = ? and to_id = ?", model.StrategyTargetPeer, old.RowId).Delete(&model.StrategyAssignment{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("peer_id = ?", old.Id).Delete(&model.PeerStrategyState{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&model.PeerAction{}).Where("peer_id = ? and status = ?", old.Id, model.PeerActionStatusPending).
+		Update("status", model.PeerActionStatusCanceled).Error; err != nil {
+		return err
+	}
+	return tx.Delete(old).Error
+}
+
+// movePeerShares 将 col 为 from 的设备共享改为 to, 已有相同对象的共享时删除旧共享.
+// col 为 peer_id 时只处理直接共享设备, 地址簿条目的共享只更新 peer_id
+func movePeerShares(tx *gorm.DB, col string, from, to interface{}) error {
+	q := tx.Model(&model.PeerShare{}).Where(col+" = ?", from)
+	exists := "exists (select 1 from peer_shares n where n." + col + " = ? and n.type = peer_shares.type and n.to_id = peer_shares.to_id"
+	if col == "peer_id" {
+		q = q.Where("ab_row_id = 0")
+		exists += " and n.ab_row_id = 0"
+	}
+	var dup []uint
+	q.Where(exists+")", to).Pluck("id", &dup)
+	if len(dup) > 0 {
+		if err := tx.Where("id in ?", dup).Delete(&model.PeerShare{}).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Model(&model.PeerShare{}).Where(col+" = ?", from).Update(col, to).Error
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/config"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func identityTestDB(t *testing.T, mergeMode string) {
+	newTestDB(t, &model.Peer{}, &model.AddressBook{}, &model.ShareRecord{}, &model.PeerAttributeValue{},
+		&model.PeerSysinfo{}, &model.PeerPresenceEvent{}, &model.StrategyAssignment{}, &model.PeerStrategyState{},
+		&model.PeerAction{}, &model.PeerIdentityChange{}, &model.UserToken{}, &model.PeerShare{},
+		&model.AddressBookChange{}, &model.AddressBookRevision{})
+	oldConfig := Config
+	t.Cleanup(func() { Config = oldConfig })
+	Config = &config.Config{App: config.App{PeerMergeMode: mergeMode}}
+}
+
+func TestMergePeerInto(t *testing.T) {
+	identityTestDB(t, PeerMergeModeManual)
+	old := &model.Peer{Id: "old", Uuid: "u", UserId: 3, GroupId: 4, Alias: "accueil"}
+	cur := &model.Peer{Id: "new", Uuid: "u", GroupId: 9}
+	DB.Create(old)
+	DB.Create(cur)
+	// 用户 3 的地址簿只有旧 id, 用户 5 的地址簿两个 id 都有
+	DB.Create(&model.AddressBook{Id: "old", UserId: 3})
+	dupAb := &model.AddressBook{Id: "old", UserId: 5}
+	keepAb := &model.AddressBook{Id: "new", UserId: 5}
+	DB.Create(dupAb)
+	DB.Create(keepAb)
+	// 直接共享给用户 7 的只有旧 id, 共享给群组 2 的两个 id 都有; 用户 5 共享了将被删除的旧条目
+	DB.Create(&model.PeerShare{UserId: 3, PeerId: "old", Type: model.ShareAddressBookRuleTypePersonal, ToId: 7})
+	DB.Create(&model.PeerShare{UserId: 3, PeerId: "old", Type: model.ShareAddressBookRuleTypeGroup, ToId: 2})
+	DB.Create(&model.PeerShare{UserId: 3, PeerId: "new", Type: model.ShareAddressBookRuleTypeGroup, ToId: 2})
+	DB.Create(&model.PeerShare{UserId: 5, AbRowId: dupAb.RowId, PeerId: "old", Type: model.ShareAddressBookRuleTypePersonal, ToId: 8})
+	DB.Create(&model.ShareRecord{PeerId: "old", UserId: 3})
+	DB.Create(&model.PeerAttributeValue{PeerId: "old", DefId: 1, Value: "AT-1"})
+	DB.Create(&model.PeerAttributeValue{PeerId: "old", DefId: 2, Value: "old value"})
+	DB.Create(&model.PeerAttributeValue{PeerId: "new", DefId: 2, Value: "new value"})
+	DB.Create(&model.PeerPresenceEvent{PeerId: "old", Online: true})
+	DB.Create(&model.StrategyAssignment{Type: model.StrategyTargetPeer, ToId: old.RowId, StrategyId: 1})
+	DB.Create(&model.PeerAction{PeerId: "old", Action: model.PeerActionSysinfo, Status: model.PeerActionStatusPending})
+
+	if err := mergePeerInto(DB, old, cur, &AbActor{Source: model.AbChangeSourceSystem}); err != nil {
+		t.Fatal(err)
+	}
+	merged := &model.Peer{}
+	DB.Where("row_id = ?", cur.RowId).First(merged)
+	if merged.UserId != 3 || merged.GroupId != 9 || merged.Alias != "accueil" {
+		t.Errorf("merged peer = user %d group %d alias %q, want owner and alias from old, group kept", merged.UserId, merged.GroupId, merged.Alias)
+	}
+	var n int64
+	DB.Model(&model.Peer{}).Where("id = ?", "old").Count(&n)
+	if n != 0 {
+		t.Error("old peer should be deleted")
+	}
+	DB.Model(&model.AddressBook{}).Where("id = ?", "old").Count(&n)
+	if n != 0 {
+		t.Errorf("%d address book entries still use the old id", n)
+	}
+	DB.Model(&model.AddressBook{}).Where("id = ? and user_id = ?", "new", 5).Count(&n)
+	if n != 1 {
+		t.Errorf("user 5 has %d entries for the new id, want 1", n)
+	}
+	DB.Model(&model.AddressBook{}).Where("id = ? and user_id = ?", "new", 3).Count(&n)
+	if n != 1 {
+		t.Errorf("user 3 has %d entries for the new id, want 1", n)
+	}
+	DB.Model(&model.ShareRecord{}).Where("peer_id = ?", "new").Count(&n)
+	if n != 1 {
+		t.Error("share record should follow the new id")
+	}
+	DB.Model(&model.PeerShare{}).Where("peer_id = ?", "old").Count(&n)
+	if n != 0 {
+		t.Errorf("%d peer shares still use the old id", n)
+	}
+	DB.Model(&model.PeerShare{}).Where("ab_row_id = 0 and peer_id = ?", "new").Count(&n)
+	if n != 2 {
+		t.Errorf("%d direct shares for the new id, want 2 (duplicate removed)", n)
+	}
+	DB.Model(&model.PeerShare{}).Where("ab_row_id = ? and peer_id = ? and to_id = ?", keepAb.RowId, "new", 8).Count(&n)
+	if n != 1 {
+		t.Error("share of the removed entry should follow the kept entry")
+	}
+	var actions []string
+	DB.Model(&model.AddressBookChange{}).Order("user_id").Pluck("action", &actions)
+	if len(actions) != 2 || actions[0] != model.AbChangeActionUpdate || actions[1] != model.AbChangeActionDelete {
+		t.Errorf("address book history = %v, want rename of user 3 entry and removal of user 5 duplicate", actions)
+	}
+	// 两个地址簿的版本号都要增加, 客户端才会重新拉取
+	var revs []uint64
+	DB.Model(&model.AddressBookRevision{}).Order("user_id").Pluck("revision", &revs)
+	if len(revs) != 2 || revs[0] != 1 || revs[1] != 1 {
+		t.Errorf("address book revisions = %v, want both books bumped", revs)
+	}
+	values := map[uint]string{}
+	var attrs []*model.PeerAttributeValue
+	DB.Where("peer_id = ?", "new").Find(&attrs)
+	for _, a := range attrs {
+		values[a.DefId] = a.Value
+	}
+	if len(values) != 2 || values[1] != "AT-1" || values[2] != "new value" {
+		t.Errorf("attributes = %v, want missing one copied and existing one kept", values)
+	}
+	DB.Model(&model.PeerPresenceEvent{}).Where("peer_id = ?", "new").Count(&n)
+	if n != 1 {
+		t.Error("presence history should follow the new id")
+	}
+	DB.Model(&model.StrategyAssignment{}).Where("type = ? and to_id = ?", model.StrategyTargetPeer, cur.RowId).Count(&n)
+	if n != 1 {
+		t.Error("strategy assignment should follow the new peer")
+	}
+	a := &model.PeerAction{}
+	DB.Where("peer_id = ?", "old").First(a)
+	if a.Status != model.PeerActionStatusCanceled {
+		t.Errorf("pending action of the old peer status = %d, want canceled", a.Status)
+	}
+}
+
+func TestAutoMergeRequiresLogin(t *testing.T) {
+	identityTestDB(t, PeerMergeModeAuto)
+	s := &PeerIdentityService{}
+	old := &model.Peer{Id: "old", Uuid: "u", UserId: 3}
+	DB.Create(old)
+
+	// 只凭 uuid 上传系统信息不会合并
+	cur := &model.Peer{Id: "new", Uuid: "u"}
+	DB.Create(cur)
+	s.DetectIdChange(cur)
+	r := &model.PeerIdentityChange{}
+	DB.Where("old_row_id = ? and peer_row_id = ?", old.RowId, cur.RowId).First(r)
+	if r.Id == 0 || r.Status != model.PeerIdentityStatusPending {
+		t.Fatalf("id change = %+v, want pending", r)
+	}
+
+	// 其他用户在该 uuid 下登录也不合并
+	DB.Create(&model.UserToken{UserId: 7, DeviceUuid: "u", DeviceId: "new", Token: "t7", ExpiredAt: time.Now().Add(time.Hour).Unix()})
+	s.MergePendingOnLogin("new", "u")
+	if r = s.InfoById(r.Id); r.Status != model.PeerIdentityStatusPending {
+		t.Fatalf("id change status = %d after another user's login, want pending", r.Status)
+	}
+
+	// 旧设备的所有者登录后合并
+	DB.Create(&model.UserToken{UserId: 3, DeviceUuid: "u", DeviceId: "new", Token: "t3", ExpiredAt: time.Now().Add(time.Hour).Unix()})
+	s.MergePendingOnLogin("new", "u")
+	if r = s.InfoById(r.Id); r.Status != model.PeerIdentityStatusMerged || !r.Auto {
+		t.Fatalf("id change = %+v, want merged automatically", r)
+	}
+	var n int64
+	DB.Model(&model.Peer{}).Where("id = ?", "old").Count(&n)
+	if n != 0 {
+		t.Error("old peer should be deleted after the merge")
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+	"gorm.io/gorm"
+)
+
+// SharedWithMeCollectionId 虚拟地址簿 "与我共享" 的集合 id, 放在 guid 的集合位置, 不会与真实集合冲突
+const SharedWithMeCollectionId uint = math.MaxUint32
+
+var (
+	ErrPeerShareNotFound = errors.New("ItemNotFound")
+	ErrPeerShareExists   = errors.New("ItemExists")
+	ErrPeerShareToSelf   = errors.New("CannotShareToSelf")
+	ErrPeerShareTarget   = errors.New("ParamsError")
+	ErrPeerShareExpiry   = errors.New("InvalidRuleWindow")
+)
+
+type PeerShareService struct {
+}
+
+// activeShare 未到期的共享
+func activeShare(now int64) func(tx *gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("end_at = 0 or end_at > ?", now)
+	}
+}
+
+func (s *PeerShareService) InfoById(id uint) *model.PeerShare {
+	p := &model.PeerShare{}
+	DB.Where("id = ?", id).First(p)
+	return p
+}
+
+func (s *PeerShareService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerShareList) {
+	res = &model.PeerShareList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerShare{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.PeerShares)
+	return
+}
+
+// Check 校验共享对象和有效期, 共享地址簿条目时补全设备 ID. 共享人权限由调用方检查
+func (s *PeerShareService) Check(p *model.PeerShare) error {
+	if p.AbRowId > 0 {
+		ab := AllService.AddressBookService.InfoByRowId(p.AbRowId)
+		if ab.RowId == 0 {
+			return ErrPeerShareNotFound
+		}
+		p.PeerId = ab.Id
+	} else if p.PeerId == "" || AllService.PeerService.FindById(p.PeerId).RowId == 0 {
+		return ErrPeerShareNotFound
+	}
+	switch p.Type {
+	case model.ShareAddressBookRuleTypePersonal:
+		if p.ToId == p.UserId {
+			return ErrPeerShareToSelf
+		}
+		if AllService.UserService.InfoById(p.ToId).Id == 0 {
+			return ErrPeerShareNotFound
+		}
+	case model.ShareAddressBookRuleTypeGroup:
+		if AllService.GroupService.InfoById(p.ToId).Id == 0 {
+			return ErrPeerShareNotFound
+		}
+	default:
+		return ErrPeerShareTarget
+	}
+	if p.EndAt > 0 && p.EndAt <= time.Now().Unix() {
+		return ErrPeerShareExpiry
+	}
+	ex := &model.PeerShare{}
+	DB.Where("ab_row_id = ? and peer_id = ? and type = ? and to_id = ? and id <> ?", p.AbRowId, p.PeerId, p.Type, p.ToId, p.Id).First(ex)
+	if ex.Id > 0 {
+		return ErrPeerShareExists
+	}
+	return nil
+}
+
+func (s *PeerShareService) Create(p *model.PeerShare) error {
+	return DB.Create(p).Error
+}
+
+// Update 只修改级别和有效期, 共享的设备和对象不变
+func (s *PeerShareService) Update(p *model.PeerShare) error {
+	return DB.Model(p).Select("level", "end_at").Updates(p).Error
+}
+
+func (s *PeerShareService) Delete(p *model.PeerShare) error {
+	return DB.Delete(p).Error
+}
+
+// DeleteExpired 删除已到期的共享, 由定时任务调用
+func (s *PeerShareService) DeleteExpired() {
+	res := DB.Where("end_at > 0 and end_at <= ?", time.Now().Unix()).Delete(&model.PeerShare{})
+	if res.Error != nil {
+		Logger.Error("Delete expired peer shares failed: ", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		Logger.Info("Expired peer shares deleted: ", res.RowsAffected)
+	}
+}
+
+// DeleteByUserId 删除用户时清理: 该用户共享出去的、共享给该用户的和共享该用户地址簿条目的
+func (s *PeerShareService) DeleteByUserId(tx *gorm.DB, userId uint) error {
+	sub := tx.Session(&gorm.Session{NewDB: true}).Model(&model.AddressBook{}).Select("row_id").Where("user_id = ?", userId)
+	return tx.Where("user_id = ? or (type = ? and to_id = ?) or ab_row_id in (?)", userId, model.ShareAddressBookRuleTypePersonal, userId, sub).
+		Delete(&model.PeerShare{}).Error
+}
+
+// DeleteByPeerIds 删除设备时清理直接共享该设备的记录, 同一 ID 重新注册时不会恢复访问
+func (s *PeerShareService) DeleteByPeerIds(tx *gorm.DB, peerIds []string) error {
+	if len(peerIds) == 0 {
+		return nil
+	}
+	return tx.Where("ab_row_id = 0 and peer_id in ?", peerIds).Delete(&model.PeerShare{}).Error
+}
+
+// DeleteByAbRowIds 删除地址簿条目时清理共享该条目的记录
+func (s *PeerShareService) DeleteByAbRowIds(tx *gorm.DB, rowIds []uint) error {
+	if len(rowIds) == 0 {
+		return nil
+	}
+	return tx.Where("ab_row_id in ?", rowIds).Delete(&model.PeerShare{}).Error
+}
+
+// DeleteByCollectionId 删除集合时清理共享集合中条目的记录, 需在删除条目之前调用
+func (s *PeerShareService) DeleteByCollectionId(tx *gorm.DB, cid uint) error {
+	sub := tx.Session(&gorm.Session{NewDB: true}).Model(&model.AddressBook{}).Select("row_id").Where("collection_id = ?", cid)
+	return tx.Where("ab_row_id in (?)", sub).Delete(&model.PeerShare{}).Error
+}
+
+// ReceivedShares 共享给用户(个人或所在群组)且未到期的共享
+func (s *PeerShareService) ReceivedShares(user *model.User) []*model.PeerShare {
+	var res []*model.PeerShare
+	DB.Scopes(activeShare(time.Now().Unix())).
+		Where("(type = ? and to_id = ?) or (type = ? and to_id = ?)",
+			model.ShareAddressBookRuleTypePersonal, user.Id, model.ShareAddressBookRuleTypeGroup, user.GroupId).
+		Order("id asc").Find(&res)
+	return res
+}
+
+// SharedWithMe 虚拟地址簿 "与我共享" 的内容. 只读级别不带密码, 共享人已不再拥有该条目或设备时忽略
+func (s *PeerShareService) SharedWithMe(user *model.User) []*model.AddressBook {
+	shares := s.ReceivedShares(user)
+	items := make([]sharedPeer, 0, len(shares))
+	owners := make(map[uint]*model.User)
+	for _, p := range shares {
+		if ab := s.entry(p, owners); ab != nil {
+			items = append(items, sharedPeer{ab: ab, level: p.Level})
+		}
+	}
+	res := dedupeSharedPeers(items)
+	for _, ab := range res {
+		ab.UserId = user.Id
+	}
+	return res
+}
+
+type sharedPeer struct {
+	ab    *model.AddressBook
+	level int
+}
+
+// dedupeSharedPeers 同一设备被多次共享时只保留一次, 取级别最高的共享, 顺序按首次出现
+func dedupeSharedPeers(items []sharedPeer) []*model.AddressBook {
+	res := make([]*model.AddressBook, 0, len(items))
+	index := make(map[string]int)
+	levels := make(map[string]int)
+	for _, it := range items {
+		if i, ok := index[it.ab.Id]; ok {
+			if it.level <= levels[it.ab.Id] {
+				continue
+			}
+			res[i] = it.ab
+		} else {
+			index[it.ab.Id] = len(res)
+			res = append(res, it.ab)
+		}
+		levels[it.ab.Id] = it.level
+	}
+	return res
+}
+
+// CanShare 用户能否共享该条目或设备: 地址簿条目须为本人所有, 设备须属于本人, 管理员可以共享所有设备
+func (s *PeerShareService) CanShare(user *model.User, p *model.PeerShare) bool {
+	if p.AbRowId > 0 {
+		return AllService.AddressBookService.InfoByRowId(p.AbRowId).UserId == user.Id
+	}
+	peer := AllService.PeerService.FindById(p.PeerId)
+	if peer.RowId == 0 {
+		return false
+	}
+	return peer.UserId == user.Id || AllService.UserService.IsAdmin(user)
+}
+
+// entry 共享对应的地址簿条目, 失效时返回 nil
+func (s *PeerShareService) entry(p *model.PeerShare, owners map[uint]*model.User) *model.AddressBook {
+	var ab *model.AddressBook
+	if p.AbRowId > 0 {
+		ab = AllService.AddressBookService.InfoByRowId(p.AbRowId)
+		if ab.RowId == 0 || ab.UserId != p.UserId {
+			return nil
+		}
+		if p.Level < model.PeerShareLevelConnect {
+			ab.Password = ""
+			ab.Hash = ""
+		}
+	} else {
+		peer := AllService.PeerService.FindById(p.PeerId)
+		if peer.RowId == 0 {
+			return nil
+		}
+		owner, ok := owners[p.UserId]
+		if !ok {
+			owner = AllService.UserService.InfoById(p.UserId)
+			owners[p.UserId] = owner
+		}
+		if owner.Id == 0 || (!AllService.UserService.IsAdmin(owner) && peer.UserId != owner.Id) {
+			return nil
+		}
+		ab = &model.AddressBook{
+			Id:       peer.Id,
+			Username: peer.Username,
+			Hostname: peer.Hostname,
+			Alias:    peer.Alias,
+			Platform: AllService.AddressBookService.PlatformFromOs(peer.Os),
+		}
+	}
+	ab.RowId = 0
+	ab.CollectionId = SharedWithMeCollectionId
+	ab.FolderId = 0
+	ab.Tags = custom_types.AutoJson("[]")
+	return ab
+}
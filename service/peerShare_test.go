@@ -0,0 +1,65 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestDedupeSharedPeers(t *testing.T) {
+	read := &model.AddressBook{Id: "100", Alias: "read"}
+	connect := &model.AddressBook{Id: "100", Alias: "connect", Password: "secret"}
+	other := &model.AddressBook{Id: "200"}
+	again := &model.AddressBook{Id: "100", Alias: "read again"}
+	got := dedupeSharedPeers([]sharedPeer{
+		{ab: read, level: model.PeerShareLevelRead},
+		{ab: other, level: model.PeerShareLevelRead},
+		{ab: connect, level: model.PeerShareLevelConnect},
+		{ab: again, level: model.PeerShareLevelRead},
+	})
+	if len(got) != 2 {
+		t.Fatalf("got %d peers, want 2", len(got))
+	}
+	if got[0] != connect || got[1] != other {
+		t.Errorf("got %s, %s; want connect level entry first, then 200", got[0].Alias, got[1].Id)
+	}
+}
+
+func TestPeerShareCleanup(t *testing.T) {
+	newTestDB(t, &model.Peer{}, &model.PeerShare{}, &model.AddressBook{}, &model.AddressBookChange{}, &model.AddressBookRevision{},
+		&model.AddressBookCollection{}, &model.AddressBookCollectionRule{}, &model.AddressBookFolder{},
+		&model.PeerSysinfo{}, &model.PeerAttributeValue{}, &model.PeerPresenceEvent{}, &model.PeerAction{},
+		&model.PeerStrategyState{}, &model.StrategyAssignment{}, &model.PeerIdentityChange{}, &model.UserToken{})
+	peer := &model.Peer{Id: "111"}
+	DB.Create(peer)
+	ab := &model.AddressBook{Id: "222", UserId: 1}
+	colAb := &model.AddressBook{Id: "333", UserId: 1, CollectionId: 5}
+	DB.Create(ab)
+	DB.Create(colAb)
+	DB.Create(&model.PeerShare{UserId: 1, PeerId: "111", Type: model.ShareAddressBookRuleTypePersonal, ToId: 2})
+	DB.Create(&model.PeerShare{UserId: 1, AbRowId: ab.RowId, PeerId: "222", Type: model.ShareAddressBookRuleTypePersonal, ToId: 2})
+	DB.Create(&model.PeerShare{UserId: 1, AbRowId: colAb.RowId, PeerId: "333", Type: model.ShareAddressBookRuleTypePersonal, ToId: 2})
+
+	count := func() (n int64) {
+		DB.Model(&model.PeerShare{}).Count(&n)
+		return
+	}
+	if err := AllService.PeerService.Delete(peer); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("%d shares after peer delete, want 2", n)
+	}
+	if err := AllService.AddressBookService.Delete(ab, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Fatalf("%d shares after entry delete, want 1", n)
+	}
+	if err := AllService.AddressBookService.DeleteCollection(&model.AddressBookCollection{IdModel: model.IdModel{Id: 5}, UserId: 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 0 {
+		t.Fatalf("%d shares after collection delete, want 0", n)
+	}
+}
@@ -0,0 +1,143 @@
+package service
+
+import (
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type PeerSysinfoService struct {
+}
+
+// snapshotFields 参与比较的字段, 顺序即diff输出顺序
+func snapshotFields(s *model.PeerSysinfo) [][2]string {
+	return [][2]string{
+		{"os", s.Os},
+		{"version", s.Version},
+		{"hostname", s.Hostname},
+		{"username", s.Username},
+		{"cpu", s.Cpu},
+		{"memory", s.Memory},
+	}
+}
+
+// DiffSnapshots 比较两个快照, 返回变化的字段, t为变化时间
+func DiffSnapshots(from, to *model.PeerSysinfo, t int64) []*model.PeerSysinfoChange {
+	res := make([]*model.PeerSysinfoChange, 0)
+	ff := snapshotFields(from)
+	tf := snapshotFields(to)
+	for i := range ff {
+		if ff[i][1] != tf[i][1] {
+			res = append(res, &model.PeerSysinfoChange{Field: ff[i][0], From: ff[i][1], To: tf[i][1], Time: t})
+		}
+	}
+	return res
+}
+
+func snapshotFromPeer(p *model.Peer) *model.PeerSysinfo {
+	return &model.PeerSysinfo{
+		PeerId:   p.Id,
+		Cpu:      p.Cpu,
+		Hostname: p.Hostname,
+		Memory:   p.Memory,
+		Os:       p.Os,
+		Username: p.Username,
+		Version:  p.Version,
+	}
+}
+
+// Latest 取设备最新的快照
+func (s *PeerSysinfoService) Latest(peerId string) *model.PeerSysinfo {
+	r := &model.PeerSysinfo{}
+	DB.Where("peer_id = ?", peerId).Order("id desc").First(r)
+	return r
+}
+
+// Record 系统信息与最新快照不同时记录新快照
+func (s *PeerSysinfoService) Record(p *model.Peer) error {
+	if p.Id == "" {
+		return nil
+	}
+	cur := snapshotFromPeer(p)
+	last := s.Latest(p.Id)
+	if last.Id > 0 && len(DiffSnapshots(last, cur, 0)) == 0 {
+		return nil
+	}
+	if err := DB.Create(cur).Error; err != nil {
+		return err
+	}
+	s.trimPeer(p.Id)
+	return nil
+}
+
+// trimPeer 保留每个设备最近的 sysinfo-history-max 条快照
+func (s *PeerSysinfoService) trimPeer(peerId string) {
+	max := Config.App.SysinfoHistoryMax
+	if max <= 0 {
+		return
+	}
+	var ids []uint
+	DB.Model(&model.PeerSysinfo{}).Where("peer_id = ?", peerId).Order("id desc").Offset(max).Pluck("id", &ids)
+	if len(ids) > 0 {
+		DB.Where("id in ?", ids).Delete(&model.PeerSysinfo{})
+	}
+}
+
+// Prune 删除超过 sysinfo-history-days 天的快照, 每个设备至少保留最新一条, 由后台任务定时调用
+func (s *PeerSysinfoService) Prune() {
+	days := Config.App.SysinfoHistoryDays
+	if days <= 0 {
+		return
+	}
+	before := time.Now().AddDate(0, 0, -days)
+	var peerIds []string
+	DB.Model(&model.PeerSysinfo{}).Where("created_at < ?", before).Distinct().Pluck("peer_id", &peerIds)
+	var total int64
+	for _, peerId := range peerIds {
+		latest := s.Latest(peerId)
+		tx := DB.Where("peer_id = ? and created_at < ? and id < ?", peerId, before, latest.Id).Delete(&model.PeerSysinfo{})
+		if tx.Error != nil {
+			Logger.Error("Prune sysinfo history of peer ", peerId, " failed: ", tx.Error)
+			continue
+		}
+		total += tx.RowsAffected
+	}
+	if total > 0 {
+		Logger.Info("Pruned ", total, " peer sysinfo snapshots")
+	}
+}
+
+func (s *PeerSysinfoService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerSysinfoList) {
+	res = &model.PeerSysinfoList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerSysinfo{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.PeerSysinfos)
+	return
+}
+
+// Timeline 返回设备在[from, to]时间范围内的系统信息变化, 按时间先后排序, from/to为0表示不限
+func (s *PeerSysinfoService) Timeline(peerId string, from, to int64) []*model.PeerSysinfoChange {
+	var snapshots []*model.PeerSysinfo
+	DB.Where("peer_id = ?", peerId).Order("id asc").Find(&snapshots)
+	res := make([]*model.PeerSysinfoChange, 0)
+	for i := 1; i < len(snapshots); i++ {
+		t := time.Time(snapshots[i].CreatedAt).Unix()
+		if (from > 0 && t < from) || (to > 0 && t > to) {
+			continue
+		}
+		res = append(res, DiffSnapshots(snapshots[i-1], snapshots[i], t)...)
+	}
+	return res
+}
+
+// DeleteByPeerId 删除设备的全部快照
+func (s *PeerSysinfoService) DeleteByPeerId(peerId string) error {
+	return DB.Where("peer_id = ?", peerId).Delete(&model.PeerSysinfo{}).Error
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+	from := &model.PeerSysinfo{Os: "Windows 10", Version: "1.3.0", Hostname: "pc-01", Cpu: "i5"}
+	to := &model.PeerSysinfo{Os: "Windows 11", Version: "1.3.0", Hostname: "pc-02", Cpu: "i5"}
+	changes := DiffSnapshots(from, to, 100)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+	if changes[0].Field != "os" || changes[0].From != "Windows 10" || changes[0].To != "Windows 11" || changes[0].Time != 100 {
+		t.Errorf("unexpected os change: %+v", changes[0])
+	}
+	if changes[1].Field != "hostname" || changes[1].To != "pc-02" {
+		t.Errorf("unexpected hostname change: %+v", changes[1])
+	}
+	if len(DiffSnapshots(from, from, 0)) != 0 {
+		t.Errorf("expected no change for identical snapshots")
+	}
+}
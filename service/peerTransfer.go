@@ -0,0 +1,423 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	PeerTransferFormatCsv  = "csv"
+	PeerTransferFormatJson = "json"
+
+	// PeerAttrColumnPrefix 自定义字段列名前缀, 如 attr.asset_tag
+	PeerAttrColumnPrefix = "attr."
+
+	peerExportBatchSize = 500
+)
+
+// PeerExportColumns 导出的设备字段, 自定义字段追加在后面
+var PeerExportColumns = []string{"id", "uuid", "hostname", "alias", "username", "os", "version", "cpu", "memory",
+	"group_id", "user_id", "last_online_time", "last_online_ip", "geo_country", "geo_city", "geo_asn"}
+
+// errPeerImportPreview dryRun 时回滚单行的事务
+var errPeerImportPreview = errors.New("peer import preview")
+
+// PeerImportFields 可导入的设备字段
+var PeerImportFields = []string{"id", "uuid", "hostname", "alias", "username", "os", "version", "cpu", "memory",
+	"group_id", "user_id"}
+
+// PeerImportData 解析后的导入数据, Rows 的键为映射后的字段名
+type PeerImportData struct {
+	Ignored []string
+	Rows    []map[string]string
+}
+
+// mapPeerColumn 按 mapping(源列名 => 字段名)转换列名, 未映射的列按同名处理; 返回空表示忽略该列
+func mapPeerColumn(mapping map[string]string, col string) string {
+	col = strings.TrimSpace(col)
+	if m, ok := mapping[col]; ok {
+		col = m
+	}
+	if strings.HasPrefix(col, PeerAttrColumnPrefix) && len(col) > len(PeerAttrColumnPrefix) {
+		return col
+	}
+	for _, f := range PeerImportFields {
+		if f == col {
+			return col
+		}
+	}
+	return ""
+}
+
+func addIgnored(data *PeerImportData, seen map[string]bool, col string) {
+	if !seen[col] {
+		seen[col] = true
+		data.Ignored = append(data.Ignored, col)
+	}
+}
+
+// ReadPeerCSV 读取 CSV, 第一行为表头
+func ReadPeerCSV(r io.Reader, mapping map[string]string) (*PeerImportData, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	// 去掉 Excel 导出的 BOM
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+	data := &PeerImportData{}
+	ignored := make(map[string]bool)
+	fields := make([]string, len(header))
+	for i, col := range header {
+		fields[i] = mapPeerColumn(mapping, col)
+		if fields[i] == "" {
+			addIgnored(data, ignored, col)
+		}
+	}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string)
+		for i, v := range record {
+			if i < len(fields) && fields[i] != "" {
+				row[fields[i]] = strings.TrimSpace(v)
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	return data, nil
+}
+
+// ReadPeerJSON 读取 JSON 数组, attributes 对象展开为 attr.* 字段
+func ReadPeerJSON(r io.Reader, mapping map[string]string) (*PeerImportData, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var items []map[string]interface{}
+	if err := dec.Decode(&items); err != nil {
+		return nil, err
+	}
+	data := &PeerImportData{}
+	ignored := make(map[string]bool)
+	for _, item := range items {
+		row := make(map[string]string)
+		for k, v := range item {
+			if attrs, ok := v.(map[string]interface{}); ok && k == "attributes" {
+				for name, av := range attrs {
+					if av != nil {
+						row[PeerAttrColumnPrefix+name] = strings.TrimSpace(fmt.Sprint(av))
+					}
+				}
+				continue
+			}
+			f := mapPeerColumn(mapping, k)
+			if f == "" {
+				addIgnored(data, ignored, k)
+				continue
+			}
+			if v != nil {
+				row[f] = strings.TrimSpace(fmt.Sprint(v))
+			}
+		}
+		data.Rows = append(data.Rows, row)
+	}
+	sort.Strings(data.Ignored)
+	return data, nil
+}
+
+// ExportColumns 导出的列, 包含自定义字段
+func (ps *PeerService) ExportColumns() []string {
+	cols := append([]string{}, PeerExportColumns...)
+	var defs []*model.PeerAttributeDef
+	DB.Order("sort asc, id asc").Find(&defs)
+	for _, d := range defs {
+		cols = append(cols, PeerAttrColumnPrefix+d.Name)
+	}
+	return cols
+}
+
+// PeerExportRecord 设备转为导出行
+func PeerExportRecord(p *model.Peer, columns []string) []string {
+	rec := make([]string, len(columns))
+	for i, col := range columns {
+		switch col {
+		case "id":
+			rec[i] = p.Id
+		case "uuid":
+			rec[i] = p.Uuid
+		case "hostname":
+			rec[i] = p.Hostname
+		case "alias":
+			rec[i] = p.Alias
+		case "username":
+			rec[i] = p.Username
+		case "os":
+			rec[i] = p.Os
+		case "version":
+			rec[i] = p.Version
+		case "cpu":
+			rec[i] = p.Cpu
+		case "memory":
+			rec[i] = p.Memory
+		case "group_id":
+			rec[i] = strconv.FormatUint(uint64(p.GroupId), 10)
+		case "user_id":
+			rec[i] = strconv.FormatUint(uint64(p.UserId), 10)
+		case "last_online_time":
+			rec[i] = strconv.FormatInt(p.LastOnlineTime, 10)
+		case "last_online_ip":
+			rec[i] = p.LastOnlineIp
+		case "geo_country":
+			rec[i] = p.GeoInfo.Country
+		case "geo_city":
+			rec[i] = p.GeoInfo.City
+		case "geo_asn":
+			rec[i] = strconv.FormatUint(uint64(p.GeoInfo.Asn), 10)
+		default:
+			rec[i] = p.Attributes[strings.TrimPrefix(col, PeerAttrColumnPrefix)]
+		}
+	}
+	return rec
+}
+
+// peerExportNumberColumns 导出 JSON 时为数字的列
+var peerExportNumberColumns = map[string]bool{"group_id": true, "user_id": true, "last_online_time": true, "geo_asn": true}
+
+// PeerExportJSON 设备转为导出的 JSON 对象, 列与 CSV 相同, 自定义字段放在 attributes 中, 与 ReadPeerJSON 对应
+func PeerExportJSON(p *model.Peer, columns []string) ([]byte, error) {
+	rec := PeerExportRecord(p, columns)
+	buf := &bytes.Buffer{}
+	buf.WriteByte('{')
+	attrs := make([]int, 0)
+	n := 0
+	for i, col := range columns {
+		if strings.HasPrefix(col, PeerAttrColumnPrefix) {
+			attrs = append(attrs, i)
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		k, _ := json.Marshal(col)
+		buf.Write(k)
+		buf.WriteByte(':')
+		if peerExportNumberColumns[col] {
+			buf.WriteString(rec[i])
+			continue
+		}
+		v, err := json.Marshal(rec[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	if n > 0 {
+		buf.WriteByte(',')
+	}
+	buf.WriteString(`"attributes":{`)
+	n = 0
+	for _, i := range attrs {
+		// 没有值的字段不导出, 导入时不会清空
+		if rec[i] == "" {
+			continue
+		}
+		if n > 0 {
+			buf.WriteByte(',')
+		}
+		n++
+		k, _ := json.Marshal(strings.TrimPrefix(columns[i], PeerAttrColumnPrefix))
+		v, err := json.Marshal(rec[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteString("}}")
+	return buf.Bytes(), nil
+}
+
+// ExportBatches 按条件分批读取设备并填充自定义字段, 避免一次性加载整张表.
+// 条件中可能带有用户排序, 所以按偏移分页而不是按主键, 主键只作为排序的最后一列
+func (ps *PeerService) ExportBatches(where func(tx *gorm.DB), fn func(peers []*model.Peer) error) (int, error) {
+	return exportPeerBatches(where, peerExportBatchSize, fn)
+}
+
+func exportPeerBatches(where func(tx *gorm.DB), size int, fn func(peers []*model.Peer) error) (int, error) {
+	total := 0
+	for offset := 0; ; offset += size {
+		tx := DB.Model(&model.Peer{})
+		if where != nil {
+			where(tx)
+		}
+		var batch []*model.Peer
+		if err := tx.Order("row_id").Limit(size).Offset(offset).Find(&batch).Error; err != nil {
+			return total, err
+		}
+		if len(batch) == 0 {
+			return total, nil
+		}
+		AllService.PeerAttributeService.FillPeers(batch)
+		total += len(batch)
+		if err := fn(batch); err != nil {
+			return total, err
+		}
+		if len(batch) < size {
+			return total, nil
+		}
+	}
+}
+
+type peerImportContext struct {
+	defs     map[string]*model.PeerAttributeDef
+	groupIds map[uint]bool
+	userIds  map[uint]bool
+}
+
+func idSet(m interface{}) map[uint]bool {
+	var ids []uint
+	DB.Model(m).Pluck("id", &ids)
+	set := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// parseImportRow 校验单行, 返回要写入的设备字段和自定义字段
+func (c *peerImportContext) parseImportRow(row map[string]string, creating bool) (map[string]interface{}, map[string]string, error) {
+	fields := make(map[string]interface{})
+	attrs := make(map[string]string)
+	for k, v := range row {
+		if strings.HasPrefix(k, PeerAttrColumnPrefix) {
+			name := strings.TrimPrefix(k, PeerAttrColumnPrefix)
+			d, ok := c.defs[name]
+			if !ok {
+				return nil, nil, fmt.Errorf("unknown attribute %s", name)
+			}
+			if err := ValidateValue(d, v); err != nil {
+				return nil, nil, err
+			}
+			attrs[name] = v
+			continue
+		}
+		switch k {
+		case "id":
+			continue
+		case "group_id", "user_id":
+			if v == "" {
+				v = "0"
+			}
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s must be a number", k)
+			}
+			id := uint(n)
+			if id > 0 && k == "group_id" && !c.groupIds[id] {
+				return nil, nil, fmt.Errorf("device group %d not found", id)
+			}
+			if id > 0 && k == "user_id" && !c.userIds[id] {
+				return nil, nil, fmt.Errorf("user %d not found", id)
+			}
+			fields[k] = id
+		default:
+			if len(v) > 255 {
+				return nil, nil, fmt.Errorf("%s is too long", k)
+			}
+			fields[k] = v
+		}
+	}
+	if creating {
+		for name, d := range c.defs {
+			if _, ok := attrs[name]; !ok && d.Required {
+				return nil, nil, fmt.Errorf("%s is required", name)
+			}
+		}
+	}
+	return fields, attrs, nil
+}
+
+// Import 按 RustDesk id 新增或更新设备, 出错的行跳过并记录; dryRun 时只校验
+func (ps *PeerService) Import(data *PeerImportData, dryRun bool) *model.PeerImportReport {
+	report := &model.PeerImportReport{DryRun: dryRun, Total: len(data.Rows), IgnoredColumns: data.Ignored}
+	ctx := &peerImportContext{
+		defs:     AllService.PeerAttributeService.AllDefs(),
+		groupIds: idSet(&model.DeviceGroup{}),
+		userIds:  idSet(&model.User{}),
+	}
+	seen := make(map[string]int)
+	for i, row := range data.Rows {
+		rowNo := i + 1
+		id := row["id"]
+		fail := func(err error) {
+			report.Failed++
+			report.Errors = append(report.Errors, &model.PeerImportRowError{Row: rowNo, Id: id, Error: err.Error()})
+		}
+		if id == "" {
+			fail(errors.New("id is required"))
+			continue
+		}
+		if first, ok := seen[id]; ok {
+			fail(fmt.Errorf("duplicate id, first seen at row %d", first))
+			continue
+		}
+		seen[id] = rowNo
+		ex := ps.FindById(id)
+		fields, attrs, err := ctx.parseImportRow(row, ex.RowId == 0)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		// 设备和自定义字段在同一事务中写入; dryRun 时执行后回滚, 写入错误也能预览
+		err = DB.Transaction(func(tx *gorm.DB) error {
+			p := ex
+			if p.RowId == 0 {
+				p = &model.Peer{Id: id}
+				if err := tx.Create(p).Error; err != nil {
+					return err
+				}
+			}
+			if len(fields) > 0 {
+				if err := tx.Model(p).Updates(fields).Error; err != nil {
+					return err
+				}
+			}
+			if err := setAttributeValues(tx, ctx.defs, id, attrs); err != nil {
+				return err
+			}
+			if dryRun {
+				return errPeerImportPreview
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errPeerImportPreview) {
+			fail(err)
+			continue
+		}
+		if ex.RowId == 0 {
+			report.Created++
+		} else {
+			report.Updated++
+		}
+	}
+	return report
+}
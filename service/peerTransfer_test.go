@@ -0,0 +1,117 @@
+package service
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func TestReadPeerCSV(t *testing.T) {
+	in := "\ufeffRustDesk ID,Nom,Inventaire,row_id\n123456789,pc-compta,A-42,7\n987654321, pc-rh ,,8\n"
+	mapping := map[string]string{"RustDesk ID": "id", "Nom": "hostname", "Inventaire": "attr.asset_tag"}
+	data, err := ReadPeerCSV(strings.NewReader(in), mapping)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data.Ignored, []string{"row_id"}) {
+		t.Errorf("ignored = %v", data.Ignored)
+	}
+	want := []map[string]string{
+		{"id": "123456789", "hostname": "pc-compta", "attr.asset_tag": "A-42"},
+		{"id": "987654321", "hostname": "pc-rh", "attr.asset_tag": ""},
+	}
+	if !reflect.DeepEqual(data.Rows, want) {
+		t.Errorf("rows = %v", data.Rows)
+	}
+}
+
+func TestReadPeerJSON(t *testing.T) {
+	in := `[{"id":"123456789","group_id":3,"alias":null,"online":true,"attributes":{"asset_tag":"A-42"}}]`
+	data, err := ReadPeerJSON(strings.NewReader(in), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(data.Ignored, []string{"online"}) {
+		t.Errorf("ignored = %v", data.Ignored)
+	}
+	want := []map[string]string{{"id": "123456789", "group_id": "3", "attr.asset_tag": "A-42"}}
+	if !reflect.DeepEqual(data.Rows, want) {
+		t.Errorf("rows = %v", data.Rows)
+	}
+}
+
+func TestPeerExportRecord(t *testing.T) {
+	p := &model.Peer{Id: "123456789", Hostname: "pc-compta", GroupId: 3, Attributes: map[string]string{"asset_tag": "A-42"}}
+	got := PeerExportRecord(p, []string{"id", "hostname", "group_id", "attr.asset_tag", "attr.missing"})
+	want := []string{"123456789", "pc-compta", "3", "A-42", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestExportPeerBatchesWithSort(t *testing.T) {
+	newTestDB(t, &model.Peer{}, &model.PeerAttributeDef{}, &model.PeerAttributeValue{})
+	// last_online_time 与 row_id 顺序相反, 按 row_id 分页会漏掉设备
+	for i, ts := range []int64{10, 50, 40, 30, 20} {
+		DB.Create(&model.Peer{Id: strconv.Itoa(i), LastOnlineTime: ts})
+	}
+	where, err := AllService.SearchService.Compile(SearchResourcePeer, "", "-last_online")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	total, err := exportPeerBatches(where, 2, func(peers []*model.Peer) error {
+		for _, p := range peers {
+			got = append(got, p.LastOnlineTime)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 5 || !reflect.DeepEqual(got, []int64{50, 40, 30, 20, 10}) {
+		t.Errorf("exported %d peers in order %v", total, got)
+	}
+}
+
+func TestPeerExportJSONRoundTrip(t *testing.T) {
+	p := &model.Peer{Id: "123456789", Hostname: "pc-compta", GroupId: 3, Attributes: map[string]string{"asset_tag": "A-42"}}
+	b, err := PeerExportJSON(p, []string{"id", "hostname", "group_id", "last_online_time", "attr.asset_tag", "attr.missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"id":"123456789","hostname":"pc-compta","group_id":3,"last_online_time":0,"attributes":{"asset_tag":"A-42"}}`
+	if string(b) != want {
+		t.Fatalf("json = %s", b)
+	}
+	data, err := ReadPeerJSON(strings.NewReader("["+string(b)+"]"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := map[string]string{"id": "123456789", "hostname": "pc-compta", "group_id": "3", "attr.asset_tag": "A-42"}
+	if !reflect.DeepEqual(data.Rows, []map[string]string{row}) || !reflect.DeepEqual(data.Ignored, []string{"last_online_time"}) {
+		t.Errorf("rows = %v, ignored = %v", data.Rows, data.Ignored)
+	}
+}
+
+func TestPeerImportDryRunWithAttributes(t *testing.T) {
+	newTestDB(t, &model.Peer{}, &model.PeerAttributeDef{}, &model.PeerAttributeValue{}, &model.DeviceGroup{}, &model.User{})
+	DB.Create(&model.PeerAttributeDef{Name: "asset_tag", Type: model.PeerAttributeTypeString})
+	data := &PeerImportData{Rows: []map[string]string{{"id": "1", "attr.asset_tag": "A-1"}}}
+	report := AllService.PeerService.Import(data, true)
+	var peers, values int64
+	DB.Model(&model.Peer{}).Count(&peers)
+	DB.Model(&model.PeerAttributeValue{}).Count(&values)
+	if report.Created != 1 || report.Failed != 0 || peers != 0 || values != 0 {
+		t.Fatalf("dry run report %+v wrote %d peers, %d values", report, peers, values)
+	}
+	report = AllService.PeerService.Import(data, false)
+	DB.Model(&model.Peer{}).Count(&peers)
+	DB.Model(&model.PeerAttributeValue{}).Count(&values)
+	if report.Created != 1 || peers != 1 || values != 1 {
+		t.Fatalf("import report %+v wrote %d peers, %d values", report, peers, values)
+	}
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type PresenceService struct {
+}
+
+const (
+	defaultPresenceTimeout = 90 * time.Second
+	// 心跳最多每30s更新一次last_online_time, 超时时间不能太短
+	minPresenceTimeout = 60 * time.Second
+)
+
+// Timeout 超过该时间没有心跳视为下线
+func (s *PresenceService) Timeout() time.Duration {
+	if Config == nil || Config.App.PresenceTimeout == 0 {
+		return defaultPresenceTimeout
+	}
+	if Config.App.PresenceTimeout < minPresenceTimeout {
+		return minPresenceTimeout
+	}
+	return Config.App.PresenceTimeout
+}
+
+// Touch 收到心跳时调用, 设备之前离线则标记为上线并记录事件
+func (s *PresenceService) Touch(peer *model.Peer, ip string) {
+	if peer.Online {
+		return
+	}
+	now := time.Now().Unix()
+	tx := DB.Model(&model.Peer{}).Where("row_id = ? and online = ?", peer.RowId, false).
+		Updates(map[string]interface{}{"online": true, "online_since": now, "stale": false})
+	if tx.Error != nil {
+		Logger.Error("Mark peer ", peer.Id, " online failed: ", tx.Error)
+		return
+	}
+	// 并发心跳时只记录一次
+	if tx.RowsAffected == 0 {
+		return
+	}
+	peer.Online = true
+	peer.OnlineSince = now
+	s.recordEvent(peer.Id, true, ip, now)
+}
+
+// SweepOffline 将超时未心跳的设备标记为下线, 由后台任务定时调用
+func (s *PresenceService) SweepOffline() {
+	now := time.Now()
+	threshold := now.Add(-s.Timeout()).Unix()
+	var peers []*model.Peer
+	DB.Where("online = ? and last_online_time < ?", true, threshold).Find(&peers)
+	for _, p := range peers {
+		tx := DB.Model(&model.Peer{}).Where("row_id = ? and online = ? and last_online_time < ?", p.RowId, true, threshold).
+			Updates(map[string]interface{}{"online": false, "online_since": 0})
+		if tx.Error != nil {
+			Logger.Error("Mark peer ", p.Id, " offline failed: ", tx.Error)
+			continue
+		}
+		if tx.RowsAffected == 0 {
+			continue
+		}
+		s.recordEvent(p.Id, false, p.LastOnlineIp, now.Unix())
+	}
+}
+
+func (s *PresenceService) recordEvent(peerId string, online bool, ip string, t int64) {
+	e := &model.PeerPresenceEvent{PeerId: peerId, Online: online, Ip: ip, Time: t}
+	if err := DB.Create(e).Error; err != nil {
+		Logger.Error("Record presence event of peer ", peerId, " failed: ", err)
+	}
+}
+
+// DeleteEventsByPeerId 删除设备的全部上下线记录
+func (s *PresenceService) DeleteEventsByPeerId(peerId string) error {
+	return DB.Where("peer_id = ?", peerId).Delete(&model.PeerPresenceEvent{}).Error
+}
+
+func (s *PresenceService) ListEvents(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerPresenceEventList) {
+	res = &model.PeerPresenceEventList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerPresenceEvent{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.PeerPresenceEvents)
+	return
+}
+
+// OnlineIds 返回ids中当前在线的设备id
+func (s *PresenceService) OnlineIds(ids []string) map[string]bool {
+	res := make(map[string]bool)
+	if len(ids) == 0 {
+		return res
+	}
+	var online []string
+	DB.Model(&model.Peer{}).Where("id in ? and online = ?", ids, true).Pluck("id", &online)
+	for _, id := range online {
+		res[id] = true
+	}
+	return res
+}
+
+// FillAddressBookOnline 根据设备在线状态填充地址簿的Online字段
+func (s *PresenceService) FillAddressBookOnline(abs []*model.AddressBook) {
+	ids := make([]string, 0, len(abs))
+	for _, ab := range abs {
+		ids = append(ids, ab.Id)
+	}
+	online := s.OnlineIds(ids)
+	for _, ab := range abs {
+		ab.Online = online[ab.Id]
+	}
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/search"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+const (
+	SearchResourcePeer      = "peer"
+	SearchResourceUser      = "user"
+	SearchResourceAuditConn = "audit_conn"
+	SearchResourceAuditFile = "audit_file"
+)
+
+// searchResources 各列表可筛选的字段白名单, Sortable 只标记有索引的列
+var searchResources = map[string]*search.Resource{
+	SearchResourcePeer: {
+		Fields: map[string]*search.Field{
+			"row_id":      {Column: "row_id", Type: search.TypeNumber, Sortable: true},
+			"id":          {Column: "id", Type: search.TypeString, Sortable: true},
+			"uuid":        {Column: "uuid", Type: search.TypeString, Sortable: true},
+			"hostname":    {Column: "hostname", Type: search.TypeString},
+			"alias":       {Column: "alias", Type: search.TypeString, Sortable: true},
+			"username":    {Column: "username", Type: search.TypeString},
+			"os":          {Column: "os", Type: search.TypeString},
+			"version":     {Column: "version", Type: search.TypeString},
+			"cpu":         {Column: "cpu", Type: search.TypeString},
+			"memory":      {Column: "memory", Type: search.TypeString},
+			"ip":          {Column: "last_online_ip", Type: search.TypeString},
+			"country":     {Column: "geo_country", Type: search.TypeString, Sortable: true},
+			"city":        {Column: "geo_city", Type: search.TypeString},
+			"asn":         {Column: "geo_asn", Type: search.TypeNumber},
+			"asn_org":     {Column: "geo_asn_org", Type: search.TypeString},
+			"last_online": {Column: "last_online_time", Type: search.TypeUnix, Sortable: true},
+			"online":      {Column: "online", Type: search.TypeBool, Sortable: true},
+			"stale":       {Column: "stale", Type: search.TypeBool, Sortable: true},
+			"group_id":    {Column: "group_id", Type: search.TypeNumber, Sortable: true},
+			"group":       {Column: "group_id", Ref: &search.Ref{Model: &model.DeviceGroup{}, Column: "name"}},
+			"user_id":     {Column: "user_id", Type: search.TypeNumber, Sortable: true},
+			"user":        {Column: "user_id", Ref: &search.Ref{Model: &model.User{}, Column: "username"}},
+			"created":     {Column: "created_at", Type: search.TypeTime},
+		},
+		Text: []string{"id", "hostname", "alias", "username"},
+	},
+	SearchResourceUser: {
+		Fields: map[string]*search.Field{
+			"id":       {Column: "id", Type: search.TypeNumber, Sortable: true},
+			"username": {Column: "username", Type: search.TypeString, Sortable: true},
+			"email":    {Column: "email", Type: search.TypeString, Sortable: true},
+			"nickname": {Column: "nickname", Type: search.TypeString},
+			"remark":   {Column: "remark", Type: search.TypeString},
+			"status":   {Column: "status", Type: search.TypeNumber},
+			"admin":    {Column: "is_admin", Type: search.TypeBool},
+			"group_id": {Column: "group_id", Type: search.TypeNumber, Sortable: true},
+			"group":    {Column: "group_id", Ref: &search.Ref{Model: &model.Group{}, Column: "name"}},
+			"created":  {Column: "created_at", Type: search.TypeTime},
+		},
+		Text: []string{"username", "email", "nickname"},
+	},
+	SearchResourceAuditConn: {
+		Fields: map[string]*search.Field{
+			"id":        {Column: "id", Type: search.TypeNumber, Sortable: true},
+			"peer":      {Column: "peer_id", Type: search.TypeString, Sortable: true},
+			"conn_id":   {Column: "conn_id", Type: search.TypeNumber, Sortable: true},
+			"from_peer": {Column: "from_peer", Type: search.TypeString},
+			"from_name": {Column: "from_name", Type: search.TypeString},
+			"ip":        {Column: "ip", Type: search.TypeString},
+			"country":   {Column: "geo_country", Type: search.TypeString, Sortable: true},
+			"city":      {Column: "geo_city", Type: search.TypeString},
+			"asn":       {Column: "geo_asn", Type: search.TypeNumber},
+			"asn_org":   {Column: "geo_asn_org", Type: search.TypeString},
+			"action":    {Column: "action", Type: search.TypeString},
+			"type":      {Column: "type", Type: search.TypeNumber},
+			"closed":    {Column: "close_time", Type: search.TypeUnix},
+			"created":   {Column: "created_at", Type: search.TypeTime},
+		},
+		Text: []string{"peer", "from_peer", "from_name"},
+	},
+	SearchResourceAuditFile: {
+		Fields: map[string]*search.Field{
+			"id":        {Column: "id", Type: search.TypeNumber, Sortable: true},
+			"peer":      {Column: "peer_id", Type: search.TypeString, Sortable: true},
+			"from_peer": {Column: "from_peer", Type: search.TypeString, Sortable: true},
+			"from_name": {Column: "from_name", Type: search.TypeString},
+			"path":      {Column: "path", Type: search.TypeString},
+			"ip":        {Column: "ip", Type: search.TypeString},
+			"is_file":   {Column: "is_file", Type: search.TypeBool},
+			"type":      {Column: "type", Type: search.TypeNumber},
+			"created":   {Column: "created_at", Type: search.TypeTime},
+		},
+		Text: []string{"peer", "from_peer", "path"},
+	},
+}
+
+type SearchService struct {
+}
+
+// SearchFields 返回资源可用的字段, 供前端提示
+func (s *SearchService) SearchFields(resource string) ([]*search.FieldInfo, error) {
+	r, ok := searchResources[resource]
+	if !ok {
+		return nil, search.ErrUnknownResource
+	}
+	return r.Describe(), nil
+}
+
+// Compile 解析筛选表达式和排序, 表达式和排序都为空时返回 nil
+func (s *SearchService) Compile(resource, q, sort string) (func(tx *gorm.DB), error) {
+	r, ok := searchResources[resource]
+	if !ok {
+		return nil, search.ErrUnknownResource
+	}
+	if q == "" && sort == "" {
+		return nil, nil
+	}
+	return r.Compile(q, sort, time.Now())
+}
+
+func (s *SearchService) SavedInfoById(id uint) *model.SavedSearch {
+	r := &model.SavedSearch{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *SearchService) ListSaved(page, pageSize uint, where func(tx *gorm.DB)) (res *model.SavedSearchList) {
+	res = &model.SavedSearchList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.SavedSearch{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.SavedSearches)
+	return
+}
+
+// ValidateSaved 保存前先编译一次, 避免保存无法使用的表达式
+func (s *SearchService) ValidateSaved(r *model.SavedSearch) error {
+	_, err := s.Compile(r.Resource, r.Query, r.Sort)
+	return err
+}
+
+func (s *SearchService) CreateSaved(r *model.SavedSearch) error {
+	return DB.Create(r).Error
+}
+
+func (s *SearchService) UpdateSaved(r *model.SavedSearch) error {
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+func (s *SearchService) DeleteSaved(r *model.SavedSearch) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteSavedByUserId 删除用户的全部保存的筛选
+func (s *SearchService) DeleteSavedByUserId(tx *gorm.DB, userId uint) error {
+	return tx.Where("user_id = ?", userId).Delete(&model.SavedSearch{}).Error
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/secret"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+)
+
+var ErrSecretDisabled = errors.New("encryption master key is not configured")
+
+// secretColumns 使用 secret 序列化的字段, 地址簿变更记录快照中的密码另外处理(见 Migrate)
+var secretColumns = []struct {
+	model   interface{}
+	pk      string
+	columns []string
+}{
+	{&model.AddressBook{}, "row_id", []string{"password", "hash"}},
+	{&model.Oauth{}, "id", []string{"client_secret"}},
+}
+
+type SecretService struct {
+}
+
+// SecretMigrateResult 每张表重新加密的行数
+type SecretMigrateResult map[string]int
+
+func (r SecretMigrateResult) Total() (n int) {
+	for _, v := range r {
+		n += v
+	}
+	return
+}
+
+func (r SecretMigrateResult) String() string {
+	return fmt.Sprint(map[string]int(r))
+}
+
+// Migrate 加密明文; rotate 时同时用当前主密钥重新加密使用旧主密钥的值
+func (s *SecretService) Migrate(rotate bool) (SecretMigrateResult, error) {
+	kr := secret.Current()
+	if kr == nil {
+		return nil, ErrSecretDisabled
+	}
+	// 满足前缀的值不需要处理
+	prefix := secret.Prefix
+	if rotate {
+		prefix += kr.KeyId() + ":"
+	}
+	res := SecretMigrateResult{}
+	for _, t := range secretColumns {
+		where := ""
+		args := make([]interface{}, 0, len(t.columns))
+		for i, col := range t.columns {
+			if i > 0 {
+				where += " or "
+			}
+			where += "(" + col + " <> '' and " + col + " not like ?)"
+			args = append(args, prefix+"%")
+		}
+		err := migrateSecretRows(t.model, t.pk, t.columns, where, args, res, func(v string) (string, bool, error) {
+			if !kr.NeedsRewrap(v) {
+				return v, false, nil
+			}
+			enc, err := kr.Rewrap(v)
+			return enc, true, err
+		})
+		if err != nil {
+			return res, err
+		}
+	}
+	// 地址簿变更记录中设备快照的密码和 hash, 快照为 JSON, 先按非空且前缀不符筛选
+	where := ""
+	args := make([]interface{}, 0)
+	for _, col := range []string{"before", "after"} {
+		for _, field := range []string{"password", "hash"} {
+			if where != "" {
+				where += " or "
+			}
+			where += "(" + col + " like ? and " + col + " not like ? and " + col + " not like ?)"
+			args = append(args, `%"`+field+`":"%`, `%"`+field+`":""%`, `%"`+field+`":"`+prefix+"%")
+		}
+	}
+	err := migrateSecretRows(&model.AddressBookChange{}, "id", []string{"before", "after"},
+		"entity = ? and ("+where+")", append([]interface{}{model.AbChangeEntityPeer}, args...), res,
+		func(v string) (string, bool, error) {
+			return rewrapPeerSnapshot(kr, v)
+		})
+	return res, err
+}
+
+// migrateSecretRows 分批处理满足 where 的行, rewrap 返回新值和是否修改
+func migrateSecretRows(m interface{}, pk string, columns []string, where string, args []interface{},
+	res SecretMigrateResult, rewrap func(v string) (string, bool, error)) error {
+	stmt := DB.Model(m)
+	if err := stmt.Statement.Parse(m); err != nil {
+		return err
+	}
+	table := stmt.Statement.Table
+	var last interface{} = 0
+	for {
+		var rows []map[string]interface{}
+		err := DB.Table(table).Select(append([]string{pk}, columns...)).
+			Where(pk+" > ?", last).Where(where, args...).
+			Order(pk).Limit(500).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			up := map[string]interface{}{}
+			for _, col := range columns {
+				v, changed, err := rewrap(columnString(row[col]))
+				if err != nil {
+					return fmt.Errorf("%s %v: %w", table, row[pk], err)
+				}
+				if changed {
+					up[col] = v
+				}
+			}
+			if len(up) > 0 {
+				if err := DB.Table(table).Where(pk+" = ?", row[pk]).UpdateColumns(up).Error; err != nil {
+					return err
+				}
+				res[table]++
+			}
+			last = row[pk]
+		}
+		if len(rows) < 500 {
+			return nil
+		}
+	}
+}
+
+// rewrapPeerSnapshot 重新加密设备快照中的密码和 hash, 其他字段不变
+func rewrapPeerSnapshot(kr *secret.Keyring, v string) (string, bool, error) {
+	if isNullJson(custom_types.AutoJson(v)) {
+		return v, false, nil
+	}
+	p := &abPeerSnapshot{}
+	if err := json.Unmarshal([]byte(v), p); err != nil {
+		return v, false, err
+	}
+	changed := false
+	for _, f := range []*string{&p.Password, &p.Hash} {
+		if !kr.NeedsRewrap(*f) {
+			continue
+		}
+		enc, err := kr.Rewrap(*f)
+		if err != nil {
+			return v, false, err
+		}
+		*f = enc
+		changed = true
+	}
+	if !changed {
+		return v, false, nil
+	}
+	b, err := json.Marshal(p)
+	return string(b), true, err
+}
+
+func columnString(v interface{}) string {
+	switch s := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(s)
+	case string:
+		return s
+	default:
+		return fmt.Sprint(s)
+	}
+}
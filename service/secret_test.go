@@ -0,0 +1,95 @@
+package service
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/lib/secret"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"github.com/RobertLesgros/rustdesk-interface/v2/model/custom_types"
+)
+
+func TestSecretMigrateHistorySnapshots(t *testing.T) {
+	db := newTestDB(t, &model.AddressBook{}, &model.Oauth{}, &model.AddressBookChange{})
+	prev := secret.Current()
+	t.Cleanup(func() { secret.SetKeyring(prev) })
+
+	oldKey, err := secret.ParseKey(secret.GenerateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKr, err := secret.NewKeyring(oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 旧主密钥加密的快照
+	secret.SetKeyring(oldKr)
+	enc, err := newPeerSnapshot(&model.AddressBook{Id: "1", Password: "old-pw"}).encode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 加密前写入的明文快照
+	plain := custom_types.AutoJson(`{"id":"2","password":"plain-pw","hash":"h"}`)
+	rows := []*model.AddressBookChange{
+		{Entity: model.AbChangeEntityPeer, Before: custom_types.AutoJson("null"), After: enc},
+		{Entity: model.AbChangeEntityPeer, Before: plain, After: custom_types.AutoJson("null")},
+		{Entity: model.AbChangeEntityPeer, Before: custom_types.AutoJson(`{"id":"3","password":"","hash":""}`), After: custom_types.AutoJson("null")},
+	}
+	for _, r := range rows {
+		if err := db.Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cur, err := secret.ParseKey(secret.GenerateKey())
+	if err != nil {
+		t.Fatal(err)
+	}
+	kr, err := secret.NewKeyring(cur, oldKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.SetKeyring(kr)
+	s := &SecretService{}
+	res, err := s.Migrate(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["address_book_changes"] != 1 {
+		t.Fatalf("migrate = %v", res)
+	}
+	res, err = s.Migrate(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res["address_book_changes"] != 1 {
+		t.Fatalf("rotate = %v", res)
+	}
+	res, err = s.Migrate(true)
+	if err != nil || res.Total() != 0 {
+		t.Fatalf("second rotate = %v %v", res, err)
+	}
+
+	// 只保留新主密钥也能解密
+	onlyCur, err := secret.NewKeyring(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	secret.SetKeyring(onlyCur)
+	var got []*model.AddressBookChange
+	db.Order("id").Find(&got)
+	if strings.Contains(string(got[1].Before), "plain-pw") {
+		t.Fatalf("plaintext left: %s", got[1].Before)
+	}
+	p, err := decodePeerSnapshot(got[0].After)
+	if err != nil || p.Password != "old-pw" {
+		t.Fatalf("rotated snapshot = %+v %v", p, err)
+	}
+	p, err = decodePeerSnapshot(got[1].Before)
+	if err != nil || p.Password != "plain-pw" || p.Hash != "h" {
+		t.Fatalf("migrated snapshot = %+v %v", p, err)
+	}
+	if string(got[2].Before) != `{"id":"3","password":"","hash":""}` {
+		t.Fatalf("empty secrets rewritten: %s", got[2].Before)
+	}
+}
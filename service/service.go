@@ -24,6 +24,26 @@ type Service struct {
 	*ServerCmdService
 	*LdapService
 	*AppService
+	*AccessPolicyService
+	*PresenceService
+	*PeerSysinfoService
+	*StaleDeviceService
+	*PeerAttributeService
+	*DeviceGroupRuleService
+	*DeviceGroupPermissionService
+	*StrategyService
+	*PeerActionService
+	*ClientVersionService
+	*SearchService
+	*GeoipService
+	*PeerIdentityService
+	*SecretService
+	*AddressBookHistoryService
+	*AddressBookRevisionService
+	*NotificationService
+	*AddressBookFolderService
+	*GlobalTagService
+	*PeerShareService
 }
 
 type Dependencies struct {
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type StaleDeviceService struct {
+}
+
+// StaleDevicePreview 按当前策略执行时会被标记和删除的设备
+type StaleDevicePreview struct {
+	ToFlag   []*model.Peer `json:"to_flag"`
+	ToDelete []*model.Peer `json:"to_delete"`
+}
+
+// GetPolicy 取清理策略, 不存在时返回默认值(未保存)
+func (s *StaleDeviceService) GetPolicy() *model.StaleDevicePolicy {
+	p := &model.StaleDevicePolicy{}
+	DB.Order("id asc").First(p)
+	if p.Id == 0 {
+		p.FlagDays = 90
+		p.ExcludeGroupIds = []byte("[]")
+	}
+	return p
+}
+
+// SavePolicy 保存清理策略
+func (s *StaleDeviceService) SavePolicy(p *model.StaleDevicePolicy) error {
+	ex := s.GetPolicy()
+	if ex.Id == 0 {
+		p.Id = 0
+		return DB.Create(p).Error
+	}
+	p.Id = ex.Id
+	return DB.Model(p).Select("*").Omit("created_at").Updates(p).Error
+}
+
+func (s *StaleDeviceService) excludeGroupIds(p *model.StaleDevicePolicy) []uint {
+	ids := make([]uint, 0)
+	_ = json.Unmarshal(p.ExcludeGroupIds, &ids)
+	return ids
+}
+
+// inactiveWhere 超过days天没有心跳且不在排除设备组中的设备, 从未心跳的设备按创建时间计算
+func (s *StaleDeviceService) inactiveWhere(days int, excludeGroupIds []uint) func(tx *gorm.DB) *gorm.DB {
+	threshold := time.Now().AddDate(0, 0, -days)
+	return func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Where("((last_online_time > 0 and last_online_time < ?) or (last_online_time = 0 and created_at < ?))", threshold.Unix(), threshold)
+		if len(excludeGroupIds) > 0 {
+			tx = tx.Where("group_id not in ?", excludeGroupIds)
+		}
+		return tx
+	}
+}
+
+// Preview 预览按策略p会被新标记和删除的设备
+func (s *StaleDeviceService) Preview(p *model.StaleDevicePolicy) *StaleDevicePreview {
+	res := &StaleDevicePreview{ToFlag: make([]*model.Peer, 0), ToDelete: make([]*model.Peer, 0)}
+	excl := s.excludeGroupIds(p)
+	if p.FlagDays > 0 {
+		DB.Scopes(s.inactiveWhere(p.FlagDays, excl)).Where("stale = ?", false).Find(&res.ToFlag)
+	}
+	if p.DeleteDays > 0 {
+		DB.Scopes(s.inactiveWhere(p.DeleteDays, excl)).Find(&res.ToDelete)
+	}
+	return res
+}
+
+// Run 按保存的策略执行一次清理并记录
+func (s *StaleDeviceService) Run(trigger string) *model.StaleDeviceRun {
+	p := s.GetPolicy()
+	run := &model.StaleDeviceRun{Trigger: trigger}
+	deletedIds := make([]string, 0)
+	if err := s.run(p, run, &deletedIds); err != nil {
+		run.Error = err.Error()
+	}
+	run.DeletedIds, _ = json.Marshal(deletedIds)
+	if err := DB.Create(run).Error; err != nil {
+		Logger.Error("Save stale device run failed: ", err)
+	}
+	Logger.Info(fmt.Sprintf("Stale device cleanup (%s): flagged %d, unflagged %d, deleted %d %v",
+		trigger, run.Flagged, run.Unflagged, run.Deleted, deletedIds))
+	return run
+}
+
+func (s *StaleDeviceService) run(p *model.StaleDevicePolicy, run *model.StaleDeviceRun, deletedIds *[]string) error {
+	if p.FlagDays <= 0 {
+		return fmt.Errorf("flag_days must be greater than 0")
+	}
+	excl := s.excludeGroupIds(p)
+
+	var inactive, flagged []uint
+	DB.Model(&model.Peer{}).Scopes(s.inactiveWhere(p.FlagDays, excl)).Pluck("row_id", &inactive)
+	DB.Model(&model.Peer{}).Where("stale = ?", true).Pluck("row_id", &flagged)
+	toFlag, toUnflag := staleFlagSets(inactive, flagged)
+	for _, chunk := range chunkIds(toFlag, 500) {
+		if err := DB.Model(&model.Peer{}).Where("row_id in ?", chunk).Update("stale", true).Error; err != nil {
+			return err
+		}
+		run.Flagged += len(chunk)
+	}
+	for _, chunk := range chunkIds(toUnflag, 500) {
+		if err := DB.Model(&model.Peer{}).Where("row_id in ?", chunk).Update("stale", false).Error; err != nil {
+			return err
+		}
+		run.Unflagged += len(chunk)
+	}
+
+	if p.DeleteDays <= 0 {
+		return nil
+	}
+	var toDelete []*model.Peer
+	DB.Scopes(s.inactiveWhere(p.DeleteDays, excl)).Find(&toDelete)
+	for _, peer := range toDelete {
+		if err := AllService.PeerService.Delete(peer); err != nil {
+			return err
+		}
+		run.Deleted++
+		*deletedIds = append(*deletedIds, peer.Id)
+	}
+	return nil
+}
+
+// RunScheduled 策略启用时执行清理, 由后台任务定时调用
+func (s *StaleDeviceService) RunScheduled() {
+	if !s.GetPolicy().Enable {
+		return
+	}
+	s.Run(model.StaleRunTriggerCron)
+}
+
+func (s *StaleDeviceService) ListRuns(page, pageSize uint, where func(tx *gorm.DB)) (res *model.StaleDeviceRunList) {
+	res = &model.StaleDeviceRunList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.StaleDeviceRun{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.StaleDeviceRuns)
+	return
+}
+
+// staleFlagSets 根据当前不活跃的设备和已标记的设备, 计算需要新标记和取消标记的设备
+func staleFlagSets(inactive, flagged []uint) (toFlag, toUnflag []uint) {
+	inactiveSet := make(map[uint]bool, len(inactive))
+	for _, id := range inactive {
+		inactiveSet[id] = true
+	}
+	flaggedSet := make(map[uint]bool, len(flagged))
+	toUnflag = make([]uint, 0)
+	for _, id := range flagged {
+		flaggedSet[id] = true
+		if !inactiveSet[id] {
+			toUnflag = append(toUnflag, id)
+		}
+	}
+	toFlag = make([]uint, 0)
+	for _, id := range inactive {
+		if !flaggedSet[id] {
+			toFlag = append(toFlag, id)
+		}
+	}
+	return
+}
+
+func chunkIds(ids []uint, size int) [][]uint {
+	res := make([][]uint, 0, len(ids)/size+1)
+	for len(ids) > size {
+		res = append(res, ids[:size])
+		ids = ids[size:]
+	}
+	if len(ids) > 0 {
+		res = append(res, ids)
+	}
+	return res
+}
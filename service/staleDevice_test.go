@@ -0,0 +1,46 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChunkIds(t *testing.T) {
+	cases := []struct {
+		ids  []uint
+		size int
+		want [][]uint
+	}{
+		{nil, 2, [][]uint{}},
+		{[]uint{1}, 2, [][]uint{{1}}},
+		{[]uint{1, 2}, 2, [][]uint{{1, 2}}},
+		{[]uint{1, 2, 3, 4, 5}, 2, [][]uint{{1, 2}, {3, 4}, {5}}},
+	}
+	for _, c := range cases {
+		if got := chunkIds(c.ids, c.size); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("chunkIds(%v, %d) = %v, want %v", c.ids, c.size, got, c.want)
+		}
+	}
+}
+
+func TestStaleFlagSets(t *testing.T) {
+	cases := []struct {
+		name         string
+		inactive     []uint
+		flagged      []uint
+		flag, unflag []uint
+	}{
+		{"newly inactive", []uint{1, 2}, nil, []uint{1, 2}, []uint{}},
+		{"already flagged", []uint{1, 2}, []uint{1}, []uint{2}, []uint{}},
+		{"back online", []uint{2}, []uint{1, 2}, []uint{}, []uint{1}},
+		// 设备组被排除后不再出现在 inactive 中, 已有标记被取消
+		{"excluded group", []uint{}, []uint{3}, []uint{}, []uint{3}},
+		{"nothing to do", nil, nil, []uint{}, []uint{}},
+	}
+	for _, c := range cases {
+		flag, unflag := staleFlagSets(c.inactive, c.flagged)
+		if !reflect.DeepEqual(flag, c.flag) || !reflect.DeepEqual(unflag, c.unflag) {
+			t.Errorf("%s: got flag %v unflag %v, want flag %v unflag %v", c.name, flag, unflag, c.flag, c.unflag)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+type StrategyService struct {
+}
+
+func (s *StrategyService) InfoById(id uint) *model.Strategy {
+	r := &model.Strategy{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *StrategyService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *model.StrategyList) {
+	res = &model.StrategyList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.Strategy{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.Strategies)
+	return
+}
+
+func (s *StrategyService) Create(r *model.Strategy) error {
+	r.Version = time.Now().UnixMilli()
+	return DB.Create(r).Error
+}
+
+// Update 更新策略, 选项或状态变化时更新版本, 让客户端重新应用
+func (s *StrategyService) Update(r *model.Strategy, ex *model.Strategy) error {
+	r.Version = ex.Version
+	if string(r.Options) != string(ex.Options) || r.Status != ex.Status {
+		r.Version = time.Now().UnixMilli()
+	}
+	return DB.Model(r).Select("*").Omit("created_at").Updates(r).Error
+}
+
+// Delete 删除策略及其分配
+func (s *StrategyService) Delete(r *model.Strategy) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("strategy_id = ?", r.Id).Delete(&model.StrategyAssignment{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(r).Error
+	})
+}
+
+func (s *StrategyService) AssignmentInfoById(id uint) *model.StrategyAssignment {
+	r := &model.StrategyAssignment{}
+	DB.Where("id = ?", id).First(r)
+	return r
+}
+
+func (s *StrategyService) ListAssignments(page, pageSize uint, where func(tx *gorm.DB)) (res *model.StrategyAssignmentList) {
+	res = &model.StrategyAssignmentList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.StrategyAssignment{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Find(&res.StrategyAssignments)
+	return
+}
+
+// Assign 为设备组, 用户或设备设置策略, 已有分配时替换
+func (s *StrategyService) Assign(strategyId uint, t int, toId uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("type = ? and to_id = ?", t, toId).Delete(&model.StrategyAssignment{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&model.StrategyAssignment{StrategyId: strategyId, Type: t, ToId: toId}).Error
+	})
+}
+
+func (s *StrategyService) DeleteAssignment(r *model.StrategyAssignment) error {
+	return DB.Delete(r).Error
+}
+
+// DeleteAssignmentsByTarget 删除设备组, 用户或设备的策略分配
+func (s *StrategyService) DeleteAssignmentsByTarget(tx *gorm.DB, t int, toId uint) error {
+	return tx.Where("type = ? and to_id = ?", t, toId).Delete(&model.StrategyAssignment{}).Error
+}
+
+// Effective 设备生效的策略, 优先级: 单个设备 > 用户 > 设备组; 没有或策略禁用时返回nil
+func (s *StrategyService) Effective(p *model.Peer) *model.Strategy {
+	targets := [][2]uint{{model.StrategyTargetPeer, p.RowId}}
+	if p.UserId > 0 {
+		targets = append(targets, [2]uint{model.StrategyTargetUser, p.UserId})
+	}
+	if p.GroupId > 0 {
+		targets = append(targets, [2]uint{model.StrategyTargetDeviceGroup, p.GroupId})
+	}
+	for _, t := range targets {
+		a := &model.StrategyAssignment{}
+		DB.Where("type = ? and to_id = ?", t[0], t[1]).First(a)
+		if a.Id == 0 {
+			continue
+		}
+		st := s.InfoById(a.StrategyId)
+		if st.Id == 0 || st.Status != model.COMMON_STATUS_ENABLE {
+			return nil
+		}
+		return st
+	}
+	return nil
+}
+
+// HeartbeatPayload 心跳响应中的策略部分, 客户端上报的 modifiedAt 与版本一致时只返回版本
+func (s *StrategyService) HeartbeatPayload(p *model.Peer, modifiedAt int64) map[string]interface{} {
+	st := s.Effective(p)
+	s.recordState(p.Id, st, modifiedAt)
+	if st == nil {
+		return nil
+	}
+	res := map[string]interface{}{"modified_at": st.Version}
+	if modifiedAt != st.Version {
+		options := map[string]string{}
+		_ = json.Unmarshal(st.Options, &options)
+		res["strategy"] = map[string]interface{}{
+			"config_options": options,
+			"extra":          map[string]string{},
+		}
+	}
+	return res
+}
+
+// recordState 记录设备的策略应用状态, 没有变化时只在超过一分钟后更新 last_seen
+func (s *StrategyService) recordState(peerId string, st *model.Strategy, modifiedAt int64) {
+	var strategyId uint
+	var expected int64
+	if st != nil {
+		strategyId = st.Id
+		expected = st.Version
+	}
+	now := time.Now().Unix()
+	ex := &model.PeerStrategyState{}
+	DB.Where("peer_id = ?", peerId).First(ex)
+	if ex.Id == 0 {
+		if st == nil {
+			return
+		}
+		DB.Create(&model.PeerStrategyState{PeerId: peerId, StrategyId: strategyId, ExpectedVersion: expected, AppliedVersion: modifiedAt, LastSeen: now})
+		return
+	}
+	if ex.StrategyId == strategyId && ex.ExpectedVersion == expected && ex.AppliedVersion == modifiedAt && now-ex.LastSeen < 60 {
+		return
+	}
+	DB.Model(ex).Updates(map[string]interface{}{
+		"strategy_id":      strategyId,
+		"expected_version": expected,
+		"applied_version":  modifiedAt,
+		"last_seen":        now,
+	})
+}
+
+func (s *StrategyService) ListStates(page, pageSize uint, where func(tx *gorm.DB)) (res *model.PeerStrategyStateList) {
+	res = &model.PeerStrategyStateList{}
+	res.Page = int64(page)
+	res.PageSize = int64(pageSize)
+	tx := DB.Model(&model.PeerStrategyState{})
+	if where != nil {
+		where(tx)
+	}
+	tx.Count(&res.Total)
+	tx.Scopes(Paginate(page, pageSize))
+	tx.Order("id desc").Find(&res.PeerStrategyStates)
+	return
+}
+
+// DeleteStateByPeerId 删除设备的策略状态
+func (s *StrategyService) DeleteStateByPeerId(peerId string) error {
+	return DB.Where("peer_id = ?", peerId).Delete(&model.PeerStrategyState{}).Error
+}
@@ -33,6 +33,7 @@ func (s *TagService) ListByUserIdAndCollectionId(userId, cid uint) (res *model.T
 	return
 }
 func (s *TagService) UpdateTags(userId uint, tags map[string]uint) {
+	actor := &AbActor{UserId: userId, Source: model.AbChangeSourceClient}
 	tx := DB.Begin()
 	//先查询所有tag
 	var allTags []*model.Tag
@@ -41,11 +42,14 @@ func (s *TagService) UpdateTags(userId uint, tags map[string]uint) {
 		if _, ok := tags[t.Name]; !ok {
 			//删除
 			tx.Delete(t)
+			AllService.AddressBookHistoryService.RecordTag(tx, actor, t, nil)
 		} else {
 			if tags[t.Name] != t.Color {
 				//更新
+				before := *t
 				t.Color = tags[t.Name]
 				tx.Save(t)
+				AllService.AddressBookHistoryService.RecordTag(tx, actor, &before, t)
 			}
 			//移除
 			delete(tags, t.Name)
@@ -58,6 +62,7 @@ func (s *TagService) UpdateTags(userId uint, tags map[string]uint) {
 		t.Color = color
 		t.UserId = userId
 		tx.Create(t)
+		AllService.AddressBookHistoryService.RecordTag(tx, actor, nil, t)
 	}
 	tx.Commit()
 }
@@ -83,16 +88,28 @@ func (s *TagService) List(page, pageSize uint, where func(tx *gorm.DB)) (res *mo
 	return
 }
 
-// Create 创建
-func (s *TagService) Create(u *model.Tag) error {
+// Create 创建, actor 不为空时记录变更历史
+func (s *TagService) Create(u *model.Tag, actor *AbActor) error {
 	res := DB.Create(u).Error
+	if res == nil {
+		AllService.AddressBookHistoryService.RecordTag(nil, actor, nil, u)
+	}
 	return res
 }
-func (s *TagService) Delete(u *model.Tag) error {
-	return DB.Delete(u).Error
+func (s *TagService) Delete(u *model.Tag, actor *AbActor) error {
+	err := DB.Delete(u).Error
+	if err == nil {
+		AllService.AddressBookHistoryService.RecordTag(nil, actor, u, nil)
+	}
+	return err
 }
 
 // Update 更新
-func (s *TagService) Update(u *model.Tag) error {
-	return DB.Model(u).Select("*").Omit("created_at").Updates(u).Error
+func (s *TagService) Update(u *model.Tag, actor *AbActor) error {
+	before := s.InfoById(u.Id)
+	err := DB.Model(u).Select("*").Omit("created_at").Updates(u).Error
+	if err == nil && before.Id != 0 {
+		AllService.AddressBookHistoryService.RecordTag(nil, actor, before, u)
+	}
+	return err
 }
@@ -105,8 +105,11 @@ func (us *UserService) Login(u *model.User, llog *model.LoginLog) *model.UserTok
 	}
 	DB.Create(ut)
 	llog.UserTokenId = ut.UserId
+	AllService.GeoipService.Fill(&llog.GeoInfo, llog.Ip)
 	DB.Create(llog)
 	if llog.Uuid != "" {
+		// 先合并, 检查时旧设备还是原所有者
+		AllService.PeerIdentityService.MergePendingOnLogin(llog.DeviceId, llog.Uuid)
 		AllService.PeerService.UuidBindUserId(llog.DeviceId, llog.Uuid, u.Id)
 	}
 	return ut
@@ -208,43 +211,112 @@ func (us *UserService) Logout(u *model.User, token string) error {
 
 // Delete deletes user and associated OAuth information
 func (us *UserService) Delete(u *model.User) error {
+	_, err := us.DeleteWithTransfer(u, nil, nil)
+	return err
+}
+
+// DeleteWithTransfer deletes the user, transferring its peers and address books to another user first when to is not nil.
+// The transfer report is nil when nothing was transferred; actor records the address book history of the transfer
+func (us *UserService) DeleteWithTransfer(u *model.User, to *model.User, actor *AbActor) (*model.OwnershipTransferReport, error) {
 	userCount := us.getAdminUserCount()
 	if userCount <= 1 && us.IsAdmin(u) {
-		return errors.New("The last admin user cannot be deleted")
+		return nil, errors.New("The last admin user cannot be deleted")
 	}
+	if to != nil && to.Id == u.Id {
+		return nil, ErrTransferSameUser
+	}
+	var res *model.OwnershipTransferReport
 	tx := DB.Begin()
+	// Transfer ownership before the associated data is deleted
+	if to != nil {
+		var err error
+		if res, err = transferOwnership(tx, u, to, actor); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
 	// Delete user
 	if err := tx.Delete(u).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 	// Delete associated OAuth information
 	if err := tx.Where("user_id = ?", u.Id).Delete(&model.UserThird{}).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	// Delete associated peer shares, before the address books they may refer to
+	if err := AllService.PeerShareService.DeleteByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 	// Delete associated address books
 	if err := tx.Where("user_id = ?", u.Id).Delete(&model.AddressBook{}).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 	// Delete associated address book collections
 	if err := tx.Where("user_id = ?", u.Id).Delete(&model.AddressBookCollection{}).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	// Delete associated address book folders
+	if err := AllService.AddressBookFolderService.DeleteByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 	// Delete associated address book collection rules
 	if err := tx.Where("user_id = ?", u.Id).Delete(&model.AddressBookCollectionRule{}).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
+	}
+	// Delete associated IP access rules
+	if err := AllService.AccessPolicyService.DeleteIpRulesByTarget(tx, model.AccessTargetTypeUser, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated access schedules
+	if err := AllService.AccessPolicyService.DeleteSchedulesByTarget(tx, model.AccessTargetTypeUser, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated device group permissions
+	if err := AllService.DeviceGroupPermissionService.DeleteByTarget(tx, model.AccessTargetTypeUser, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated strategy assignment
+	if err := AllService.StrategyService.DeleteAssignmentsByTarget(tx, model.StrategyTargetUser, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated address book history
+	if err := AllService.AddressBookHistoryService.DeleteByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated address book revisions
+	if err := AllService.AddressBookRevisionService.DeleteByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated notifications
+	if err := AllService.NotificationService.DeleteByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// Delete associated saved searches
+	if err := AllService.SearchService.DeleteSavedByUserId(tx, u.Id); err != nil {
+		tx.Rollback()
+		return nil, err
 	}
 	tx.Commit()
 	// Delete associated peers
 	if err := AllService.PeerService.EraseUserId(u.Id); err != nil {
 		Logger.Warn("User deleted successfully, but failed to unlink peer.")
-		return nil
+		return res, nil
 	}
-	return nil
+	return res, nil
 }
 
 // Update updates user information
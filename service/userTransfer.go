@@ -0,0 +1,194 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+	"gorm.io/gorm"
+)
+
+var ErrTransferSameUser = errors.New("cannot transfer ownership to the same user")
+
+// errTransferPreview 预览时回滚事务
+var errTransferPreview = errors.New("transfer preview")
+
+// TransferOwnership 将 from 的设备、地址簿集合、地址簿、标签、分享记录、共享规则和设备共享转给 to, 在一个事务中完成.
+// dryRun 时在事务中执行后回滚, 返回的结果与实际转移一致. 地址簿条目和标签的转移以 actor 记录变更历史
+func (us *UserService) TransferOwnership(from, to *model.User, dryRun bool, actor *AbActor) (*model.OwnershipTransferReport, error) {
+	if from.Id == to.Id {
+		return nil, ErrTransferSameUser
+	}
+	var res *model.OwnershipTransferReport
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		res, err = transferOwnership(tx, from, to, actor)
+		if err != nil {
+			return err
+		}
+		if dryRun {
+			return errTransferPreview
+		}
+		return nil
+	})
+	if errors.Is(err, errTransferPreview) {
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.DryRun = dryRun
+	return res, nil
+}
+
+func transferOwnership(tx *gorm.DB, from, to *model.User, actor *AbActor) (*model.OwnershipTransferReport, error) {
+	res := &model.OwnershipTransferReport{FromUserId: from.Id, ToUserId: to.Id}
+
+	// 在原地址簿中记录删除, 这些记录随原地址簿的变更记录一起转移, 恢复到转移前时可以找回条目;
+	// 全部转移后再在新地址簿中记录新增
+	var abs []*model.AddressBook
+	var tags []*model.Tag
+	tx.Where("user_id = ?", from.Id).Find(&abs)
+	tx.Where("user_id = ?", from.Id).Find(&tags)
+	for _, ab := range abs {
+		AllService.AddressBookHistoryService.RecordPeer(tx, actor, ab, nil)
+	}
+	for _, t := range tags {
+		AllService.AddressBookHistoryService.RecordTag(tx, actor, t, nil)
+	}
+
+	r := tx.Model(&model.Peer{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.Peers = r.RowsAffected
+
+	// 原用户的默认地址簿(collection_id = 0)转为目标用户的一个集合, 避免与目标用户的默认地址簿冲突
+	var personal, personalTags int64
+	tx.Model(&model.AddressBook{}).Where("user_id = ? and collection_id = 0", from.Id).Count(&personal)
+	tx.Model(&model.Tag{}).Where("user_id = ? and collection_id = 0", from.Id).Count(&personalTags)
+	if personal > 0 || personalTags > 0 {
+		name := from.Nickname
+		if name == "" {
+			name = from.Username
+		}
+		col := &model.AddressBookCollection{UserId: from.Id, Name: name}
+		if err := tx.Create(col).Error; err != nil {
+			return nil, err
+		}
+		for _, m := range []interface{}{&model.AddressBook{}, &model.Tag{}, &model.AddressBookChange{}, &model.AddressBookRevision{}} {
+			if err := tx.Model(m).Where("user_id = ? and collection_id = 0", from.Id).Update("collection_id", col.Id).Error; err != nil {
+				return nil, err
+			}
+		}
+		res.PersonalCollection = name
+		for _, ab := range abs {
+			if ab.CollectionId == 0 {
+				ab.CollectionId = col.Id
+			}
+		}
+		for _, t := range tags {
+			if t.CollectionId == 0 {
+				t.CollectionId = col.Id
+			}
+		}
+	}
+
+	var cids []uint
+	tx.Model(&model.AddressBookCollection{}).Where("user_id = ?", from.Id).Pluck("id", &cids)
+	r = tx.Model(&model.AddressBookCollection{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.Collections = r.RowsAffected
+	if res.PersonalCollection != "" {
+		res.Collections--
+	}
+	r = tx.Model(&model.AddressBook{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.AddressBooks = r.RowsAffected
+	r = tx.Model(&model.Tag{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.Tags = r.RowsAffected
+	r = tx.Model(&model.ShareRecord{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.ShareRecords = r.RowsAffected
+	if err := tx.Model(&model.AddressBookFolder{}).Where("user_id = ?", from.Id).Update("user_id", to.Id).Error; err != nil {
+		return nil, err
+	}
+	// 地址簿的变更记录随地址簿转移, 以便继续恢复
+	if err := tx.Model(&model.AddressBookChange{}).Where("user_id = ?", from.Id).Update("user_id", to.Id).Error; err != nil {
+		return nil, err
+	}
+	// 版本号随地址簿转移并继续递增, 客户端缓存的 ETag 不会与新版本号重合
+	if err := tx.Model(&model.AddressBookRevision{}).Where("user_id = ?", from.Id).Update("user_id", to.Id).Error; err != nil {
+		return nil, err
+	}
+
+	if len(cids) > 0 {
+		// 目标用户已是所有者, 原来共享给他的规则没有意义
+		if err := tx.Where("type = ? and to_id = ? and collection_id in (?)", model.ShareAddressBookRuleTypePersonal, to.Id, cids).
+			Delete(&model.AddressBookCollectionRule{}).Error; err != nil {
+			return nil, err
+		}
+		r = tx.Model(&model.AddressBookCollectionRule{}).Where("collection_id in (?)", cids).Update("user_id", to.Id)
+		if r.Error != nil {
+			return nil, r.Error
+		}
+		res.Rules = r.RowsAffected
+	}
+
+	// 其他集合共享给原用户的规则转给目标用户, 目标用户已有有效期相同的规则时保留较高的权限;
+	// 有效期不同的规则各自保留, 避免延长或缩短任何一方的共享期限
+	var grants []*model.AddressBookCollectionRule
+	tx.Where("type = ? and to_id = ?", model.ShareAddressBookRuleTypePersonal, from.Id).Find(&grants)
+	for _, g := range grants {
+		existing := &model.AddressBookCollectionRule{}
+		tx.Where("type = ? and to_id = ? and collection_id = ? and folder_id = ? and start_at = ? and end_at = ?",
+			model.ShareAddressBookRuleTypePersonal, to.Id, g.CollectionId, g.FolderId, g.StartAt, g.EndAt).First(existing)
+		var err error
+		switch {
+		case g.UserId == to.Id:
+			err = tx.Delete(g).Error
+		case existing.Id != 0:
+			if g.Rule > existing.Rule {
+				err = tx.Model(existing).Update("rule", g.Rule).Error
+			}
+			if err == nil {
+				err = tx.Delete(g).Error
+			}
+		default:
+			err = tx.Model(g).Update("to_id", to.Id).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		res.Grants++
+	}
+
+	// 原用户共享出去的设备由目标用户继续共享, 共享给目标用户本人的没有意义
+	if err := tx.Where("user_id = ? and type = ? and to_id = ?", from.Id, model.ShareAddressBookRuleTypePersonal, to.Id).
+		Delete(&model.PeerShare{}).Error; err != nil {
+		return nil, err
+	}
+	r = tx.Model(&model.PeerShare{}).Where("user_id = ?", from.Id).Update("user_id", to.Id)
+	if r.Error != nil {
+		return nil, r.Error
+	}
+	res.PeerShares = r.RowsAffected
+
+	for _, ab := range abs {
+		ab.UserId = to.Id
+		AllService.AddressBookHistoryService.RecordPeer(tx, actor, nil, ab)
+	}
+	for _, t := range tags {
+		t.UserId = to.Id
+		AllService.AddressBookHistoryService.RecordTag(tx, actor, nil, t)
+	}
+	return res, nil
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/RobertLesgros/rustdesk-interface/v2/model"
+)
+
+func transferTestDB(t *testing.T) {
+	newTestDB(t, &model.User{}, &model.Peer{}, &model.AddressBook{}, &model.Tag{}, &model.AddressBookCollection{},
+		&model.AddressBookCollectionRule{}, &model.AddressBookChange{}, &model.AddressBookFolder{}, &model.ShareRecord{}, &model.PeerShare{},
+		&model.AddressBookRevision{})
+}
+
+func TestTransferOwnershipPersonalBook(t *testing.T) {
+	transferTestDB(t)
+	from := &model.User{Username: "alice", Nickname: "Alice"}
+	to := &model.User{Username: "bob"}
+	DB.Create(from)
+	DB.Create(to)
+	DB.Create(&model.AddressBook{Id: "111", UserId: from.Id})
+	DB.Create(&model.Tag{Name: "prod", UserId: from.Id})
+	// 目标用户自己的默认地址簿不受影响
+	DB.Create(&model.AddressBook{Id: "222", UserId: to.Id})
+	DB.Create(&model.AddressBookRevision{UserId: from.Id, Revision: 5})
+
+	res, err := AllService.UserService.TransferOwnership(from, to, false, &AbActor{Source: model.AbChangeSourceAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PersonalCollection != "Alice" || res.Collections != 0 || res.AddressBooks != 1 || res.Tags != 1 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+	col := &model.AddressBookCollection{}
+	DB.Where("name = ?", "Alice").First(col)
+	if col.Id == 0 || col.UserId != to.Id {
+		t.Fatalf("personal book should become a collection of the target user, got %+v", col)
+	}
+	ab := &model.AddressBook{}
+	DB.Where("id = ?", "111").First(ab)
+	if ab.UserId != to.Id || ab.CollectionId != col.Id {
+		t.Errorf("entry user/collection = %d/%d, want %d/%d", ab.UserId, ab.CollectionId, to.Id, col.Id)
+	}
+	tag := &model.Tag{}
+	DB.Where("name = ?", "prod").First(tag)
+	if tag.UserId != to.Id || tag.CollectionId != col.Id {
+		t.Errorf("tag user/collection = %d/%d, want %d/%d", tag.UserId, tag.CollectionId, to.Id, col.Id)
+	}
+	own := &model.AddressBook{}
+	DB.Where("id = ?", "222").First(own)
+	if own.UserId != to.Id || own.CollectionId != 0 {
+		t.Errorf("target personal book changed: %+v", own)
+	}
+	// 转移记录在新集合的历史中, 先删除后新增
+	var actions []string
+	DB.Model(&model.AddressBookChange{}).Where("user_id = ? and collection_id = ? and entity = ?", to.Id, col.Id, model.AbChangeEntityPeer).
+		Order("id").Pluck("action", &actions)
+	if len(actions) != 2 || actions[0] != model.AbChangeActionDelete || actions[1] != model.AbChangeActionCreate {
+		t.Errorf("history of the transferred entry = %v", actions)
+	}
+	// 版本号随地址簿转移并继续递增
+	if rev := AllService.AddressBookRevisionService.Get(to.Id, col.Id); rev <= 5 {
+		t.Errorf("revision of the transferred book = %d, want above 5", rev)
+	}
+	var n int64
+	DB.Model(&model.AddressBookRevision{}).Where("user_id = ?", from.Id).Count(&n)
+	if n != 0 {
+		t.Errorf("%d revisions left on the old owner", n)
+	}
+}
+
+func TestTransferOwnershipDryRun(t *testing.T) {
+	transferTestDB(t)
+	from := &model.User{Username: "alice"}
+	to := &model.User{Username: "bob"}
+	DB.Create(from)
+	DB.Create(to)
+	DB.Create(&model.AddressBook{Id: "111", UserId: from.Id})
+
+	res, err := AllService.UserService.TransferOwnership(from, to, true, &AbActor{Source: model.AbChangeSourceAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.DryRun || res.AddressBooks != 1 || res.PersonalCollection != "alice" {
+		t.Fatalf("unexpected report %+v", res)
+	}
+	var n int64
+	DB.Model(&model.AddressBookCollection{}).Count(&n)
+	ab := &model.AddressBook{}
+	DB.Where("id = ?", "111").First(ab)
+	if n != 0 || ab.UserId != from.Id {
+		t.Errorf("dry run must not write: %d collections, entry owner %d", n, ab.UserId)
+	}
+}
+
+func TestTransferOwnershipGrants(t *testing.T) {
+	transferTestDB(t)
+	from := &model.User{Username: "alice"}
+	to := &model.User{Username: "bob"}
+	other := &model.User{Username: "carol"}
+	DB.Create(from)
+	DB.Create(to)
+	DB.Create(other)
+	c1 := &model.AddressBookCollection{UserId: other.Id, Name: "c1"}
+	c2 := &model.AddressBookCollection{UserId: other.Id, Name: "c2"}
+	c3 := &model.AddressBookCollection{UserId: to.Id, Name: "c3"}
+	DB.Create(c1)
+	DB.Create(c2)
+	DB.Create(c3)
+	personal := model.ShareAddressBookRuleTypePersonal
+	rules := []*model.AddressBookCollectionRule{
+		// c1: 两人都有规则, 保留较高的权限
+		{UserId: other.Id, CollectionId: c1.Id, Type: personal, ToId: from.Id, Rule: model.ShareAddressBookRuleRuleFullControl},
+		{UserId: other.Id, CollectionId: c1.Id, Type: personal, ToId: to.Id, Rule: model.ShareAddressBookRuleRuleRead},
+		// c2: 目标用户的规则有效期不同, 不合并, 原用户的规则直接转给目标用户
+		{UserId: other.Id, CollectionId: c2.Id, Type: personal, ToId: from.Id, Rule: model.ShareAddressBookRuleRuleReadWrite},
+		{UserId: other.Id, CollectionId: c2.Id, Type: personal, ToId: to.Id, Rule: model.ShareAddressBookRuleRuleRead, EndAt: 4102444800},
+		// c3: 目标用户自己的集合, 规则没有意义
+		{UserId: to.Id, CollectionId: c3.Id, Type: personal, ToId: from.Id, Rule: model.ShareAddressBookRuleRuleRead},
+	}
+	for _, r := range rules {
+		DB.Create(r)
+	}
+
+	res, err := AllService.UserService.TransferOwnership(from, to, false, &AbActor{Source: model.AbChangeSourceAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Grants != 3 {
+		t.Errorf("Grants = %d, want 3", res.Grants)
+	}
+	var left []*model.AddressBookCollectionRule
+	DB.Order("collection_id asc").Find(&left)
+	if len(left) != 3 {
+		t.Fatalf("got %d rules, want 3: %+v", len(left), left)
+	}
+	if left[0].CollectionId != c1.Id || left[0].ToId != to.Id || left[0].Rule != model.ShareAddressBookRuleRuleFullControl {
+		t.Errorf("c1 rule = %+v, want full control for target", left[0])
+	}
+	for _, r := range left[1:] {
+		if r.CollectionId != c2.Id || r.ToId != to.Id {
+			t.Errorf("c2 rule = %+v, want a rule for target", r)
+		}
+		if (r.EndAt == 0 && r.Rule != model.ShareAddressBookRuleRuleReadWrite) || (r.EndAt != 0 && r.Rule != model.ShareAddressBookRuleRuleRead) {
+			t.Errorf("c2 rule = %+v, want both windows kept with their own rights", r)
+		}
+	}
+}
+
+func TestTransferOwnershipPeerShares(t *testing.T) {
+	transferTestDB(t)
+	from := &model.User{Username: "alice"}
+	to := &model.User{Username: "bob"}
+	DB.Create(from)
+	DB.Create(to)
+	DB.Create(&model.PeerShare{UserId: from.Id, PeerId: "111", Type: model.ShareAddressBookRuleTypePersonal, ToId: 9})
+	DB.Create(&model.PeerShare{UserId: from.Id, PeerId: "111", Type: model.ShareAddressBookRuleTypeGroup, ToId: 2})
+	// 共享给目标用户本人的删除
+	DB.Create(&model.PeerShare{UserId: from.Id, PeerId: "111", Type: model.ShareAddressBookRuleTypePersonal, ToId: to.Id})
+
+	res, err := AllService.UserService.TransferOwnership(from, to, false, &AbActor{Source: model.AbChangeSourceAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.PeerShares != 2 {
+		t.Fatalf("unexpected report %+v", res)
+	}
+	var n int64
+	DB.Model(&model.PeerShare{}).Where("user_id = ?", to.Id).Count(&n)
+	if n != 2 {
+		t.Errorf("target user shares %d peers, want 2", n)
+	}
+	DB.Model(&model.PeerShare{}).Where("to_id = ? and type = ?", to.Id, model.ShareAddressBookRuleTypePersonal).Count(&n)
+	if n != 0 {
+		t.Error("share to the target user should be removed")
+	}
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"errors"
+	"net"
+	"strings"
+)
+
+// ParseCidr parses a CIDR block; a bare IP is treated as a single-host network (/32 or /128).
+func ParseCidr(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, errors.New("empty cidr")
+	}
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, errors.New("invalid ip: " + s)
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// IpInCidr reports whether ip belongs to the given CIDR block (or equals the given IP).
+// Invalid input never matches.
+func IpInCidr(ip string, cidr string) bool {
+	pip := net.ParseIP(strings.TrimSpace(ip))
+	if pip == nil {
+		return false
+	}
+	n, err := ParseCidr(cidr)
+	if err != nil {
+		return false
+	}
+	return n.Contains(pip)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestParseCidr(t *testing.T) {
+	valid := []string{"10.8.0.0/16", "192.168.1.10", " 172.16.0.0/12 ", "2001:db8::/32", "::1"}
+	for _, s := range valid {
+		if _, err := ParseCidr(s); err != nil {
+			t.Errorf("ParseCidr(%q) unexpected error: %v", s, err)
+		}
+	}
+	invalid := []string{"", "10.8.0.0/33", "not-an-ip", "300.1.1.1"}
+	for _, s := range invalid {
+		if _, err := ParseCidr(s); err == nil {
+			t.Errorf("ParseCidr(%q) expected error", s)
+		}
+	}
+}
+
+func TestIpInCidr(t *testing.T) {
+	cases := []struct {
+		ip   string
+		cidr string
+		want bool
+	}{
+		{"10.8.3.4", "10.8.0.0/16", true},
+		{"10.9.3.4", "10.8.0.0/16", false},
+		{"192.168.1.10", "192.168.1.10", true},
+		{"192.168.1.11", "192.168.1.10", false},
+		{"2001:db8::1", "2001:db8::/32", true},
+		{"10.8.3.4", "2001:db8::/32", false},
+		{"bad", "10.8.0.0/16", false},
+		{"10.8.3.4", "bad", false},
+	}
+	for _, c := range cases {
+		if got := IpInCidr(c.ip, c.cidr); got != c.want {
+			t.Errorf("IpInCidr(%q, %q) = %v, want %v", c.ip, c.cidr, got, c.want)
+		}
+	}
+}
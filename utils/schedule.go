@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const HolidayDateLayout = "2006-01-02"
+
+// WeeklyWindow 每周时间窗口, Weekday 0为周日; End 小于等于 Start 时表示跨越午夜
+type WeeklyWindow struct {
+	Weekday int    `json:"weekday"`
+	Start   string `json:"start"` // HH:MM
+	End     string `json:"end"`   // HH:MM, 24:00 表示当天结束
+}
+
+// parseClock 将 HH:MM 转为当天的分钟数
+func parseClock(s string) (int, error) {
+	var h, m int
+	if _, err := fmt.Sscanf(s, "%d:%d", &h, &m); err != nil {
+		return 0, errors.New("invalid time: " + s)
+	}
+	if h < 0 || m < 0 || m > 59 || h > 24 || (h == 24 && m != 0) {
+		return 0, errors.New("invalid time: " + s)
+	}
+	return h*60 + m, nil
+}
+
+// Validate 校验时间窗口
+func (w *WeeklyWindow) Validate() error {
+	if w.Weekday < 0 || w.Weekday > 6 {
+		return fmt.Errorf("invalid weekday: %d", w.Weekday)
+	}
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return err
+	}
+	if start == end {
+		return errors.New("empty window: " + w.Start + "-" + w.End)
+	}
+	return nil
+}
+
+// contains t 必须已经转换为目标时区
+func (w *WeeklyWindow) contains(t time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+	minute := t.Hour()*60 + t.Minute()
+	weekday := int(t.Weekday())
+	if start < end {
+		return weekday == w.Weekday && minute >= start && minute < end
+	}
+	// 跨越午夜: 当天 start 之后, 或者第二天 end 之前
+	if weekday == w.Weekday && minute >= start {
+		return true
+	}
+	return weekday == (w.Weekday+1)%7 && minute < end
+}
+
+// InWeeklyWindows 判断t在loc时区内是否落在任一时间窗口内, holidays 中的日期(YYYY-MM-DD)全天不可用
+func InWeeklyWindows(t time.Time, loc *time.Location, windows []WeeklyWindow, holidays []string) bool {
+	if loc == nil {
+		loc = time.Local
+	}
+	lt := t.In(loc)
+	day := lt.Format(HolidayDateLayout)
+	for _, h := range holidays {
+		if h == day {
+			return false
+		}
+	}
+	for i := range windows {
+		if windows[i].contains(lt) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeeklyWindowValidate(t *testing.T) {
+	valid := []WeeklyWindow{
+		{Weekday: 1, Start: "08:00", End: "18:00"},
+		{Weekday: 5, Start: "22:00", End: "06:00"},
+		{Weekday: 0, Start: "00:00", End: "24:00"},
+	}
+	for _, w := range valid {
+		if err := w.Validate(); err != nil {
+			t.Errorf("%+v: unexpected error %v", w, err)
+		}
+	}
+	invalid := []WeeklyWindow{
+		{Weekday: 7, Start: "08:00", End: "18:00"},
+		{Weekday: 1, Start: "8h", End: "18:00"},
+		{Weekday: 1, Start: "08:00", End: "08:00"},
+		{Weekday: 1, Start: "08:60", End: "18:00"},
+		{Weekday: 1, Start: "24:30", End: "18:00"},
+	}
+	for _, w := range invalid {
+		if err := w.Validate(); err == nil {
+			t.Errorf("%+v: expected error", w)
+		}
+	}
+}
+
+func TestInWeeklyWindows(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	windows := []WeeklyWindow{
+		{Weekday: 1, Start: "08:00", End: "18:00"}, // lundi
+		{Weekday: 5, Start: "22:00", End: "02:00"}, // vendredi soir -> samedi
+	}
+	holidays := []string{"2026-12-28"}
+	cases := []struct {
+		name string
+		t    time.Time
+		want bool
+	}{
+		{"monday morning", time.Date(2026, 10, 19, 9, 0, 0, 0, paris), true},
+		{"monday before start", time.Date(2026, 10, 19, 7, 59, 0, 0, paris), false},
+		{"monday end is exclusive", time.Date(2026, 10, 19, 18, 0, 0, 0, paris), false},
+		{"tuesday", time.Date(2026, 10, 20, 9, 0, 0, 0, paris), false},
+		{"friday night", time.Date(2026, 10, 23, 23, 0, 0, 0, paris), true},
+		{"saturday after midnight", time.Date(2026, 10, 24, 1, 30, 0, 0, paris), true},
+		{"saturday after window", time.Date(2026, 10, 24, 2, 0, 0, 0, paris), false},
+		{"holiday monday", time.Date(2026, 12, 28, 9, 0, 0, 0, paris), false},
+		{"utc instant converted", time.Date(2026, 10, 19, 6, 30, 0, 0, time.UTC), true},
+	}
+	for _, c := range cases {
+		if got := InWeeklyWindows(c.t, paris, windows, holidays); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	PlatformWindows = "windows"
+	PlatformMacos   = "macos"
+	PlatformLinux   = "linux"
+	PlatformAndroid = "android"
+	PlatformIos     = "ios"
+)
+
+var Platforms = []string{PlatformWindows, PlatformMacos, PlatformLinux, PlatformAndroid, PlatformIos}
+
+// NormalizePlatform 将客户端上报的 os 字符串(如 "windows / Windows 10 Pro")归一为平台标识, 无法识别时返回空
+func NormalizePlatform(os string) string {
+	s := strings.ToLower(os)
+	switch {
+	case s == "":
+		return ""
+	case strings.Contains(s, "windows"):
+		return PlatformWindows
+	case strings.Contains(s, "android"):
+		return PlatformAndroid
+	case strings.Contains(s, "ios"), strings.Contains(s, "iphone"), strings.Contains(s, "ipad"):
+		return PlatformIos
+	case strings.Contains(s, "mac"), strings.Contains(s, "darwin"):
+		return PlatformMacos
+	case strings.Contains(s, "linux"), strings.Contains(s, "ubuntu"), strings.Contains(s, "debian"),
+		strings.Contains(s, "fedora"), strings.Contains(s, "centos"), strings.Contains(s, "arch"):
+		return PlatformLinux
+	}
+	return ""
+}
+
+// versionParts 取版本号中的数字段, "1.3.2-beta" => [1 3 2]
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	if i := strings.IndexAny(v, "-+ "); i >= 0 {
+		v = v[:i]
+	}
+	parts := make([]int, 0, 4)
+	for _, s := range strings.Split(v, ".") {
+		end := 0
+		for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+			end++
+		}
+		n, _ := strconv.Atoi(s[:end])
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// CompareVersion 比较两个版本号, a < b 返回 -1, 相等返回 0, a > b 返回 1; 缺少的段视为 0
+func CompareVersion(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
@@ -0,0 +1,41 @@
+package utils
+
+import "testing"
+
+func TestCompareVersion(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"1.3.2", "1.3.2", 0},
+		{"1.3.2", "1.3.10", -1},
+		{"1.4.0", "1.3.9", 1},
+		{"1.3", "1.3.0", 0},
+		{"1.3.1", "1.3", 1},
+		{"v1.2.3", "1.2.3", 0},
+		{"1.3.0-beta", "1.3.0", 0},
+		{"1.2.7", "1.3.0", -1},
+	}
+	for _, c := range cases {
+		if got := CompareVersion(c.a, c.b); got != c.want {
+			t.Errorf("CompareVersion(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func TestNormalizePlatform(t *testing.T) {
+	cases := map[string]string{
+		"windows / Windows 10 Pro": PlatformWindows,
+		"Mac OS / 14.2":            PlatformMacos,
+		"linux / Ubuntu 22.04":     PlatformLinux,
+		"Android 13":               PlatformAndroid,
+		"iOS 17.1":                 PlatformIos,
+		"":                         "",
+		"Plan 9":                   "",
+	}
+	for os, want := range cases {
+		if got := NormalizePlatform(os); got != want {
+			t.Errorf("NormalizePlatform(%q) = %q, want %q", os, got, want)
+		}
+	}
+}